@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ibm/chic-sched/pkg/builder"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// runDescribe : describe a topology with the resource utilization of its nodes
+func runDescribe(args []string, stdout io.Writer) error {
+	fs := newFlagSet("describe")
+	topologyFile := fs.String("topology", "", "topology tree file (JSON or YAML)")
+	output := fs.String("o", "text", "output format: json, yaml, text, dot, or tree")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	pTree, err := loadTopology(*topologyFile)
+	if err != nil {
+		return err
+	}
+	if writeRendering(stdout, *output, pTree, nil) {
+		return nil
+	}
+	spec := builder.PTreeToSpec(pTree)
+	resourceNames := pTree.GetResourceNames()
+	return writeOutput(stdout, *output, spec, func(w io.Writer) {
+		if spec != nil {
+			writePNodeText(w, spec, resourceNames, 0)
+		}
+	})
+}
+
+// writePNodeText : write a physical subtree as indented text with utilization of resources
+func writePNodeText(w io.Writer, node *util.JPNode, resourceNames []string, depth int) {
+	fmt.Fprintf(w, "%s%s [%s]", strings.Repeat("  ", depth), node.ID, node.Level)
+	for _, name := range resourceNames {
+		capacity := node.Capacity[name]
+		allocated := node.Allocated[name]
+		utilization := 0.0
+		if capacity > 0 {
+			utilization = 100 * float64(allocated) / float64(capacity)
+		}
+		fmt.Fprintf(w, " %s=%d/%d(%.0f%%)", name, allocated, capacity, utilization)
+	}
+	fmt.Fprintln(w)
+	for _, child := range node.Children {
+		writePNodeText(w, child, resourceNames, depth+1)
+	}
+}
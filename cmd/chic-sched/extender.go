@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/ibm/chic-sched/pkg/extender"
+)
+
+// runExtender : run a Kubernetes scheduler extender placing groups of pods
+func runExtender(args []string, stdout io.Writer) error {
+	fs := newFlagSet("extender")
+	addr := fs.String("addr", ":8888", "address to listen on")
+	levelLabels := fs.String("level-labels", strings.Join(extender.DefaultLevelLabels, ","),
+		"comma separated node labels defining the topology levels, from the top down")
+	resourceNames := fs.String("resources", strings.Join(extender.DefaultResourceNames, ","),
+		"comma separated resources considered for placement")
+	planTimeout := fs.Duration("plan-timeout", extender.DefaultPlanTimeout,
+		"time after which placements and filtered pods not bound are forgotten")
+	apiServer := fs.String("api-server", "",
+		"URL of the API server binding pods and listing running pods (in-cluster if empty)")
+	tokenFile := fs.String("token-file", "", "file of the bearer token of the API server")
+	caFile := fs.String("ca-file", "", "file of the certificate authority of the API server")
+	syncInterval := fs.Duration("sync-interval", 30*time.Second,
+		"interval of listing running pods, releasing the resources of deleted pods")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *syncInterval <= 0 {
+		return fmt.Errorf("sync interval %s must be positive", *syncInterval)
+	}
+
+	client, err := newAPIClient(*apiServer, *tokenFile, *caFile)
+	if err != nil {
+		return err
+	}
+	e := extender.NewExtender(strings.Split(*levelLabels, ","), strings.Split(*resourceNames, ","))
+	e.SetPlanTimeout(*planTimeout)
+	e.SetBinder(client)
+	go syncPods(e, client, *syncInterval)
+	fmt.Fprintf(stdout, "listening on %s\n", *addr)
+	return http.ListenAndServe(*addr, e.Handler())
+}
+
+// newAPIClient : create a client of a given API server, or of the API server of the cluster if none
+func newAPIClient(server string, tokenFile string, caFile string) (*extender.APIClient, error) {
+	if len(server) == 0 {
+		return extender.NewInClusterAPIClient()
+	}
+	token := ""
+	if len(tokenFile) > 0 {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	return extender.NewAPIClient(server, token, caFile)
+}
+
+// syncPods : synchronize the running pods of the extender with the API server periodically
+func syncPods(e *extender.Extender, client *extender.APIClient, interval time.Duration) {
+	for {
+		listed := time.Now()
+		if pods, err := client.ListPods(); err != nil {
+			klog.Errorf("error listing pods: %s", err.Error())
+		} else {
+			e.SyncPods(pods, listed)
+		}
+		time.Sleep(interval)
+	}
+}
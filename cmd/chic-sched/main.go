@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/ibm/chic-sched/pkg/builder"
+	"github.com/ibm/chic-sched/pkg/placement"
+	"github.com/ibm/chic-sched/pkg/render"
+	"github.com/ibm/chic-sched/pkg/topology"
+)
+
+// Command line tool to run placements from spec files
+//   - place: place groups on a topology and print the resulting logical trees and bindings
+//   - validate: check topology and group spec files
+//   - describe: print a topology with resource utilization
+//   - serve: run a scheduling service holding the cluster state in memory
+//   - extender: run a Kubernetes scheduler extender
+
+const usage = `Usage: chic-sched <command> [flags]
+
+Commands:
+  place      place groups on a topology
+  validate   validate topology and group spec files
+  describe   describe a topology with resource utilization
+  serve      run an HTTP scheduling service
+  extender   run a Kubernetes scheduler extender (filter, prioritize, bind)
+
+Run 'chic-sched <command> -h' for the flags of a command.
+`
+
+// command : a subcommand of the tool
+type command struct {
+	name string
+	run  func(args []string, stdout io.Writer) error
+}
+
+var commands = []command{
+	{name: "place", run: runPlace},
+	{name: "validate", run: runValidate},
+	{name: "describe", run: runDescribe},
+	{name: "serve", run: runServe},
+	{name: "extender", run: runExtender},
+}
+
+// errNotPlaced : returned if some group is not fully placed
+var errNotPlaced = fmt.Errorf("not all groups fully placed")
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		fmt.Fprint(os.Stdout, usage)
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			err := cmd.run(os.Args[2:], os.Stdout)
+			klog.Flush()
+			if err == errNotPlaced {
+				os.Exit(2)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "chic-sched %s: %s\n", name, err.Error())
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "chic-sched: unknown command %q\n\n%s", name, usage)
+	os.Exit(1)
+}
+
+// stringList : a flag which may be repeated
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// newFlagSet : create a flag set for a command, including logging flags
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	klog.InitFlags(fs)
+	fs.Set("logtostderr", "true")
+	return fs
+}
+
+// isYaml : check if a file is YAML (by extension)
+func isYaml(fileName string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// loadTopology : create a PTree from a JSON or YAML topology file
+func loadTopology(fileName string) (*topology.PTree, error) {
+	if len(fileName) == 0 {
+		return nil, fmt.Errorf("missing topology file")
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var pTree *topology.PTree
+	if isYaml(fileName) {
+		pTree, err = builder.CreateTopologyTreeFromYaml(string(data))
+	} else {
+		pTree, err = builder.CreateTopologyTreeFromJson(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err.Error())
+	}
+	return pTree, nil
+}
+
+// loadGroup : create a PGroup from a JSON or YAML group file, given the topology
+func loadGroup(fileName string, pTree *topology.PTree) (*placement.PGroup, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var pg *placement.PGroup
+	if isYaml(fileName) {
+		pg, err = builder.CreatePGroupFromYaml(string(data), pTree.GetResourceNames(), pTree.GetLevelNames())
+	} else {
+		pg, err = builder.CreatePGroupFromJson(string(data), pTree.GetResourceNames(), pTree.GetLevelNames())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err.Error())
+	}
+	return pg, nil
+}
+
+// writeOutput : write an object in a given format (json or yaml), or as text using a given function
+func writeOutput(w io.Writer, format string, obj interface{}, text func(io.Writer)) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(data))
+	case "yaml":
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(w, string(data))
+	case "text":
+		text(w)
+	default:
+		return fmt.Errorf("invalid output format %q, expected json, yaml, or text", format)
+	}
+	return nil
+}
+
+// writeRendering : write a rendering of a topology in a given format (dot or tree),
+// with logical trees overlaid; returns false if not a rendering format
+func writeRendering(w io.Writer, format string, pTree *topology.PTree, lTrees []*topology.LTree) bool {
+	switch format {
+	case "dot":
+		fmt.Fprint(w, render.PTreeToDot(pTree, lTrees...))
+	case "tree":
+		fmt.Fprint(w, render.PTreeToASCII(pTree, 0, lTrees...))
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+func TestRunPlace(t *testing.T) {
+	var stdout bytes.Buffer
+	args := []string{"-topology", "../../samples/testTree.yaml", "-group", "../../samples/testGroup.json", "-o", "json"}
+	if err := runPlace(args, &stdout); err != nil {
+		t.Fatalf("runPlace() error = %v", err)
+	}
+	var placements []*util.JPlacement
+	if err := json.Unmarshal(stdout.Bytes(), &placements); err != nil {
+		t.Fatalf("runPlace() output not JSON: %v", err)
+	}
+	if len(placements) != 1 || !placements[0].FullyPlaced || len(placements[0].Bindings) != 4 {
+		t.Errorf("runPlace() = %s", stdout.String())
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			args:    []string{"-topology", "../../samples/testTree.json", "-group", "../../samples/testGroup.yaml"},
+			wantErr: false,
+		},
+		{
+			name:    "missing topology",
+			args:    []string{"-group", "../../samples/testGroup.yaml"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid group",
+			args:    []string{"-topology", "../../samples/testTree.json", "-group", "../../samples/testTree.json"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			if err := runValidate(tt.args, &stdout); (err != nil) != tt.wantErr {
+				t.Errorf("runValidate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
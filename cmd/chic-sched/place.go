@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ibm/chic-sched/pkg/builder"
+	"github.com/ibm/chic-sched/pkg/placement"
+	"github.com/ibm/chic-sched/pkg/report"
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// runPlace : place groups, in the given order, on a topology;
+// each placed group is claimed before placing the next one
+func runPlace(args []string, stdout io.Writer) error {
+	fs := newFlagSet("place")
+	topologyFile := fs.String("topology", "", "topology tree file (JSON or YAML)")
+	var groupFiles stringList
+	fs.Var(&groupFiles, "group", "placement group file (JSON or YAML), may be repeated")
+	output := fs.String("o", "text", "output format: json, yaml, text, dot, or tree")
+	reportFile := fs.String("report", "", "write an HTML report of the placements to a file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(groupFiles) == 0 {
+		return fmt.Errorf("missing group file")
+	}
+
+	pTree, err := loadTopology(*topologyFile)
+	if err != nil {
+		return err
+	}
+	pgs := make([]*placement.PGroup, len(groupFiles))
+	for i, fileName := range groupFiles {
+		if pgs[i], err = loadGroup(fileName, pTree); err != nil {
+			return err
+		}
+	}
+
+	placements := make([]*util.JPlacement, len(pgs))
+	allPlaced := true
+	for i, pg := range pgs {
+		p := placement.NewPlacer(pTree)
+		if _, err := p.PlaceGroup(pg); err == nil {
+			if err := pg.TryClaim(pg.GetSize(), pTree); err != nil {
+				pg.UnClaimAll(pTree)
+				return fmt.Errorf("group %s not claimed: %s", pg.GetID(), err.Error())
+			}
+		}
+		placements[i] = builder.PlacementToSpec(pg)
+		allPlaced = allPlaced && placements[i].FullyPlaced
+	}
+
+	if len(*reportFile) > 0 {
+		if err := writeReport(*reportFile, pTree, pgs); err != nil {
+			return err
+		}
+	}
+
+	lTrees := make([]*topology.LTree, 0, len(pgs))
+	for _, pg := range pgs {
+		lTrees = append(lTrees, pg.GetLTree())
+	}
+	if !writeRendering(stdout, *output, pTree, lTrees) {
+		err = writeOutput(stdout, *output, placements, func(w io.Writer) {
+			for _, jp := range placements {
+				writePlacementText(w, jp)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !allPlaced {
+		return errNotPlaced
+	}
+	return nil
+}
+
+// writePlacementText : write a placement as text
+func writePlacementText(w io.Writer, jp *util.JPlacement) {
+	fmt.Fprintf(w, "group %s: placed %d/%d\n", jp.Group, jp.Placed, jp.Size)
+	if jp.LTree != nil {
+		writeLNodeText(w, jp.LTree, 1)
+	}
+	if len(jp.Bindings) > 0 {
+		fmt.Fprintln(w, "bindings:")
+		ids := make([]string, 0, len(jp.Bindings))
+		for id := range jp.Bindings {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			fmt.Fprintf(w, "  %s -> %s\n", id, jp.Bindings[id])
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// writeLNodeText : write a logical subtree as indented text
+func writeLNodeText(w io.Writer, node *util.JLNode, depth int) {
+	fmt.Fprintf(w, "%s%s: %d\n", strings.Repeat("  ", depth), node.ID, node.Count)
+	for _, child := range node.Children {
+		writeLNodeText(w, child, depth+1)
+	}
+}
+
+// writeReport : write an HTML report of placement groups to a file
+func writeReport(fileName string, pTree *topology.PTree, pgs []*placement.PGroup) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteHTML(f, "chic-sched placement report", pTree, pgs...); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"k8s.io/klog/v2"
+
+	"github.com/ibm/chic-sched/pkg/server"
+)
+
+// runServe : run the scheduling service, optionally loading an initial topology
+func runServe(args []string, stdout io.Writer) error {
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	topologyFile := fs.String("topology", "", "initial topology tree file (JSON or YAML), optional")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s := server.NewServer()
+	if len(*topologyFile) > 0 {
+		pTree, err := loadTopology(*topologyFile)
+		if err != nil {
+			return err
+		}
+		s.SetPTree(pTree)
+	}
+	fmt.Fprintf(stdout, "listening on %s\n", *addr)
+	klog.V(4).Infof("serving on %s", *addr)
+	return http.ListenAndServe(*addr, s.Handler())
+}
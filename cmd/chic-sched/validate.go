@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// runValidate : validate a topology file and group files against it
+func runValidate(args []string, stdout io.Writer) error {
+	fs := newFlagSet("validate")
+	topologyFile := fs.String("topology", "", "topology tree file (JSON or YAML)")
+	var groupFiles stringList
+	fs.Var(&groupFiles, "group", "placement group file (JSON or YAML), may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	pTree, err := loadTopology(*topologyFile)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s: valid (%d nodes, %d leaves)\n", *topologyFile,
+		len(pTree.GetNodeIDs()), len(pTree.GetLeafIDs()))
+
+	numInvalid := 0
+	for _, fileName := range groupFiles {
+		if _, err := loadGroup(fileName, pTree); err != nil {
+			fmt.Fprintln(stdout, err.Error())
+			numInvalid++
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: valid\n", fileName)
+	}
+	if numInvalid > 0 {
+		return fmt.Errorf("%d invalid group file(s)", numInvalid)
+	}
+	return nil
+}
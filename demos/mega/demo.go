@@ -154,7 +154,9 @@ func main() {
 	fmt.Println(strings.Repeat("=", lineLength))
 	fmt.Println("Physical tree after group allocation:")
 	fmt.Println(strings.Repeat("=", lineLength))
-	pg.ClaimAll(pTree)
+	if !pg.ClaimAll(pTree) {
+		fmt.Println("Group not fully claimed")
+	}
 	fmt.Print(pTree)
 
 	// fmt.Println(strings.Repeat("=", lineLength))
@@ -126,7 +126,9 @@ func main() {
 	fmt.Println(strings.Repeat("=", lineLength))
 
 	numClaimed := int(math.Ceil(fractionClaimed * float64(groupSize)))
-	pg.Claim(numClaimed, pTree)
+	if !pg.Claim(numClaimed, pTree) {
+		fmt.Println("Group not fully claimed")
+	}
 	fmt.Print(pg)
 
 	fmt.Println(strings.Repeat("=", lineLength))
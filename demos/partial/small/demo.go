@@ -109,7 +109,9 @@ func main() {
 	fmt.Println("Allocation on servers:")
 	fmt.Println(strings.Repeat("=", lineLength))
 	numClaimed := int(math.Ceil(fractionClaimed * float64(groupSize)))
-	pg.Claim(numClaimed, pTree)
+	if !pg.Claim(numClaimed, pTree) {
+		fmt.Println("Group not fully claimed")
+	}
 	for i := 0; i < numServers; i++ {
 		fmt.Println(pes[i])
 	}
@@ -192,7 +192,9 @@ func main() {
 				fmt.Print(pg)
 			}
 
-			pg.ClaimAll(pTree)
+			if !pg.ClaimAll(pTree) {
+				fmt.Println("Group not fully claimed")
+			}
 
 			if isPrint {
 				fmt.Println(strings.Repeat("=", lineLength))
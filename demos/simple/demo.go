@@ -125,7 +125,9 @@ func main() {
 
 	// allocate resources
 	fmt.Println("Allocate logical tree:")
-	pg.ClaimAll(pTree)
+	if !pg.ClaimAll(pTree) {
+		fmt.Println("Group not fully claimed")
+	}
 	fmt.Print(pTree)
 	fmt.Print(pg)
 
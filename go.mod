@@ -2,6 +2,9 @@ module github.com/ibm/chic-sched
 
 go 1.20
 
-require k8s.io/klog/v2 v2.130.1
+require (
+	k8s.io/klog/v2 v2.130.1
+	sigs.k8s.io/yaml v1.4.0
+)
 
 require github.com/go-logr/logr v1.4.2 // indirect
@@ -0,0 +1,364 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/ibm/chic-sched/pkg/placement"
+	"github.com/ibm/chic-sched/pkg/system"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// CreatePGroupFromJson : create a PGroup from JSON string
+//   - resourceNames: names of resources of the topology, ordering the demand
+//   - levelNames: names of levels of the topology, from the top (below root) down to the leaves
+func CreatePGroupFromJson(groupString string, resourceNames []string, levelNames []string) (*placement.PGroup, error) {
+	spec, err := ParseGroupSpec([]byte(groupString))
+	if err != nil {
+		return nil, err
+	}
+	return CreatePGroupFromSpec(spec, resourceNames, levelNames)
+}
+
+// CreatePGroupFromYaml : create a PGroup from YAML string
+func CreatePGroupFromYaml(groupString string, resourceNames []string, levelNames []string) (*placement.PGroup, error) {
+	jsonGroup, err := yaml.YAMLToJSON([]byte(groupString))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing group: %s", err.Error())
+	}
+	return CreatePGroupFromJson(string(jsonGroup), resourceNames, levelNames)
+}
+
+// ParseGroupSpec : parse a placement group spec from JSON data, rejecting unknown fields
+func ParseGroupSpec(data []byte) (*util.PlacementGroup, error) {
+	var spec util.PlacementGroup
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("error parsing group: %s", err.Error())
+	}
+	return &spec, nil
+}
+
+// CreatePGroupFromSpec : validate a placement group spec and create the corresponding PGroup
+func CreatePGroupFromSpec(spec *util.PlacementGroup, resourceNames []string,
+	levelNames []string) (*placement.PGroup, error) {
+
+	if err := ValidateGroupSpec(spec, resourceNames, levelNames); err != nil {
+		return nil, err
+	}
+
+	// make demand ordered by resource names
+	demand, err := allocationFromSpec(spec.Spec.Demand, resourceNames, "spec.demand")
+	if err != nil {
+		return nil, err
+	}
+	pg := placement.NewPGroup(spec.MetaData.Name, spec.Spec.Size, demand)
+	pg.SetDuration(spec.Spec.Duration)
+
+	// make level constraints
+	for _, jlc := range spec.Spec.LevelConstraints {
+		level, _ := levelFromSpec(jlc.Level, jlc.LevelName, levelNames)
+		affinity, _ := util.StringToAffinity(jlc.Affinity)
+		lc := placement.NewLevelConstraint(jlc.ID, level, affinity, jlc.Hard)
+		if jlc.Range != nil {
+			lc.SetRange(jlc.Range.Min, jlc.Range.Max)
+		}
+		if jlc.Partitions > 0 {
+			lc.SetNumPartitions(jlc.Partitions)
+		}
+		if jlc.Factor > 0 {
+			lc.SetFactor(jlc.Factor)
+		}
+		lc.SetHomogeneousKey(jlc.HomogeneousKey)
+		lc.SetExclusive(jlc.Exclusive)
+		pg.AddLevelConstraint(lc)
+	}
+
+	// make tolerations
+	for _, jtol := range spec.Spec.Tolerations {
+		pg.AddToleration(system.Toleration{
+			Key:      jtol.Key,
+			Value:    jtol.Value,
+			AnyValue: strings.EqualFold(jtol.Operator, "Exists"),
+		})
+	}
+
+	// make node selectors
+	for _, jsel := range spec.Spec.NodeSelector {
+		pg.AddSelector(selectorFromSpec(&jsel))
+	}
+
+	// make anti-affinities
+	for _, jaa := range spec.Spec.AntiAffinity {
+		level, _ := levelFromSpec(jaa.Level, jaa.LevelName, levelNames)
+		pg.AddAntiAffinity(placement.NewAntiAffinity(jaa.Group, level, jaa.MaxShared))
+	}
+
+	// make affinities
+	for _, jga := range spec.Spec.Affinity {
+		level, _ := levelFromSpec(jga.Level, jga.LevelName, levelNames)
+		pg.AddGroupAffinity(placement.NewGroupAffinity(jga.Group, level, jga.Hard))
+	}
+
+	// make node order
+	if len(spec.Spec.NodeOrder) > 0 {
+		order, _ := placement.StringToNodeOrder(spec.Spec.NodeOrder)
+		var weights []float64
+		if len(spec.Spec.ResourceWeights) > 0 {
+			weights = make([]float64, len(resourceNames))
+			for i, name := range resourceNames {
+				weights[i] = spec.Spec.ResourceWeights[name]
+			}
+		}
+		pg.SetNodeOrder(order, weights)
+	}
+	return pg, nil
+}
+
+// ValidateGroupSpec : check a placement group spec, the returned error names the offending field
+//   - level numbers are checked against the height of the topology only if level names are given
+func ValidateGroupSpec(spec *util.PlacementGroup, resourceNames []string, levelNames []string) error {
+	if spec == nil {
+		return fmt.Errorf("group spec is nil")
+	}
+	if spec.Kind != util.DefaultGroupKind {
+		return fieldError("kind", "invalid kind %q, expected %q", spec.Kind, util.DefaultGroupKind)
+	}
+	if len(spec.MetaData.Name) == 0 {
+		return fieldError("metadata.name", "missing group name")
+	}
+	if spec.Spec.Size <= 0 {
+		return fieldError("spec.size", "size %d must be positive", spec.Spec.Size)
+	}
+	if spec.Spec.Duration < 0 {
+		return fieldError("spec.duration", "duration %d must not be negative", spec.Spec.Duration)
+	}
+
+	// check demand
+	if len(spec.Spec.Demand) == 0 {
+		return fieldError("spec.demand", "missing demand")
+	}
+	known := make(map[string]bool)
+	for _, name := range resourceNames {
+		known[name] = true
+	}
+	isPositive := false
+	for name, value := range spec.Spec.Demand {
+		if !known[name] {
+			return fieldError("spec.demand."+name, "unknown resource, expected one of %v", resourceNames)
+		}
+		if value < 0 {
+			return fieldError("spec.demand."+name, "demand %d must not be negative", value)
+		}
+		isPositive = isPositive || value > 0
+	}
+	if !isPositive {
+		return fieldError("spec.demand", "demand must be positive for at least one resource")
+	}
+
+	// check level constraints
+	ids := make(map[string]bool)
+	levels := make(map[int]bool)
+	for i, jlc := range spec.Spec.LevelConstraints {
+		field := fmt.Sprintf("spec.level-constraints[%d]", i)
+		if len(jlc.ID) == 0 {
+			return fieldError(field+".id", "missing id")
+		}
+		if ids[jlc.ID] {
+			return fieldError(field+".id", "duplicate id %q", jlc.ID)
+		}
+		ids[jlc.ID] = true
+
+		level, err := levelFromSpec(jlc.Level, jlc.LevelName, levelNames)
+		if err != nil {
+			return fieldError(field+"."+err.field, "%s", err.msg)
+		}
+		if levels[level] {
+			return fieldError(field, "duplicate constraint at level %d", level)
+		}
+		levels[level] = true
+
+		if _, ok := util.StringToAffinity(jlc.Affinity); !ok {
+			return fieldError(field+".affinity", "invalid affinity %q, expected Pack or Spread", jlc.Affinity)
+		}
+		if jlc.Range != nil {
+			if jlc.Hard {
+				return fieldError(field+".range", "range not allowed with hard constraint")
+			}
+			if jlc.Range.Min <= 0 {
+				return fieldError(field+".range.min", "min %d must be positive", jlc.Range.Min)
+			}
+			if jlc.Range.Max < jlc.Range.Min {
+				return fieldError(field+".range.max", "max %d less than min %d", jlc.Range.Max, jlc.Range.Min)
+			}
+		}
+		if jlc.Partitions < 0 {
+			return fieldError(field+".partitions", "partitions %d must be positive", jlc.Partitions)
+		}
+		if jlc.Partitions > 0 && jlc.Hard {
+			return fieldError(field+".partitions", "partitions not allowed with hard constraint")
+		}
+		if jlc.Factor < 0 {
+			return fieldError(field+".factor", "factor %d must be positive", jlc.Factor)
+		}
+	}
+
+	// check tolerations
+	for i, jtol := range spec.Spec.Tolerations {
+		field := fmt.Sprintf("spec.tolerations[%d]", i)
+		switch strings.ToLower(jtol.Operator) {
+		case "", "equal":
+			if len(jtol.Key) == 0 {
+				return fieldError(field+".key", "missing key with operator Equal")
+			}
+		case "exists":
+			if len(jtol.Value) > 0 {
+				return fieldError(field+".value", "value not allowed with operator Exists")
+			}
+		default:
+			return fieldError(field+".operator", "invalid operator %q, expected Equal or Exists", jtol.Operator)
+		}
+	}
+
+	// check node selectors
+	for i, jsel := range spec.Spec.NodeSelector {
+		field := fmt.Sprintf("spec.node-selector[%d]", i)
+		if len(jsel.Key) == 0 {
+			return fieldError(field+".key", "missing key")
+		}
+		op := system.Equals
+		if len(jsel.Operator) > 0 {
+			var ok bool
+			if op, ok = system.StringToSelectorOperator(jsel.Operator); !ok {
+				return fieldError(field+".operator", "invalid operator %q, expected Equals, In, NotIn, or Exists",
+					jsel.Operator)
+			}
+		}
+		if selectorFromSpec(&jsel) == nil {
+			switch op {
+			case system.Equals:
+				return fieldError(field+".values", "expected a single value with operator Equals")
+			case system.Exists:
+				return fieldError(field+".values", "values not allowed with operator Exists")
+			default:
+				return fieldError(field+".values", "missing values with operator %s",
+					system.SelectorOperatorToString(op))
+			}
+		}
+	}
+
+	// check anti-affinities and affinities
+	for i, jaa := range spec.Spec.AntiAffinity {
+		field := fmt.Sprintf("spec.anti-affinity[%d]", i)
+		if err := checkGroupAffinity(&jaa, spec.MetaData.Name, levelNames, field); err != nil {
+			return err
+		}
+		if jaa.MaxShared < 0 {
+			return fieldError(field+".max-shared", "max-shared %d must not be negative", jaa.MaxShared)
+		}
+		if jaa.Hard {
+			return fieldError(field+".hard", "hard not allowed with anti-affinity")
+		}
+	}
+	for i, jga := range spec.Spec.Affinity {
+		field := fmt.Sprintf("spec.affinity[%d]", i)
+		if err := checkGroupAffinity(&jga, spec.MetaData.Name, levelNames, field); err != nil {
+			return err
+		}
+		if jga.MaxShared != 0 {
+			return fieldError(field+".max-shared", "max-shared not allowed with affinity")
+		}
+	}
+
+	// check node order
+	order := placement.NumFitOrder
+	if len(spec.Spec.NodeOrder) > 0 {
+		var ok bool
+		if order, ok = placement.StringToNodeOrder(spec.Spec.NodeOrder); !ok {
+			return fieldError("spec.node-order", "invalid node order %q, expected NumFit, DominantShare, or WeightedLeftover",
+				spec.Spec.NodeOrder)
+		}
+	}
+	if len(spec.Spec.ResourceWeights) > 0 && order != placement.WeightedLeftoverOrder {
+		return fieldError("spec.resource-weights", "resource weights allowed only with node order WeightedLeftover")
+	}
+	for name, w := range spec.Spec.ResourceWeights {
+		if !known[name] {
+			return fieldError("spec.resource-weights."+name, "unknown resource, expected one of %v", resourceNames)
+		}
+		if w < 0 {
+			return fieldError("spec.resource-weights."+name, "weight %v must not be negative", w)
+		}
+	}
+	return nil
+}
+
+// checkGroupAffinity : check the group and level of an affinity or anti-affinity spec
+func checkGroupAffinity(jga *util.JGroupAffinity, groupName string, levelNames []string, field string) error {
+	if len(jga.Group) == 0 {
+		return fieldError(field+".group", "missing group")
+	}
+	if jga.Group == groupName {
+		return fieldError(field+".group", "affinity to the group itself")
+	}
+	if _, err := levelFromSpec(jga.Level, jga.LevelName, levelNames); err != nil {
+		return fieldError(field+"."+err.field, "%s", err.msg)
+	}
+	return nil
+}
+
+// selectorFromSpec : make a selector given its spec (nil if invalid)
+func selectorFromSpec(jsel *util.JSelector) *system.Selector {
+	op := system.Equals
+	if len(jsel.Operator) > 0 {
+		var ok bool
+		if op, ok = system.StringToSelectorOperator(jsel.Operator); !ok {
+			return nil
+		}
+	}
+	return system.NewSelector(jsel.Key, op, jsel.Values...)
+}
+
+// specError : a problem with a given field of a spec
+type specError struct {
+	field string
+	msg   string
+}
+
+// levelFromSpec : the level number of a spec given either by number or by name
+func levelFromSpec(levelNum *int, levelName string, levelNames []string) (int, *specError) {
+	height := len(levelNames)
+	if levelNum != nil && len(levelName) > 0 {
+		return 0, &specError{field: "level", msg: "only one of level and level-name may be given"}
+	}
+	if levelNum != nil {
+		level := *levelNum
+		if level < 0 || (height > 0 && level > height) {
+			return 0, &specError{field: "level", msg: fmt.Sprintf("level %d out of range [0,%d]", level, height)}
+		}
+		return level, nil
+	}
+	if len(levelName) > 0 {
+		if levelName == util.DefaultRootName {
+			return height, nil
+		}
+		for i, name := range levelNames {
+			if name == levelName {
+				return height - 1 - i, nil
+			}
+		}
+		return 0, &specError{field: "level-name",
+			msg: fmt.Sprintf("unknown level name %q, expected one of %v", levelName, levelNames)}
+	}
+	return 0, &specError{field: "level", msg: "missing level or level-name"}
+}
+
+// fieldError : an error pointing at a field of a spec
+func fieldError(field string, format string, a ...interface{}) error {
+	return fmt.Errorf("%s: %s", field, fmt.Sprintf(format, a...))
+}
@@ -0,0 +1,199 @@
+package builder
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	resourceNames []string = []string{"cpu", "memory"}
+	levelNames    []string = []string{"rack", "server"}
+)
+
+func TestCreatePGroupFromJson(t *testing.T) {
+	type args struct {
+		group string
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantErr   string
+		wantSize  int
+		wantLCIDs int
+		// demand ordered by resource names, if checked
+		wantDemand []int
+	}{
+		{
+			name: "good group",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 4, "demand": {"cpu": 2},
+				"level-constraints": [{"id": "lc-1", "level-name": "rack", "affinity": "pack"},
+				{"id": "lc-0", "level": 0, "affinity": "Spread", "partitions": 2, "factor": 2}]}}`,
+			},
+			wantErr:   "",
+			wantSize:  4,
+			wantLCIDs: 2,
+		},
+		{
+			name: "demand as numbers and quantities",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 2, "demand": {"cpu": "500m", "memory": "64Gi"}}}`,
+			},
+			wantSize:   2,
+			wantDemand: []int{500, 64 << 30},
+		},
+		{
+			name: "demand mixing numbers and quantities",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 2, "demand": {"cpu": 2, "memory": "1k"}}}`,
+			},
+			wantSize:   2,
+			wantDemand: []int{2, 1000},
+		},
+		{
+			name: "bad demand quantity",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 2, "demand": {"memory": "64GB"}}}`,
+			},
+			wantErr: "resource memory: invalid quantity",
+		},
+		{
+			name: "demand neither number nor quantity",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 2, "demand": {"memory": [64]}}}`,
+			},
+			wantErr: "resource memory: value [64]",
+		},
+		{
+			name: "bad kind",
+			args: args{
+				group: `{"kind": "Group", "metadata": {"name": "pg0"}, "spec": {"size": 4, "demand": {"cpu": 2}}}`,
+			},
+			wantErr: "kind:",
+		},
+		{
+			name: "unknown field",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"}, "spec": {"sise": 4}}`,
+			},
+			wantErr: "sise",
+		},
+		{
+			name: "unknown resource",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 4, "demand": {"gpu": 2}}}`,
+			},
+			wantErr: "spec.demand.gpu:",
+		},
+		{
+			name: "bad range",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 4, "demand": {"cpu": 2},
+				"level-constraints": [{"id": "lc-1", "level": 1, "affinity": "Pack", "range": {"min": 3, "max": 2}}]}}`,
+			},
+			wantErr: "spec.level-constraints[0].range.max:",
+		},
+		{
+			name: "bad toleration operator",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 4, "demand": {"cpu": 2},
+				"tolerations": [{"key": "gpu", "operator": "Exists"}, {"key": "a", "operator": "In"}]}}`,
+			},
+			wantErr: "spec.tolerations[1].operator:",
+		},
+		{
+			name: "node selector with values",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 4, "demand": {"cpu": 2},
+				"node-selector": [{"key": "gpu", "values": ["a100"]}, {"key": "network", "operator": "Exists", "values": ["ib"]}]}}`,
+			},
+			wantErr: "spec.node-selector[1].values:",
+		},
+		{
+			name: "anti-affinity to itself",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 4, "demand": {"cpu": 2},
+				"anti-affinity": [{"group": "pg0", "level": 1}]}}`,
+			},
+			wantErr: "spec.anti-affinity[0].group:",
+		},
+		{
+			name: "resource weights without weighted leftover order",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 4, "demand": {"cpu": 2},
+				"node-order": "DominantShare", "resource-weights": {"cpu": 1}}}`,
+			},
+			wantErr: "spec.resource-weights:",
+		},
+		{
+			name: "bad level name",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 4, "demand": {"cpu": 2},
+				"level-constraints": [{"id": "lc-1", "level": 1, "affinity": "Pack"},
+				{"id": "lc-2", "level-name": "zone", "affinity": "Pack"}]}}`,
+			},
+			wantErr: "spec.level-constraints[1].level-name:",
+		},
+		{
+			name: "bad affinity",
+			args: args{
+				group: `{"kind": "PlacementGroup", "metadata": {"name": "pg0"},
+				"spec": {"size": 4, "demand": {"cpu": 2},
+				"level-constraints": [{"id": "lc-1", "level": 1, "affinity": "Near"}]}}`,
+			},
+			wantErr: "spec.level-constraints[0].affinity:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CreatePGroupFromJson(tt.args.group, resourceNames, levelNames)
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("CreatePGroupFromJson() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("CreatePGroupFromJson() error = %v", err)
+				return
+			}
+			if got.GetSize() != tt.wantSize || len(got.GetLevelConstraintIDs()) != tt.wantLCIDs {
+				t.Errorf("CreatePGroupFromJson() = %v, want size %d with %d constraints", got, tt.wantSize, tt.wantLCIDs)
+			}
+			if demand := got.GetDemand().GetValue(); tt.wantDemand != nil && !reflect.DeepEqual(demand, tt.wantDemand) {
+				t.Errorf("CreatePGroupFromJson() demand = %v, want %v", demand, tt.wantDemand)
+			}
+		})
+	}
+}
+
+func TestCreatePGroupFromYaml(t *testing.T) {
+	data, err := os.ReadFile("../../samples/testGroup.yaml")
+	if err != nil {
+		t.Fatalf("error reading sample: %v", err)
+	}
+	pg, err := CreatePGroupFromYaml(string(data), resourceNames, levelNames)
+	if err != nil {
+		t.Fatalf("CreatePGroupFromYaml() error = %v", err)
+	}
+	if lc := pg.GetLevelConstraint(1); lc.GetID() != "lc-1" {
+		t.Errorf("CreatePGroupFromYaml() level 1 constraint = %v, want lc-1", lc)
+	}
+	if min, max, ok := pg.GetLevelConstraint(0).GetRange(); !ok || min != 1 || max != 2 {
+		t.Errorf("CreatePGroupFromYaml() level 0 range = [%d,%d]", min, max)
+	}
+}
@@ -0,0 +1,197 @@
+package builder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/system"
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+var (
+	// DefaultUnknownValue : label value used for nodes missing a topology label
+	// (not a valid Kubernetes label value, so no node label has it)
+	DefaultUnknownValue string = "<unknown>"
+)
+
+// LabeledNode : a node with labels and resources keyed by resource name
+type LabeledNode struct {
+	// unique name of the node
+	Name string
+	// labels of the node (e.g. topology.kubernetes.io/zone)
+	Labels map[string]string
+	// allocatable resources
+	Capacity map[string]int
+	// allocated resources
+	Allocated map[string]int
+	// scheduling state
+	State system.PEState
+	// taints
+	Taints []system.Taint
+	// maximum number of members hosted over all groups, e.g. max pods (zero if unlimited)
+	MaxMembers int
+}
+
+// LabelTreeGen : a physical tree generator from node labels
+type LabelTreeGen struct {
+	// node labels defining the levels, from the top (below root) down
+	levelLabels []string
+	// names of resources
+	resourceNames []string
+	// label value used for nodes missing a label
+	unknownValue string
+	// name of the leaf level
+	leafLevelName string
+
+	// the pTree
+	pTree *topology.PTree
+	// list of PEs
+	pes []*system.PE
+}
+
+// NewLabelTreeGen : create a new physical tree generator from node labels
+//   - levelLabels: node labels defining the levels, from the top (below root) down
+//   - resourceNames: names of resources
+func NewLabelTreeGen(levelLabels []string, resourceNames []string) *LabelTreeGen {
+	return &LabelTreeGen{
+		levelLabels:   levelLabels,
+		resourceNames: resourceNames,
+		unknownValue:  DefaultUnknownValue,
+		leafLevelName: util.DefaultLevelNames[0],
+		pTree:         nil,
+		pes:           make([]*system.PE, 0),
+	}
+}
+
+// SetUnknownValue : set the label value used for nodes missing a label
+//   - nodes with a level label of this value are rejected by CreateTree
+func (lg *LabelTreeGen) SetUnknownValue(value string) {
+	lg.unknownValue = value
+}
+
+// SetLeafLevelName : set the name of the leaf level
+func (lg *LabelTreeGen) SetLeafLevelName(name string) {
+	lg.leafLevelName = name
+}
+
+// GetPTree : get the physical tree
+func (lg *LabelTreeGen) GetPTree() *topology.PTree {
+	return lg.pTree
+}
+
+// GetPEs : get the PEs at the leaves of the physical tree
+func (lg *LabelTreeGen) GetPEs() []*system.PE {
+	return lg.pes
+}
+
+// CreateTree : create a physical tree from nodes, with a level for each level label
+// and PE leaves, e.g. levelLabels=[topology.kubernetes.io/zone, topology.kubernetes.io/rack] results into:
+// root -> ( zone-a -> ( zone-a/rack-1 -> ( node-0 node-1 ) ) <unknown> -> ( <unknown>/<unknown> -> ( node-2 ) ) )
+// where node-2 is missing both labels.
+//   - interior nodes are named by the path of label values, since values may repeat across subtrees
+//   - nodes missing a label are placed in a subtree named by the unknown value
+//   - returns error if a node has a level label of the unknown value, which would share the subtree
+//   - interior nodes are labeled by their level label and value (unless unknown), PEs by all node labels
+//   - returns error if bad parameters
+func (lg *LabelTreeGen) CreateTree(nodes []*LabeledNode) (*topology.PTree, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes")
+	}
+	numResources := len(lg.resourceNames)
+	root := topology.NewPNode(topology.NewNode(&system.Entity{ID: util.DefaultRootName}), 0, numResources)
+	interior := make(map[string]*topology.PNode)
+	names := make(map[string]bool)
+	pes := make([]*system.PE, 0, len(nodes))
+
+	// visit nodes in order of names
+	sorted := make([]*LabeledNode, len(nodes))
+	copy(sorted, nodes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	for _, node := range sorted {
+		if node == nil || len(node.Name) == 0 {
+			return nil, fmt.Errorf("node with no name")
+		}
+		if names[node.Name] {
+			return nil, fmt.Errorf("duplicate node %s", node.Name)
+		}
+		names[node.Name] = true
+
+		// find (or create) the path of interior nodes
+		parent := root
+		path := make([]string, 0, len(lg.levelLabels))
+		for _, label := range lg.levelLabels {
+			value, exists := node.Labels[label]
+			known := exists && len(value) > 0
+			if known && value == lg.unknownValue {
+				return nil, fmt.Errorf("node %s: label %s has the unknown value %s", node.Name, label, value)
+			}
+			if !known {
+				value = lg.unknownValue
+			}
+			path = append(path, value)
+			id := strings.Join(path, "/")
+			pNode := interior[id]
+			if pNode == nil {
+				if names[id] {
+					return nil, fmt.Errorf("node %s conflicts with topology node name", id)
+				}
+				entity := &system.Entity{ID: id}
+				if known {
+					entity.SetLabel(label, value)
+				}
+				pNode = topology.NewPNode(topology.NewNode(entity), 0, numResources)
+				interior[id] = pNode
+				parent.AddChild((*topology.Node)(unsafe.Pointer(pNode)))
+			}
+			parent = pNode
+		}
+		if interior[node.Name] != nil {
+			return nil, fmt.Errorf("node %s conflicts with topology node name", node.Name)
+		}
+
+		// create PE leaf
+		field := "node " + node.Name
+		capacity, err := allocationFromSpec(node.Capacity, lg.resourceNames, field+" capacity")
+		if err != nil {
+			return nil, err
+		}
+		allocated, err := allocationFromSpec(node.Allocated, lg.resourceNames, field+" allocated")
+		if err != nil {
+			return nil, err
+		}
+		pe := system.NewPE(node.Name, capacity)
+		if pe == nil {
+			return nil, fmt.Errorf("%s: invalid PE", field)
+		}
+		pe.SetAllocated(allocated)
+		pe.SetState(node.State)
+		if !pe.SetMaxLEs(node.MaxMembers) {
+			return nil, fmt.Errorf("%s: negative max members", field)
+		}
+		for key, value := range node.Labels {
+			pe.SetLabel(key, value)
+		}
+		for _, taint := range node.Taints {
+			if !pe.AddTaint(taint) {
+				return nil, fmt.Errorf("%s: taint with no key", field)
+			}
+		}
+		pes = append(pes, pe)
+		leaf := topology.NewPNode(topology.NewNode((*system.Entity)(unsafe.Pointer(pe))), 0, numResources)
+		parent.AddChild((*topology.Node)(unsafe.Pointer(leaf)))
+	}
+
+	pTree := topology.NewPTree(topology.NewTree((*topology.Node)(unsafe.Pointer(root))))
+	pTree.SetResourceNames(lg.resourceNames)
+	pTree.SetLevelNames(append(append([]string{}, lg.levelLabels...), lg.leafLevelName))
+	pTree.SetNodeLevels()
+	pTree.PercolateResources()
+	lg.pTree = pTree
+	lg.pes = pes
+	return pTree, nil
+}
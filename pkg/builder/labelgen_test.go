@@ -0,0 +1,98 @@
+package builder
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLabelTreeGen_CreateTree(t *testing.T) {
+	zone := "topology.kubernetes.io/zone"
+	rack := "topology.kubernetes.io/rack"
+	capacity := map[string]int{"cpu": 16, "memory": 128}
+	nodes := []*LabeledNode{
+		{Name: "node-0", Labels: map[string]string{zone: "a", rack: "r1"}, Capacity: capacity},
+		{Name: "node-1", Labels: map[string]string{zone: "a", rack: "r1"}, Capacity: capacity,
+			Allocated: map[string]int{"cpu": 4}},
+		{Name: "node-2", Labels: map[string]string{zone: "b", rack: "r1"}, Capacity: capacity},
+		{Name: "node-3", Labels: map[string]string{zone: "b"}, Capacity: capacity},
+		{Name: "node-4", Labels: map[string]string{}, Capacity: capacity},
+	}
+
+	tests := []struct {
+		name         string
+		unknownValue string
+		nodes        []*LabeledNode
+		wantNodeIDs  []string
+		wantErr      bool
+	}{
+		{
+			name:         "default unknown",
+			unknownValue: "",
+			nodes:        nodes,
+			wantNodeIDs: []string{"<unknown>", "<unknown>/<unknown>", "a", "a/r1", "b", "b/<unknown>", "b/r1",
+				"node-0", "node-1", "node-2", "node-3", "node-4", "root"},
+			wantErr: false,
+		},
+		{
+			name:         "custom unknown",
+			unknownValue: "none",
+			nodes:        nodes[3:],
+			wantNodeIDs:  []string{"b", "b/none", "node-3", "node-4", "none", "none/none", "root"},
+			wantErr:      false,
+		},
+		{
+			name:         "label value named unknown",
+			unknownValue: "",
+			nodes: append([]*LabeledNode{{Name: "node-5", Labels: map[string]string{zone: "unknown"},
+				Capacity: capacity}}, nodes[3:]...),
+			wantNodeIDs: []string{"<unknown>", "<unknown>/<unknown>", "b", "b/<unknown>", "node-3", "node-4",
+				"node-5", "root", "unknown", "unknown/<unknown>"},
+			wantErr: false,
+		},
+		{
+			name:         "label value of custom unknown",
+			unknownValue: "none",
+			nodes: append([]*LabeledNode{{Name: "node-5", Labels: map[string]string{zone: "none"},
+				Capacity: capacity}}, nodes[3:]...),
+			wantErr: true,
+		},
+		{
+			name:         "duplicate node",
+			unknownValue: "",
+			nodes:        []*LabeledNode{nodes[0], nodes[0]},
+			wantErr:      true,
+		},
+		{
+			name:         "unknown resource",
+			unknownValue: "",
+			nodes:        []*LabeledNode{{Name: "node-5", Capacity: map[string]int{"gpu": 1}}},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lg := NewLabelTreeGen([]string{zone, rack}, []string{"cpu", "memory"})
+			if len(tt.unknownValue) > 0 {
+				lg.SetUnknownValue(tt.unknownValue)
+			}
+			pTree, err := lg.CreateTree(tt.nodes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LabelTreeGen.CreateTree() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			got := pTree.GetNodeIDs()
+			sort.Strings(tt.wantNodeIDs)
+			if !reflect.DeepEqual(got, tt.wantNodeIDs) {
+				t.Errorf("LabelTreeGen.CreateTree() nodes = %v, want %v", got, tt.wantNodeIDs)
+			}
+			if pTree.GetHeight() != 3 || pTree.GetLevelName(2) != zone || len(lg.GetPEs()) != len(tt.nodes) {
+				t.Errorf("LabelTreeGen.CreateTree() height = %d, level names = %v", pTree.GetHeight(),
+					pTree.GetLevelNames())
+			}
+		})
+	}
+}
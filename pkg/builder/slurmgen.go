@@ -0,0 +1,275 @@
+package builder
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/system"
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+var (
+	// SlurmNodeLevelName : name of the leaf level of trees made from Slurm topology
+	SlurmNodeLevelName string = "node"
+	// SlurmSwitchLevelName : prefix of the names of switch levels of trees made from Slurm topology
+	SlurmSwitchLevelName string = "switch"
+)
+
+// slurmSwitch : a switch line in a Slurm topology.conf file
+type slurmSwitch struct {
+	name     string
+	switches []string
+	nodes    []string
+	line     int
+}
+
+// CreateTopologyTreeFromSlurm : create a PTree from a Slurm topology.conf (tree plugin),
+// e.g. "SwitchName=s0 Nodes=node[01-02]\nSwitchName=s1 Nodes=node[03-04]\nSwitchName=top Switches=s[0-1]"
+// results into: top -> ( s0 -> ( node01 node02 ) s1 -> ( node03 node04 ) )
+//   - hostlist expressions in Switches and Nodes are expanded
+//   - a single top level switch is the root, otherwise top level switches are children of a root node
+//   - all nodes are PEs with the given capacity, keyed by resource name
+//   - levels are named switch<level> and node
+//   - returns error if bad format, or nodes are not all at the same depth
+func CreateTopologyTreeFromSlurm(conf string, resourceNames []string, capacity map[string]int) (*topology.PTree, error) {
+	switches, err := parseSlurmTopology(conf)
+	if err != nil {
+		return nil, err
+	}
+	if len(switches) == 0 {
+		return nil, fmt.Errorf("no switches")
+	}
+	capacityAlloc, err := allocationFromSpec(capacity, resourceNames, "capacity")
+	if err != nil {
+		return nil, err
+	}
+
+	// find parents of switches and nodes
+	byName := make(map[string]*slurmSwitch)
+	for _, s := range switches {
+		if byName[s.name] != nil {
+			return nil, fmt.Errorf("line %d: duplicate switch %s", s.line, s.name)
+		}
+		byName[s.name] = s
+	}
+	parentOf := make(map[string]string)
+	for _, s := range switches {
+		for _, child := range append(append([]string{}, s.switches...), s.nodes...) {
+			if parent, exists := parentOf[child]; exists {
+				return nil, fmt.Errorf("line %d: %s already connected to switch %s", s.line, child, parent)
+			}
+			parentOf[child] = s.name
+		}
+		for _, child := range s.switches {
+			if byName[child] == nil {
+				return nil, fmt.Errorf("line %d: undefined switch %s", s.line, child)
+			}
+		}
+		for _, child := range s.nodes {
+			if byName[child] != nil {
+				return nil, fmt.Errorf("line %d: node %s conflicts with switch name", s.line, child)
+			}
+		}
+	}
+	tops := make([]*slurmSwitch, 0)
+	for _, s := range switches {
+		if _, exists := parentOf[s.name]; !exists {
+			tops = append(tops, s)
+		}
+	}
+	if len(tops) == 0 {
+		return nil, fmt.Errorf("no top level switch, switches form a cycle")
+	}
+
+	// make PTree
+	numResources := len(resourceNames)
+	visited := make(map[string]bool)
+	var makeSubtree func(s *slurmSwitch) (*topology.PNode, error)
+	makeSubtree = func(s *slurmSwitch) (*topology.PNode, error) {
+		if visited[s.name] {
+			return nil, fmt.Errorf("line %d: switch %s in a cycle", s.line, s.name)
+		}
+		visited[s.name] = true
+		pNode := topology.NewPNode(topology.NewNode(&system.Entity{ID: s.name}), 0, numResources)
+		for _, child := range s.switches {
+			childNode, err := makeSubtree(byName[child])
+			if err != nil {
+				return nil, err
+			}
+			pNode.AddChild((*topology.Node)(unsafe.Pointer(childNode)))
+		}
+		for _, name := range s.nodes {
+			pe := system.NewPE(name, capacityAlloc.Clone())
+			if pe == nil {
+				return nil, fmt.Errorf("line %d: invalid node %s", s.line, name)
+			}
+			leaf := topology.NewPNode(topology.NewNode((*system.Entity)(unsafe.Pointer(pe))), 0, numResources)
+			pNode.AddChild((*topology.Node)(unsafe.Pointer(leaf)))
+		}
+		return pNode, nil
+	}
+	var root *topology.PNode
+	if len(tops) == 1 {
+		if root, err = makeSubtree(tops[0]); err != nil {
+			return nil, err
+		}
+	} else {
+		root = topology.NewPNode(topology.NewNode(&system.Entity{ID: util.DefaultRootName}), 0, numResources)
+		for _, s := range tops {
+			child, err := makeSubtree(s)
+			if err != nil {
+				return nil, err
+			}
+			root.AddChild((*topology.Node)(unsafe.Pointer(child)))
+		}
+	}
+	if len(visited) != len(switches) {
+		return nil, fmt.Errorf("switches not connected to a top level switch form a cycle")
+	}
+
+	// check all nodes at the same depth
+	rootNode := (*topology.Node)(unsafe.Pointer(root))
+	leaves := rootNode.GetLeaves()
+	depths := rootNode.GetLeavesDepth()
+	for _, nv := range depths {
+		if nv.Value != depths[0].Value {
+			return nil, fmt.Errorf("nodes %s and %s at different depths %d and %d",
+				depths[0].Name, nv.Name, depths[0].Value, nv.Value)
+		}
+	}
+	for _, leaf := range leaves {
+		if _, exists := byName[leaf.GetID()]; exists {
+			return nil, fmt.Errorf("switch %s with no switches or nodes", leaf.GetID())
+		}
+	}
+
+	pTree := topology.NewPTree(topology.NewTree(rootNode))
+	height := pTree.GetHeight()
+	levelNames := make([]string, height)
+	for i := range levelNames {
+		levelNames[i] = SlurmSwitchLevelName + strconv.Itoa(height-1-i)
+	}
+	levelNames[height-1] = SlurmNodeLevelName
+	pTree.SetResourceNames(resourceNames)
+	pTree.SetLevelNames(levelNames)
+	pTree.SetNodeLevels()
+	pTree.PercolateResources()
+	return pTree, nil
+}
+
+// parseSlurmTopology : parse switch lines of a Slurm topology.conf,
+// skipping comments and joining lines continued with a backslash
+func parseSlurmTopology(conf string) ([]*slurmSwitch, error) {
+	switches := make([]*slurmSwitch, 0)
+	scanner := bufio.NewScanner(strings.NewReader(conf))
+	lineNum := 0
+	startLine := 0
+	line := ""
+	for scanner.Scan() {
+		lineNum++
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		if len(line) == 0 {
+			startLine = lineNum
+		}
+		text = strings.TrimSpace(text)
+		if strings.HasSuffix(text, "\\") {
+			line += strings.TrimSuffix(text, "\\") + " "
+			continue
+		}
+		line += text
+		if len(strings.TrimSpace(line)) > 0 {
+			s, err := parseSlurmSwitch(line, startLine)
+			if err != nil {
+				return nil, err
+			}
+			switches = append(switches, s)
+		}
+		line = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(line)) > 0 {
+		return nil, fmt.Errorf("line %d: unterminated line continuation", startLine)
+	}
+	return switches, nil
+}
+
+// parseSlurmSwitch : parse a switch line, keys are case insensitive and unused keys (e.g. LinkSpeed) are ignored
+func parseSlurmSwitch(line string, lineNum int) (*slurmSwitch, error) {
+	s := &slurmSwitch{line: lineNum}
+	for _, field := range strings.Fields(line) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 || len(kv[1]) == 0 {
+			return nil, fmt.Errorf("line %d: invalid field %q", lineNum, field)
+		}
+		var err error
+		switch strings.ToLower(kv[0]) {
+		case "switchname":
+			s.name = kv[1]
+		case "switches":
+			s.switches, err = util.ExpandHostlist(kv[1])
+		case "nodes":
+			s.nodes, err = util.ExpandHostlist(kv[1])
+		case "blockname", "blocksizes":
+			return nil, fmt.Errorf("line %d: block topology not supported", lineNum)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNum, err.Error())
+		}
+	}
+	if len(s.name) == 0 {
+		return nil, fmt.Errorf("line %d: missing SwitchName", lineNum)
+	}
+	if len(s.switches) > 0 && len(s.nodes) > 0 {
+		return nil, fmt.Errorf("line %d: switch %s with both Switches and Nodes", lineNum, s.name)
+	}
+	return s, nil
+}
+
+// ExportSlurmTopology : export a PTree as a Slurm topology.conf (tree plugin)
+//   - a switch line for each interior node, bottom up, with children as compressed hostlists
+//   - the root is omitted if named as the default root, leaving top level switches unconnected
+func ExportSlurmTopology(pTree *topology.PTree) string {
+	var b bytes.Buffer
+	if pTree == nil || pTree.GetRoot() == nil {
+		return ""
+	}
+	interior := make([]*topology.PNode, 0)
+	for _, node := range pTree.GetNodeListBFS() {
+		if node.IsLeaf() || (node.IsRoot() && node.GetID() == util.DefaultRootName) {
+			continue
+		}
+		interior = append(interior, (*topology.PNode)(unsafe.Pointer(node)))
+	}
+	sort.SliceStable(interior, func(i, j int) bool {
+		if interior[i].GetLevel() == interior[j].GetLevel() {
+			return interior[i].GetID() < interior[j].GetID()
+		}
+		return interior[i].GetLevel() < interior[j].GetLevel()
+	})
+
+	fmt.Fprintf(&b, "# topology of %d nodes and %d switches\n", len(pTree.GetLeaves()), len(interior))
+	for _, pNode := range interior {
+		children := pNode.GetChildren()
+		names := make([]string, len(children))
+		for i, child := range children {
+			names[i] = child.GetID()
+		}
+		key := "Switches"
+		if children[0].IsLeaf() {
+			key = "Nodes"
+		}
+		fmt.Fprintf(&b, "SwitchName=%s %s=%s\n", pNode.GetID(), key, util.CompressHostlist(names))
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package builder
+
+import "testing"
+
+const slurmConf = `# fat tree
+SwitchName=s0 Nodes=node[01-02] LinkSpeed=100
+SwitchName=s1 Nodes=node[03-04]
+SwitchName=s2 \
+	Nodes=node05,node06
+SwitchName=top Switches=s[0-2]
+`
+
+func TestCreateTopologyTreeFromSlurm(t *testing.T) {
+	tests := []struct {
+		name       string
+		conf       string
+		wantRoot   string
+		wantLeaves int
+		wantErr    bool
+	}{
+		{name: "single top", conf: slurmConf, wantRoot: "top", wantLeaves: 6},
+		{name: "disjoint tops", conf: "SwitchName=a Nodes=n[1-2]\nswitchname=b nodes=n[3-4]",
+			wantRoot: "root", wantLeaves: 4},
+		{name: "undefined switch", conf: "SwitchName=top Switches=s0", wantErr: true},
+		{name: "duplicate node", conf: "SwitchName=a Nodes=n1\nSwitchName=b Nodes=n1", wantErr: true},
+		{name: "uneven depth", conf: "SwitchName=a Nodes=n1\nSwitchName=top Switches=a Nodes=n2", wantErr: true},
+		{name: "cycle", conf: "SwitchName=a Switches=b\nSwitchName=b Switches=a", wantErr: true},
+		{name: "missing name", conf: "Nodes=n1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree, err := CreateTopologyTreeFromSlurm(tt.conf, []string{"cpu"}, map[string]int{"cpu": 8})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateTopologyTreeFromSlurm() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := pTree.GetRoot().GetID(); got != tt.wantRoot {
+				t.Errorf("root = %v, want %v", got, tt.wantRoot)
+			}
+			if got := len(pTree.GetPEs()); got != tt.wantLeaves {
+				t.Errorf("number of PEs = %v, want %v", got, tt.wantLeaves)
+			}
+			if got := pTree.GetLevelName(0); got != SlurmNodeLevelName {
+				t.Errorf("leaf level name = %v, want %v", got, SlurmNodeLevelName)
+			}
+		})
+	}
+}
+
+func TestExportSlurmTopology(t *testing.T) {
+	pTree, err := CreateTopologyTreeFromSlurm(slurmConf, []string{"cpu"}, map[string]int{"cpu": 8})
+	if err != nil {
+		t.Fatalf("CreateTopologyTreeFromSlurm() error = %v", err)
+	}
+	want := "# topology of 6 nodes and 4 switches\n" +
+		"SwitchName=s0 Nodes=node[01-02]\n" +
+		"SwitchName=s1 Nodes=node[03-04]\n" +
+		"SwitchName=s2 Nodes=node[05-06]\n" +
+		"SwitchName=top Switches=s[0-2]\n"
+	got := ExportSlurmTopology(pTree)
+	if got != want {
+		t.Errorf("ExportSlurmTopology() = %v, want %v", got, want)
+	}
+
+	// round trip
+	pTree2, err := CreateTopologyTreeFromSlurm(got, []string{"cpu"}, map[string]int{"cpu": 8})
+	if err != nil {
+		t.Fatalf("CreateTopologyTreeFromSlurm() of export error = %v", err)
+	}
+	if pTree.GetRoot().String() != pTree2.GetRoot().String() {
+		t.Errorf("round trip tree = %v, want %v", pTree2, pTree)
+	}
+}
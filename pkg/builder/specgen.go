@@ -0,0 +1,106 @@
+package builder
+
+import (
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/placement"
+	"github.com/ibm/chic-sched/pkg/system"
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// PlacementToSpec : create a placement spec of a placement group
+func PlacementToSpec(pg *placement.PGroup) *util.JPlacement {
+	spec := &util.JPlacement{
+		Group: pg.GetID(),
+		Size:  pg.GetSize(),
+	}
+	if lTree := pg.GetLTree(); lTree != nil && lTree.GetRoot() != nil {
+		lRoot := (*topology.LNode)(unsafe.Pointer(lTree.GetRoot()))
+		spec.Placed = lRoot.GetCount()
+		spec.FullyPlaced = pg.IsFullyPlaced()
+		spec.LTree = lNodeToSpec(lRoot)
+	}
+	if bindings := pg.GetBindings(); len(bindings) > 0 {
+		spec.Bindings = bindings
+	}
+	return spec
+}
+
+// lNodeToSpec : create a spec of the logical subtree rooted at a node
+func lNodeToSpec(lNode *topology.LNode) *util.JLNode {
+	spec := &util.JLNode{
+		ID:      lNode.GetID(),
+		Count:   lNode.GetCount(),
+		Claimed: lNode.GetClaimed(),
+	}
+	for _, child := range lNode.GetSortedChildren() {
+		spec.Children = append(spec.Children, lNodeToSpec((*topology.LNode)(unsafe.Pointer(child))))
+	}
+	return spec
+}
+
+// PTreeToSpec : create a spec of a physical tree with the resources of its nodes
+func PTreeToSpec(pTree *topology.PTree) *util.JPNode {
+	if pTree == nil || pTree.GetRoot() == nil {
+		return nil
+	}
+	return pNodeToSpec(pTree, (*topology.PNode)(unsafe.Pointer(pTree.GetRoot())))
+}
+
+// pNodeToSpec : create a spec of the physical subtree rooted at a node
+func pNodeToSpec(pTree *topology.PTree, pNode *topology.PNode) *util.JPNode {
+	resourceNames := pTree.GetResourceNames()
+	spec := &util.JPNode{
+		ID:         pNode.GetID(),
+		Level:      pTree.GetLevelName(pNode.GetLevel()),
+		Capacity:   allocationToSpec(pNode.GetCapacity(), resourceNames),
+		Allocated:  allocationToSpec(pNode.GetAllocated(), resourceNames),
+		ReservedBy: pNode.GetReservedBy(),
+	}
+	if maxAllocated := allocationToSpec(pNode.GetMaxAllocated(), resourceNames); maxAllocated != nil {
+		spec.MaxAlloc = make(map[string]int)
+		for name, value := range maxAllocated {
+			if value != topology.Unlimited {
+				spec.MaxAlloc[name] = value
+			}
+		}
+	}
+	if labels := pNode.Entity.GetLabels(); len(labels) > 0 {
+		spec.Labels = labels
+	}
+	// leaves are PEs if there are resources
+	if pNode.IsLeaf() && pNode.GetNumResources() > 0 {
+		pe := (*system.PE)(unsafe.Pointer(pNode.Entity))
+		if pe.GetState() != system.Schedulable {
+			spec.State = system.PEStateToString(pe.GetState())
+		}
+		for _, taint := range pe.GetTaints() {
+			spec.Taints = append(spec.Taints, util.JTaint{Key: taint.Key, Value: taint.Value})
+		}
+		spec.Reserved = allocationToSpec(pe.GetReserved(), resourceNames)
+		spec.MaxMembers = pe.GetMaxLEs()
+		if ratios := pe.GetOvercommit(); len(ratios) == len(resourceNames) {
+			spec.Overcommit = make(map[string]float64)
+			for i, r := range ratios {
+				spec.Overcommit[resourceNames[i]] = r
+			}
+		}
+	}
+	for _, child := range pNode.GetSortedChildren() {
+		spec.Children = append(spec.Children, pNodeToSpec(pTree, (*topology.PNode)(unsafe.Pointer(child))))
+	}
+	return spec
+}
+
+// allocationToSpec : create a map of values keyed by resource name (nil if unequal lengths)
+func allocationToSpec(alloc *util.Allocation, resourceNames []string) map[string]int {
+	if alloc == nil || alloc.GetSize() != len(resourceNames) {
+		return nil
+	}
+	values := make(map[string]int)
+	for i, value := range alloc.GetValue() {
+		values[resourceNames[i]] = value
+	}
+	return values
+}
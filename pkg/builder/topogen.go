@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"unsafe"
 
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
 	"github.com/ibm/chic-sched/pkg/system"
 	"github.com/ibm/chic-sched/pkg/topology"
 	"github.com/ibm/chic-sched/pkg/util"
 )
 
 // CreateTopologyTreeFromJson : create a PTree fron JSON string
+//   - leaves are PEs with resource capacity and allocated as given in the spec (zero if unspecified)
 func CreateTopologyTreeFromJson(topologyTreeString string) (pTree *topology.PTree, err error) {
 	var topologyTree util.TopologyTree
 	err = json.Unmarshal([]byte(topologyTreeString), &topologyTree)
@@ -19,45 +23,355 @@ func CreateTopologyTreeFromJson(topologyTreeString string) (pTree *topology.PTre
 	}
 
 	// process kind field
-	fmt.Println("kind=" + topologyTree.Kind)
+	klog.V(4).Infoln("kind=" + topologyTree.Kind)
 	if topologyTree.Kind != util.DefaultTreeKind {
 		return nil, fmt.Errorf("invalid kind: %s", topologyTree.Kind)
 	}
 
 	// process tree name field
 	treeName := topologyTree.MetaData.Name
-	fmt.Println("treeName=" + treeName)
+	klog.V(4).Infoln("treeName=" + treeName)
 
 	// process topology levels
 	resourceNames := make([]string, len(topologyTree.Spec.ResourceNames))
 	copy(resourceNames, topologyTree.Spec.ResourceNames)
 	numResources := len(resourceNames)
-	fmt.Println("numResources=", numResources)
-	fmt.Println("resourceNames=", resourceNames)
+	klog.V(4).Infoln("numResources=", numResources)
+	klog.V(4).Infoln("resourceNames=", resourceNames)
 
 	levelNames := make([]string, len(topologyTree.Spec.LevelNames))
 	copy(levelNames, topologyTree.Spec.LevelNames)
-	fmt.Println("numLevels=", len(levelNames))
-	fmt.Println("levelNames=", levelNames)
+	klog.V(4).Infoln("numLevels=", len(levelNames))
+	klog.V(4).Infoln("levelNames=", levelNames)
 
 	// make PTree
-	root := topology.NewPNode(topology.NewNode(&system.Entity{ID: "root"}), 0, numResources)
-	MakeSubtreeFromSpec(root, topologyTree.Spec.Tree)
+	rootEntity := &system.Entity{ID: util.DefaultRootName}
+	if err := setLabelsFromSpec(rootEntity, topologyTree.Spec.Tree.Labels, "spec.tree"); err != nil {
+		return nil, err
+	}
+	root := topology.NewPNode(topology.NewNode(rootEntity), 0, numResources)
+	if err := setMaxAllocatedFromSpec(root, topologyTree.Spec.Tree.MaxAlloc, resourceNames, "spec.tree"); err != nil {
+		return nil, err
+	}
+	if err := makeSubtreeFromSpec(root, topologyTree.Spec.Tree, resourceNames, "spec.tree"); err != nil {
+		return nil, err
+	}
 	pTree = topology.NewPTree(topology.NewTree((*topology.Node)(unsafe.Pointer(root))))
+	pTree.SetResourceNames(resourceNames)
+	pTree.SetLevelNames(levelNames)
 	pTree.SetNodeLevels()
-	fmt.Println("pTree = ", pTree)
+	if numResources > 0 {
+		pTree.PercolateResources()
+	}
+	if pTree.HasDevices() {
+		expanded, err := pTree.ExpandDevices(topologyTree.Spec.DeviceLevelNames)
+		if err != nil {
+			return nil, fieldError("spec.tree", "%s", err.Error())
+		}
+		pTree = expanded
+	}
+	// maximum of leaves overrides that of the tree
+	if n := topologyTree.Spec.MaxMembers; !pTree.SetMaxLEs(n) {
+		return nil, fieldError("spec.max-members", "max-members %d must not be negative", n)
+	}
+	if len(topologyTree.Spec.Overcommit) > 0 {
+		ratios, err := ratiosFromSpec(topologyTree.Spec.Overcommit, resourceNames, "spec.overcommit")
+		if err != nil {
+			return nil, err
+		}
+		// ratios of leaves override those of the tree
+		own := make(map[string][]float64)
+		for id, pe := range pTree.GetPEs() {
+			if pe.GetOvercommit() != nil {
+				own[id] = pe.GetOvercommit()
+			}
+		}
+		if err := pTree.SetOvercommit(ratios); err != nil {
+			return nil, fieldError("spec.overcommit", "%s", err.Error())
+		}
+		for id, r := range own {
+			pTree.SetPEOvercommit(id, r)
+		}
+	}
+	klog.V(4).Infoln("pTree = ", pTree)
 	return pTree, nil
 }
 
+// CreateTopologyTreeFromYaml : create a PTree from YAML string;
+// the tree may also be wrapped in a ConfigMap as its single data entry
+func CreateTopologyTreeFromYaml(topologyTreeString string) (pTree *topology.PTree, err error) {
+	jsonTree, err := yaml.YAMLToJSON([]byte(topologyTreeString))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing tree: %s", err.Error())
+	}
+	var configMap struct {
+		Kind string            `json:"kind"`
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(jsonTree, &configMap); err == nil && configMap.Kind == "ConfigMap" {
+		if len(configMap.Data) != 1 {
+			return nil, fmt.Errorf("data: expected a single entry in ConfigMap, found %d", len(configMap.Data))
+		}
+		for _, value := range configMap.Data {
+			return CreateTopologyTreeFromYaml(value)
+		}
+	}
+	return CreateTopologyTreeFromJson(string(jsonTree))
+}
+
 // MakeSubtreeFromSpec : make a substree rooted at a node given tree spec level hierarchy,
 // without setting level values
 func MakeSubtreeFromSpec(pNode *topology.PNode, spec util.TreeSpec) {
+	makeSubtreeFromSpec(pNode, spec, nil, "")
+}
+
+// makeSubtreeFromSpec : make a substree rooted at a node given tree spec level hierarchy,
+// leaves are created as PEs with resources given by name
+//   - field: the spec field of the node, used in error messages
+func makeSubtreeFromSpec(pNode *topology.PNode, spec util.TreeSpec, resourceNames []string, field string) error {
 	numResources := pNode.GetNumResources()
 	for childName, childSpec := range spec.Level {
-		child := topology.NewPNode(topology.NewNode(&system.Entity{ID: childName}), 0, numResources)
+		childField := field + ".level." + childName
+		var entity *system.Entity
+		if len(childSpec.Level) == 0 && numResources > 0 {
+			pe, err := makePEFromSpec(childName, childSpec, resourceNames, childField)
+			if err != nil {
+				return err
+			}
+			entity = (*system.Entity)(unsafe.Pointer(pe))
+		} else {
+			if len(childSpec.Capacity) > 0 || len(childSpec.Allocated) > 0 || len(childSpec.Devices) > 0 {
+				return fieldError(childField, "resources and devices may only be specified on leaves")
+			}
+			if len(childSpec.State) > 0 || len(childSpec.Taints) > 0 || len(childSpec.Overcommit) > 0 ||
+				len(childSpec.Reserved) > 0 || childSpec.MaxMembers != 0 {
+				return fieldError(childField,
+					"state, taints, overcommit, reserved, and max-members may only be specified on leaves")
+			}
+			entity = &system.Entity{ID: childName}
+		}
+		if err := setLabelsFromSpec(entity, childSpec.Labels, childField); err != nil {
+			return err
+		}
+		child := topology.NewPNode(topology.NewNode(entity), 0, numResources)
+		if child == nil {
+			return fieldError(childField, "invalid node name")
+		}
+		if err := setMaxAllocatedFromSpec(child, childSpec.MaxAlloc, resourceNames, childField); err != nil {
+			return err
+		}
 		pNode.AddChild((*topology.Node)(unsafe.Pointer(child)))
-		MakeSubtreeFromSpec(child, childSpec)
+		if err := makeSubtreeFromSpec(child, childSpec, resourceNames, childField); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makePEFromSpec : make a PE given a leaf spec
+func makePEFromSpec(id string, spec util.TreeSpec, resourceNames []string, field string) (*system.PE, error) {
+	if len(spec.Devices) > 0 {
+		return makePEWithDevicesFromSpec(id, spec, resourceNames, field)
+	}
+	capacity, err := allocationFromSpec(spec.Capacity, resourceNames, field+".capacity")
+	if err != nil {
+		return nil, err
+	}
+	allocated, err := allocationFromSpec(spec.Allocated, resourceNames, field+".allocated")
+	if err != nil {
+		return nil, err
+	}
+	pe := system.NewPE(id, capacity)
+	if pe == nil {
+		return nil, fieldError(field, "invalid PE")
+	}
+	pe.SetAllocated(allocated)
+	if len(spec.State) > 0 {
+		state, ok := system.StringToPEState(spec.State)
+		if !ok {
+			return nil, fieldError(field+".state", "invalid state %q, expected Schedulable, Cordoned, Draining, or Failed",
+				spec.State)
+		}
+		pe.SetState(state)
+	}
+	for i, jt := range spec.Taints {
+		if !pe.AddTaint(system.Taint{Key: jt.Key, Value: jt.Value}) {
+			return nil, fieldError(fmt.Sprintf("%s.taints[%d].key", field, i), "missing key")
+		}
+	}
+	if len(spec.Overcommit) > 0 {
+		ratios, err := ratiosFromSpec(spec.Overcommit, resourceNames, field+".overcommit")
+		if err != nil {
+			return nil, err
+		}
+		pe.SetOvercommit(ratios)
+	}
+	if len(spec.Reserved) > 0 {
+		reserved, err := allocationFromSpec(spec.Reserved, resourceNames, field+".reserved")
+		if err != nil {
+			return nil, err
+		}
+		pe.SetReserved(reserved)
+	}
+	if !pe.SetMaxLEs(spec.MaxMembers) {
+		return nil, fieldError(field+".max-members", "max-members %d must not be negative", spec.MaxMembers)
+	}
+	return pe, nil
+}
+
+// makePEWithDevicesFromSpec : make a PE given a leaf spec with devices,
+// where the resources of the PE are those of its leaf devices
+func makePEWithDevicesFromSpec(id string, spec util.TreeSpec, resourceNames []string,
+	field string) (*system.PE, error) {
+	if len(spec.Capacity) > 0 || len(spec.Allocated) > 0 || len(spec.Overcommit) > 0 || len(spec.Reserved) > 0 ||
+		spec.MaxMembers != 0 {
+		return nil, fieldError(field, "resources and max-members of a leaf with devices may only be specified on its devices")
+	}
+	capacity, _ := util.NewAllocation(len(resourceNames))
+	pe := system.NewPE(id, capacity)
+	if pe == nil {
+		return nil, fieldError(field, "invalid PE")
+	}
+	if len(spec.State) > 0 {
+		state, ok := system.StringToPEState(spec.State)
+		if !ok {
+			return nil, fieldError(field+".state", "invalid state %q, expected Schedulable, Cordoned, Draining, or Failed",
+				spec.State)
+		}
+		pe.SetState(state)
+	}
+	for i, jt := range spec.Taints {
+		if !pe.AddTaint(system.Taint{Key: jt.Key, Value: jt.Value}) {
+			return nil, fieldError(fmt.Sprintf("%s.taints[%d].key", field, i), "missing key")
+		}
+	}
+	devices, err := makeDevicesFromSpec(id, spec.Devices, resourceNames, field+".devices")
+	if err != nil {
+		return nil, err
+	}
+	if err := pe.SetDevices(devices); err != nil {
+		return nil, fieldError(field+".devices", "%s", err.Error())
+	}
+	return pe, nil
+}
+
+// makeDevicesFromSpec : make devices of a PE given their specs, where leaf devices are PEs
+// with IDs qualified by the ID of the PE
+func makeDevicesFromSpec(peID string, specs map[string]util.TreeSpec, resourceNames []string,
+	field string) ([]*system.Device, error) {
+	devices := make([]*system.Device, 0, len(specs))
+	for name, spec := range specs {
+		deviceField := field + "." + name
+		id := system.DeviceID(peID, name)
+		if len(spec.Devices) > 0 {
+			return nil, fieldError(deviceField+".devices", "devices may not have devices, use levels instead")
+		}
+		var device *system.Device
+		var entity *system.Entity
+		if len(spec.Level) == 0 {
+			pe, err := makePEFromSpec(id, spec, resourceNames, deviceField)
+			if err != nil {
+				return nil, err
+			}
+			device = system.NewLeafDevice(pe)
+			entity = &pe.Entity
+		} else {
+			if len(spec.Capacity) > 0 || len(spec.Allocated) > 0 || len(spec.State) > 0 || len(spec.Taints) > 0 ||
+				len(spec.Overcommit) > 0 || len(spec.Reserved) > 0 || spec.MaxMembers != 0 {
+				return nil, fieldError(deviceField,
+					"resources, state, taints, and max-members may only be specified on leaf devices")
+			}
+			children, err := makeDevicesFromSpec(peID, spec.Level, resourceNames, deviceField+".level")
+			if err != nil {
+				return nil, err
+			}
+			device = system.NewDevice(id, children...)
+			entity = &device.Entity
+		}
+		if err := setLabelsFromSpec(entity, spec.Labels, deviceField); err != nil {
+			return nil, err
+		}
+		if len(spec.MaxAlloc) > 0 {
+			return nil, fieldError(deviceField+".max-allocated", "maximum allocated not supported on devices")
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// setMaxAllocatedFromSpec : set the maximum allocated of a node given values keyed by resource name
+// (unlimited if unspecified)
+func setMaxAllocatedFromSpec(pNode *topology.PNode, values map[string]int, resourceNames []string,
+	field string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	if pNode.GetNumResources() == 0 {
+		return fieldError(field+".max-allocated", "no resources in tree")
+	}
+	maxAllocated, err := allocationFromSpec(values, resourceNames, field+".max-allocated")
+	if err != nil {
+		return err
+	}
+	x := maxAllocated.GetValue()
+	for i, name := range resourceNames {
+		if _, exists := values[name]; !exists {
+			x[i] = topology.Unlimited
+		}
+	}
+	maxAllocated.SetValue(x)
+	pNode.SetMaxAllocated(maxAllocated)
+	return nil
+}
+
+// ratiosFromSpec : make overcommit ratios ordered by resource names given ratios keyed by name
+// (ratio of 1 if unspecified)
+func ratiosFromSpec(values map[string]float64, resourceNames []string, field string) ([]float64, error) {
+	index := make(map[string]int)
+	ratios := make([]float64, len(resourceNames))
+	for i, name := range resourceNames {
+		index[name] = i
+		ratios[i] = 1
+	}
+	for name, r := range values {
+		i, exists := index[name]
+		if !exists {
+			return nil, fieldError(field+"."+name, "unknown resource, expected one of %v", resourceNames)
+		}
+		if r <= 0 {
+			return nil, fieldError(field+"."+name, "ratio %v must be positive", r)
+		}
+		ratios[i] = r
+	}
+	return ratios, nil
+}
+
+// setLabelsFromSpec : set the labels of an entity given a spec of labels
+func setLabelsFromSpec(entity *system.Entity, labels map[string]string, field string) error {
+	for key, value := range labels {
+		if !entity.SetLabel(key, value) {
+			return fieldError(field+".labels", "label with no key")
+		}
+	}
+	return nil
+}
+
+// allocationFromSpec : make an allocation ordered by resource names given values keyed by name
+func allocationFromSpec(values map[string]int, resourceNames []string, field string) (*util.Allocation, error) {
+	na, err := util.NewNamedAllocation(resourceNames, make([]int, len(resourceNames)))
+	if err != nil {
+		return nil, fieldError(field, "%s", err.Error())
+	}
+	for name, value := range values {
+		if _, exists := na.Get(name); !exists {
+			return nil, fieldError(field+"."+name, "unknown resource, expected one of %v", resourceNames)
+		}
+		if value < 0 {
+			return nil, fieldError(field+"."+name, "value %d must not be negative", value)
+		}
+		na.Set(name, value)
 	}
+	return na.GetAllocation(), nil
 }
 
 // CreateFlatTopology : create a flat PTree
@@ -0,0 +1,93 @@
+package builder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/topology"
+)
+
+func TestCreateTopologyTree_Quantities(t *testing.T) {
+	tests := []struct {
+		name string
+		tree string
+		yaml bool
+		// capacity and allocated of the root, ordered by resource names
+		wantCapacity  []int
+		wantAllocated []int
+		wantErr       string
+	}{
+		{
+			name: "json numbers and quantities",
+			tree: `{"kind": "TopologyTree", "metadata": {"name": "t"},
+			"spec": {"resource-names": ["cpu", "memory"], "level-names": ["server"],
+			"tree": {"level": {
+				"server-0": {"capacity": {"cpu": 4, "memory": "64Gi"}, "allocated": {"memory": "8Gi"}},
+				"server-1": {"capacity": {"cpu": 4, "memory": 68719476736}}}}}}`,
+			wantCapacity:  []int{8, 128 << 30},
+			wantAllocated: []int{0, 8 << 30},
+		},
+		{
+			name: "yaml numbers and quantities",
+			tree: `kind: TopologyTree
+metadata:
+  name: t
+spec:
+  resource-names: [cpu, memory]
+  level-names: [server]
+  tree:
+    level:
+      server-0:
+        capacity: {cpu: 4, memory: 64Gi}
+        allocated: {cpu: 1, memory: 512Mi}
+      server-1:
+        capacity: {cpu: 4, memory: "1.5Gi"}`,
+			yaml:          true,
+			wantCapacity:  []int{8, 64<<30 + 3<<29},
+			wantAllocated: []int{1, 512 << 20},
+		},
+		{
+			name: "bad quantity",
+			tree: `{"kind": "TopologyTree", "metadata": {"name": "t"},
+			"spec": {"resource-names": ["cpu", "memory"], "level-names": ["server"],
+			"tree": {"level": {"server-0": {"capacity": {"cpu": 4, "memory": "64 Gi"}}}}}}`,
+			wantErr: "resource memory: invalid quantity",
+		},
+		{
+			name: "negative quantity",
+			tree: `{"kind": "TopologyTree", "metadata": {"name": "t"},
+			"spec": {"resource-names": ["cpu", "memory"], "level-names": ["server"],
+			"tree": {"level": {"server-0": {"capacity": {"cpu": 4, "memory": "-1Gi"}}}}}}`,
+			wantErr: "resource memory: invalid quantity",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pTree *topology.PTree
+			var err error
+			if tt.yaml {
+				pTree, err = CreateTopologyTreeFromYaml(tt.tree)
+			} else {
+				pTree, err = CreateTopologyTreeFromJson(tt.tree)
+			}
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("CreateTopologyTree() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateTopologyTree() error = %v", err)
+			}
+			root := (*topology.PNode)(unsafe.Pointer(pTree.GetRoot()))
+			if got := root.GetCapacity().GetValue(); !reflect.DeepEqual(got, tt.wantCapacity) {
+				t.Errorf("root capacity = %v, want %v", got, tt.wantCapacity)
+			}
+			if got := root.GetAllocated().GetValue(); !reflect.DeepEqual(got, tt.wantAllocated) {
+				t.Errorf("root allocated = %v, want %v", got, tt.wantAllocated)
+			}
+		})
+	}
+}
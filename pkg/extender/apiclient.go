@@ -0,0 +1,153 @@
+package extender
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// ServiceAccountDir : directory of the service account credentials of pods running in a cluster
+	ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	// PodSucceeded : phase of a pod whose containers all terminated successfully
+	PodSucceeded = "Succeeded"
+	// PodFailed : phase of a pod whose containers all terminated, some in failure
+	PodFailed = "Failed"
+)
+
+// APIClient : a minimal client of the Kubernetes API server, binding pods to nodes and listing pods
+type APIClient struct {
+	// URL of the API server
+	server string
+	// bearer token (empty if none)
+	token string
+	// HTTP client
+	client *http.Client
+}
+
+// NewAPIClient : create a client of the API server at a given URL
+//   - token: bearer token (empty if none)
+//   - caFile: file of the certificate authority of the server (system roots if empty)
+//   - returns error if bad URL or certificate authority
+func NewAPIClient(server string, token string, caFile string) (*APIClient, error) {
+	if _, err := url.ParseRequestURI(server); err != nil {
+		return nil, fmt.Errorf("API server %q: %s", server, err.Error())
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(caFile) > 0 {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &APIClient{
+		server: strings.TrimSuffix(server, "/"),
+		token:  token,
+		client: &http.Client{Transport: transport, Timeout: 30 * time.Second},
+	}, nil
+}
+
+// NewInClusterAPIClient : create a client of the API server of the cluster the extender runs in,
+// with the credentials of its service account
+//   - returns error if not running in a cluster
+func NewInClusterAPIClient() (*APIClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if len(host) == 0 || len(port) == 0 {
+		return nil, fmt.Errorf("not running in a cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT not set")
+	}
+	token, err := os.ReadFile(ServiceAccountDir + "/token")
+	if err != nil {
+		return nil, err
+	}
+	return NewAPIClient("https://"+net.JoinHostPort(host, port), strings.TrimSpace(string(token)),
+		ServiceAccountDir+"/ca.crt")
+}
+
+// binding : a binding of a pod to a node (v1.Binding)
+type binding struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Metadata   ObjectMeta      `json:"metadata"`
+	Target     objectReference `json:"target"`
+}
+
+// objectReference : a reference to an object (v1.ObjectReference)
+type objectReference struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// Bind : bind a pod to a node by creating a binding of the pod
+func (c *APIClient) Bind(args *ExtenderBindingArgs) error {
+	b := &binding{
+		APIVersion: "v1",
+		Kind:       "Binding",
+		Metadata:   ObjectMeta{Name: args.PodName, Namespace: args.PodNamespace, UID: args.PodUID},
+		Target:     objectReference{Kind: "Node", Name: args.Node},
+	}
+	path := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/binding",
+		url.PathEscape(args.PodNamespace), url.PathEscape(args.PodName))
+	return c.do(http.MethodPost, path, b, nil)
+}
+
+// ListPods : list the pods of all namespaces
+func (c *APIClient) ListPods() ([]Pod, error) {
+	var list PodList
+	if err := c.do(http.MethodGet, "/api/v1/pods", nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// do : send a request to the API server, decoding the response into result (if not nil)
+func (c *APIClient) do(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.server+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if len(c.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if result != nil {
+		return json.Unmarshal(data, result)
+	}
+	return nil
+}
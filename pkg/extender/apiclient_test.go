@@ -0,0 +1,62 @@
+package extender
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIClient(t *testing.T) {
+	var gotBinding binding
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/namespaces/default/pods/train-0/binding":
+			if err := json.NewDecoder(r.Body).Decode(&gotBinding); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/pods":
+			w.Write([]byte(`{"kind": "PodList", "items": [{"metadata": {"name": "train-0", "uid": "uid-0"},
+				"spec": {"nodeName": "node-a0"}, "status": {"phase": "Running"}}]}`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	if _, err := NewAPIClient("not a url", "", ""); err == nil {
+		t.Errorf("NewAPIClient() bad URL error = nil, want error")
+	}
+	c, err := NewAPIClient(server.URL+"/", "secret", "")
+	if err != nil {
+		t.Fatalf("NewAPIClient() error = %v", err)
+	}
+	args := &ExtenderBindingArgs{PodName: "train-0", PodNamespace: "default", PodUID: "uid-0", Node: "node-a0"}
+	if err := c.Bind(args); err != nil {
+		t.Errorf("Bind() error = %v", err)
+	}
+	if gotBinding.Kind != "Binding" || gotBinding.Metadata.UID != "uid-0" || gotBinding.Target.Name != "node-a0" {
+		t.Errorf("Bind() posted %+v", gotBinding)
+	}
+	pods, err := c.ListPods()
+	if err != nil || len(pods) != 1 || pods[0].Spec.NodeName != "node-a0" || pods[0].Status.Phase != "Running" {
+		t.Errorf("ListPods() = %+v, %v", pods, err)
+	}
+
+	// errors of the API server are returned with their status
+	args.PodName = "unknown"
+	if err := c.Bind(args); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("Bind() unknown pod error = %v, want status 404", err)
+	}
+	unauthorized, _ := NewAPIClient(server.URL, "", "")
+	if _, err := unauthorized.ListPods(); err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Errorf("ListPods() without token error = %v, want status 401", err)
+	}
+}
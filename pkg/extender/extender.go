@@ -0,0 +1,594 @@
+package extender
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+	"unsafe"
+
+	"k8s.io/klog/v2"
+
+	"github.com/ibm/chic-sched/pkg/builder"
+	"github.com/ibm/chic-sched/pkg/placement"
+	"github.com/ibm/chic-sched/pkg/system"
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+const (
+	// GroupAnnotation : pod annotation naming the placement group of the pod
+	GroupAnnotation = "chic-sched.ibm.com/placement-group"
+	// GroupSpecAnnotation : pod annotation holding the (JSON) PlacementGroup spec of the group,
+	// needed on the first pod of the group to be scheduled; the demand defaults to the pod requests
+	GroupSpecAnnotation = "chic-sched.ibm.com/placement-group-spec"
+	// HostnameLabel : node label of the leaf level
+	HostnameLabel = "kubernetes.io/hostname"
+	// DefaultPlanTimeout : time after which placement plans and filtered pods not bound are forgotten
+	DefaultPlanTimeout = 5 * time.Minute
+)
+
+var (
+	// DefaultLevelLabels : node labels defining the topology levels, from the top down
+	DefaultLevelLabels []string = []string{"topology.kubernetes.io/region", "topology.kubernetes.io/zone"}
+	// DefaultResourceNames : resources considered for placement
+	DefaultResourceNames []string = []string{"cpu", "memory"}
+)
+
+// Binder : binds a pod to a node (e.g. through the Kubernetes API)
+type Binder interface {
+	Bind(args *ExtenderBindingArgs) error
+}
+
+// Extender : a Kubernetes scheduler extender placing groups of pods
+//   - the filter verb places the group of the pod (once per group) on a PTree built from the
+//     topology labels of the candidate nodes, and keeps the nodes with members of the group left to bind
+//   - the prioritize verb scores nodes by the number of members left to bind
+//   - the bind verb binds a pod to a node of the placement of its group
+//   - the delete verb releases the resources of a deleted pod
+//
+// Resources allocated on nodes are those of the members of active placements left to bind,
+// of bound pods until they are deleted, and of the pods running on the nodes as last synchronized
+// with the API server; pods which do not belong to a group pass through unfiltered.
+// Placements and filtered pods not bound within the plan timeout are forgotten.
+type Extender struct {
+	// lock protecting state
+	mu sync.Mutex
+	// node labels defining the topology levels, from the top down
+	levelLabels []string
+	// resources considered for placement
+	resourceNames []string
+	// binder of pods (nil if pods are bound by the caller of the bind verb)
+	binder Binder
+
+	// time after which placement plans and filtered pods not bound are forgotten
+	planTimeout time.Duration
+	// current time (replaceable for testing)
+	now func() time.Time
+
+	// placement plans mapped to group names
+	plans map[string]*groupPlan
+	// filtered pods not bound mapped to pod UIDs
+	pods map[string]*filteredPod
+	// pods bound since the last synchronization mapped to pod UIDs
+	bound map[string]*boundPod
+	// pods running on nodes as of the last synchronization mapped to pod UIDs
+	running map[string]*boundPod
+}
+
+// filteredPod : a pod of a group filtered and not bound
+type filteredPod struct {
+	// name of the group
+	groupName string
+	// time last filtered
+	filtered time.Time
+}
+
+// boundPod : a pod bound to a node
+type boundPod struct {
+	// name of the node
+	node string
+	// resource demand of the pod
+	demand *util.NamedAllocation
+	// time bound
+	bound time.Time
+}
+
+// groupPlan : placement of a group on nodes
+type groupPlan struct {
+	// the placement group
+	pg *placement.PGroup
+	// resource demand of a member
+	demand *util.NamedAllocation
+	// number of members left to bind mapped to node names
+	slots map[string]int
+	// number of members placed mapped to node names
+	placed map[string]int
+	// node names of bound pods mapped to pod UIDs
+	bound map[string]string
+	// time of the last placement or binding
+	updated time.Time
+}
+
+// NewExtender : create a new scheduler extender
+//   - levelLabels: node labels defining the topology levels, from the top down (default if empty)
+//   - resourceNames: resources considered for placement (default if empty)
+func NewExtender(levelLabels []string, resourceNames []string) *Extender {
+	if len(levelLabels) == 0 {
+		levelLabels = DefaultLevelLabels
+	}
+	if len(resourceNames) == 0 {
+		resourceNames = DefaultResourceNames
+	}
+	return &Extender{
+		levelLabels:   levelLabels,
+		resourceNames: resourceNames,
+		binder:        nil,
+		planTimeout:   DefaultPlanTimeout,
+		now:           time.Now,
+		plans:         make(map[string]*groupPlan),
+		pods:          make(map[string]*filteredPod),
+		bound:         make(map[string]*boundPod),
+		running:       make(map[string]*boundPod),
+	}
+}
+
+// SetBinder : set the binder of pods
+func (e *Extender) SetBinder(binder Binder) {
+	e.binder = binder
+}
+
+// SetPlanTimeout : set the time after which placement plans and filtered pods not bound are forgotten
+func (e *Extender) SetPlanTimeout(timeout time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.planTimeout = timeout
+}
+
+// Filter : filter the candidate nodes of a pod
+func (e *Extender) Filter(args *ExtenderArgs) *ExtenderFilterResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if args.Pod == nil || args.Nodes == nil {
+		return &ExtenderFilterResult{Error: "pod and nodes required (nodeCacheCapable not supported)"}
+	}
+	e.expire()
+	groupName := args.Pod.Annotations[GroupAnnotation]
+	if len(groupName) == 0 {
+		return &ExtenderFilterResult{Nodes: args.Nodes}
+	}
+	plan, err := e.getPlan(groupName, args.Pod, args.Nodes.Items)
+	if err != nil {
+		return &ExtenderFilterResult{Error: err.Error()}
+	}
+	e.pods[args.Pod.UID] = &filteredPod{groupName: groupName, filtered: e.now()}
+
+	result := &ExtenderFilterResult{
+		Nodes:       &NodeList{Items: make([]Node, 0)},
+		FailedNodes: make(map[string]string),
+	}
+	for _, node := range args.Nodes.Items {
+		if plan.slots[node.Name] > 0 {
+			result.Nodes.Items = append(result.Nodes.Items, node)
+		} else {
+			result.FailedNodes[node.Name] = fmt.Sprintf("node not in placement of group %s", groupName)
+		}
+	}
+	return result
+}
+
+// Prioritize : score the candidate nodes of a pod
+func (e *Extender) Prioritize(args *ExtenderArgs) (*HostPriorityList, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if args.Pod == nil || args.Nodes == nil {
+		return nil, fmt.Errorf("pod and nodes required (nodeCacheCapable not supported)")
+	}
+	list := make(HostPriorityList, len(args.Nodes.Items))
+	plan := e.plans[args.Pod.Annotations[GroupAnnotation]]
+	maxSlots := 0
+	if plan != nil {
+		for _, slots := range plan.slots {
+			maxSlots = util.Max(maxSlots, slots)
+		}
+	}
+	for i, node := range args.Nodes.Items {
+		list[i] = HostPriority{Host: node.Name, Score: 0}
+		if maxSlots > 0 {
+			list[i].Score = int64(plan.slots[node.Name]) * MaxExtenderPriority / int64(maxSlots)
+		}
+	}
+	return &list, nil
+}
+
+// Bind : bind a pod to a node of the placement of its group
+func (e *Extender) Bind(args *ExtenderBindingArgs) *ExtenderBindingResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire()
+	pod, exists := e.pods[args.PodUID]
+	if !exists {
+		return &ExtenderBindingResult{Error: fmt.Sprintf("pod %s/%s not filtered", args.PodNamespace, args.PodName)}
+	}
+	groupName := pod.groupName
+	plan := e.plans[groupName]
+	if plan == nil || plan.slots[args.Node] <= 0 {
+		return &ExtenderBindingResult{Error: fmt.Sprintf("node %s not in placement of group %s", args.Node, groupName)}
+	}
+	if e.binder != nil {
+		if err := e.binder.Bind(args); err != nil {
+			return &ExtenderBindingResult{Error: err.Error()}
+		}
+	}
+	plan.slots[args.Node]--
+	plan.bound[args.PodUID] = args.Node
+	plan.updated = e.now()
+	delete(e.pods, args.PodUID)
+	e.bound[args.PodUID] = &boundPod{node: args.Node, demand: plan.demand, bound: e.now()}
+	klog.V(4).Infof("pod %s/%s of group %s bound to node %s", args.PodNamespace, args.PodName, groupName, args.Node)
+
+	// forget the plan once all members are bound, their resources are kept until the pods are deleted
+	if len(plan.bound) == plan.pg.GetSize() {
+		delete(e.plans, groupName)
+	}
+	return &ExtenderBindingResult{}
+}
+
+// PodDeletionArgs : arguments of the delete verb
+type PodDeletionArgs struct {
+	PodName      string `json:"podName"`
+	PodNamespace string `json:"podNamespace"`
+	PodUID       string `json:"podUID"`
+}
+
+// Delete : release the resources of a deleted pod (e.g. notified by a pod informer)
+//   - returns false if the pod is unknown
+func (e *Extender) Delete(args *PodDeletionArgs) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, pods := range []map[string]*boundPod{e.bound, e.running} {
+		if pod, exists := pods[args.PodUID]; exists {
+			delete(pods, args.PodUID)
+			klog.V(4).Infof("pod %s/%s deleted from node %s", args.PodNamespace, args.PodName, pod.node)
+			return true
+		}
+	}
+	if _, exists := e.pods[args.PodUID]; exists {
+		delete(e.pods, args.PodUID)
+		return true
+	}
+	return false
+}
+
+// SyncPods : synchronize the pods running on nodes with a list of pods (e.g. from the API server),
+// listed at a given time, so that the resources of deleted pods are released
+//   - pods assigned to nodes and not terminated are running, with demand their requests
+//   - pods bound before the list was taken and not in the list were deleted
+func (e *Extender) SyncPods(pods []Pod, listed time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	running := make(map[string]*boundPod)
+	for i := range pods {
+		pod := &pods[i]
+		if len(pod.Spec.NodeName) == 0 || pod.Status.Phase == PodSucceeded || pod.Status.Phase == PodFailed {
+			continue
+		}
+		values, err := e.podDemand(pod)
+		if err != nil {
+			// the pod still counts against the number of pods on the node
+			klog.Errorf("pod %s/%s: %s", pod.Namespace, pod.Name, err.Error())
+			values = make(map[string]int)
+		}
+		demand, err := util.NewNamedAllocation(e.resourceNames, make([]int, len(e.resourceNames)))
+		if err != nil {
+			klog.Errorf("pod %s/%s: %s", pod.Namespace, pod.Name, err.Error())
+			continue
+		}
+		for name, value := range values {
+			demand.Set(name, value)
+		}
+		running[pod.UID] = &boundPod{node: pod.Spec.NodeName, demand: demand}
+	}
+	for uid, pod := range e.bound {
+		if _, exists := running[uid]; exists || pod.bound.Before(listed) {
+			delete(e.bound, uid)
+		}
+	}
+	e.running = running
+	klog.V(4).Infof("synchronized %d running pods", len(running))
+}
+
+// expire : forget the placement plans not updated, and the pods not filtered, within the plan timeout;
+// the members of expired plans left to bind release their resources
+func (e *Extender) expire() {
+	deadline := e.now().Add(-e.planTimeout)
+	for groupName, plan := range e.plans {
+		if plan.updated.Before(deadline) {
+			delete(e.plans, groupName)
+			klog.V(4).Infof("placement of group %s expired with %d members bound", groupName, len(plan.bound))
+		}
+	}
+	for uid, pod := range e.pods {
+		if _, exists := e.plans[pod.groupName]; !exists || pod.filtered.Before(deadline) {
+			delete(e.pods, uid)
+		}
+	}
+}
+
+// getPlan : get the placement plan of a group, placing the group if not already placed
+func (e *Extender) getPlan(groupName string, pod *Pod, nodes []Node) (*groupPlan, error) {
+	if plan, exists := e.plans[groupName]; exists {
+		return plan, nil
+	}
+	specString, exists := pod.Annotations[GroupSpecAnnotation]
+	if !exists {
+		return nil, fmt.Errorf("group %s unknown and pod %s/%s has no %s annotation",
+			groupName, pod.Namespace, pod.Name, GroupSpecAnnotation)
+	}
+	spec, err := builder.ParseGroupSpec([]byte(specString))
+	if err != nil {
+		return nil, err
+	}
+	spec.MetaData.Name = groupName
+	for _, tol := range pod.Spec.Tolerations {
+		if tol.Effect != TaintEffectPreferNoSchedule {
+			spec.Spec.Tolerations = append(spec.Spec.Tolerations,
+				util.JToleration{Key: tol.Key, Operator: tol.Operator, Value: tol.Value})
+		}
+	}
+	keys := make([]string, 0, len(pod.Spec.NodeSelector))
+	for key := range pod.Spec.NodeSelector {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		spec.Spec.NodeSelector = append(spec.Spec.NodeSelector,
+			util.JSelector{Key: key, Values: []string{pod.Spec.NodeSelector[key]}})
+	}
+	if len(spec.Spec.Demand) == 0 {
+		if spec.Spec.Demand, err = e.podDemand(pod); err != nil {
+			return nil, err
+		}
+	}
+
+	pTree, err := e.buildPTree(nodes)
+	if err != nil {
+		return nil, err
+	}
+	pg, err := builder.CreatePGroupFromSpec(spec, pTree.GetResourceNames(), pTree.GetLevelNames())
+	if err != nil {
+		return nil, err
+	}
+	p := placement.NewPlacer(pTree)
+	if _, err := p.PlaceGroup(pg); err != nil {
+		return nil, fmt.Errorf("group %s not placed: %s", groupName, err.Error())
+	}
+	if !pg.IsFullyPlaced() {
+		return nil, fmt.Errorf("group %s cannot be fully placed on candidate nodes", groupName)
+	}
+
+	demand, err := util.NewNamedAllocation(pTree.GetResourceNames(), pg.GetDemand().GetValue())
+	if err != nil {
+		return nil, err
+	}
+	plan := &groupPlan{
+		pg:      pg,
+		demand:  demand,
+		slots:   make(map[string]int),
+		placed:  make(map[string]int),
+		bound:   make(map[string]string),
+		updated: e.now(),
+	}
+	for _, leaf := range pg.GetLTree().GetLeaves() {
+		lNode := (*topology.LNode)(unsafe.Pointer(leaf))
+		plan.slots[lNode.GetID()] = lNode.GetCount()
+		plan.placed[lNode.GetID()] = lNode.GetCount()
+	}
+	e.plans[groupName] = plan
+	klog.V(4).Infof("group %s placed: %v", groupName, plan.placed)
+	return plan, nil
+}
+
+// buildPTree : build a PTree from nodes given the level labels, nodes missing a label are placed
+// in an unknown subtree; the resources allocated on nodes are those of the active placement plans,
+// bound pods, and running pods
+func (e *Extender) buildPTree(nodes []Node) (*topology.PTree, error) {
+	labeledNodes := make([]*builder.LabeledNode, len(nodes))
+	for i := range nodes {
+		node, err := e.makeLabeledNode(&nodes[i])
+		if err != nil {
+			return nil, err
+		}
+		labeledNodes[i] = node
+	}
+	lg := builder.NewLabelTreeGen(e.levelLabels, e.resourceNames)
+	lg.SetLeafLevelName(HostnameLabel)
+	return lg.CreateTree(labeledNodes)
+}
+
+// makeLabeledNode : make a labeled node from a node, with capacity the allocatable resources of the node
+// and allocated resources of the members of active placement plans left to bind, of bound pods,
+// and of running pods
+func (e *Extender) makeLabeledNode(node *Node) (*builder.LabeledNode, error) {
+	labeledNode := &builder.LabeledNode{
+		Name:      node.Name,
+		Labels:    node.Labels,
+		Capacity:  make(map[string]int),
+		Allocated: make(map[string]int),
+	}
+	if node.Spec.Unschedulable {
+		labeledNode.State = system.Cordoned
+	}
+	for _, taint := range node.Spec.Taints {
+		// soft taints do not exclude nodes
+		if taint.Effect != TaintEffectPreferNoSchedule {
+			labeledNode.Taints = append(labeledNode.Taints, system.Taint{Key: taint.Key, Value: taint.Value})
+		}
+	}
+	for _, name := range e.resourceNames {
+		if q, exists := node.Status.Allocatable[name]; exists {
+			value, err := util.ParseQuantity(q, util.IsMilliResource(name))
+			if err != nil {
+				return nil, fmt.Errorf("node %s: allocatable %s: %s", node.Name, name, err.Error())
+			}
+			labeledNode.Capacity[name] = value
+		}
+	}
+	numPods := 0
+	for _, plan := range e.plans {
+		if n := plan.slots[node.Name]; n > 0 {
+			for _, name := range plan.demand.GetNames() {
+				value, _ := plan.demand.Get(name)
+				labeledNode.Allocated[name] += n * value
+			}
+			numPods += n
+		}
+	}
+	for _, pods := range []map[string]*boundPod{e.bound, e.running} {
+		for _, pod := range pods {
+			if pod.node == node.Name {
+				for _, name := range pod.demand.GetNames() {
+					value, _ := pod.demand.Get(name)
+					labeledNode.Allocated[name] += value
+				}
+				numPods++
+			}
+		}
+	}
+	// the number of pods on the node caps the number of members placed there, less the pods
+	// of groups bound or left to bind there; a full node is cordoned, as no cap means unlimited
+	if q, exists := node.Status.Allocatable["pods"]; exists {
+		value, err := util.ParseQuantity(q, false)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: allocatable pods: %s", node.Name, err.Error())
+		}
+		if value > numPods {
+			labeledNode.MaxMembers = value - numPods
+		} else {
+			labeledNode.State = system.Cordoned
+		}
+	}
+	return labeledNode, nil
+}
+
+// podDemand : the resource requests of a pod, summed over containers
+func (e *Extender) podDemand(pod *Pod) (map[string]int, error) {
+	sum, err := util.NewNamedAllocation(e.resourceNames, make([]int, len(e.resourceNames)))
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range pod.Spec.Containers {
+		// requests of resources not considered for placement are ignored
+		requests := make(map[string]string)
+		for _, name := range e.resourceNames {
+			if q, exists := c.Resources.Requests[name]; exists {
+				requests[name] = q
+			}
+		}
+		containerDemand, err := util.ParseNamedAllocation(requests, e.resourceNames)
+		if err != nil {
+			return nil, fmt.Errorf("pod %s/%s: container %s: request %s", pod.Namespace, pod.Name, c.Name, err.Error())
+		}
+		if err := sum.Add(containerDemand); err != nil {
+			return nil, err
+		}
+	}
+	demand := make(map[string]int)
+	for _, name := range sum.GetNames() {
+		if value, _ := sum.Get(name); value > 0 {
+			demand[name] = value
+		}
+	}
+	return demand, nil
+}
+
+// Handler : the HTTP handler of the extender, with verbs /filter, /prioritize, /bind, and /delete
+func (e *Extender) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/filter", func(w http.ResponseWriter, r *http.Request) {
+		var args ExtenderArgs
+		if !decodeArgs(w, r, &args) {
+			return
+		}
+		writeJSON(w, e.Filter(&args))
+	})
+	mux.HandleFunc("/prioritize", func(w http.ResponseWriter, r *http.Request) {
+		var args ExtenderArgs
+		if !decodeArgs(w, r, &args) {
+			return
+		}
+		list, err := e.Prioritize(&args)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, list)
+	})
+	mux.HandleFunc("/bind", func(w http.ResponseWriter, r *http.Request) {
+		var args ExtenderBindingArgs
+		if !decodeArgs(w, r, &args) {
+			return
+		}
+		writeJSON(w, e.Bind(&args))
+	})
+	mux.HandleFunc("/delete", func(w http.ResponseWriter, r *http.Request) {
+		var args PodDeletionArgs
+		if !decodeArgs(w, r, &args) {
+			return
+		}
+		if !e.Delete(&args) {
+			http.Error(w, fmt.Sprintf("pod %s/%s unknown", args.PodNamespace, args.PodName), http.StatusNotFound)
+		}
+	})
+	return mux
+}
+
+// GetPlacedNodes : the nodes of the placement of a group with the number of members placed (nil if unknown)
+func (e *Extender) GetPlacedNodes(groupName string) map[string]int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	plan := e.plans[groupName]
+	if plan == nil {
+		return nil
+	}
+	placed := make(map[string]int)
+	for name, n := range plan.placed {
+		placed[name] = n
+	}
+	return placed
+}
+
+// GetGroupNames : the (sorted) names of groups with active placements
+func (e *Extender) GetGroupNames() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	names := make([]string, 0, len(e.plans))
+	for name := range e.plans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// decodeArgs : decode the JSON arguments of a verb, writing an error response on failure
+func decodeArgs(w http.ResponseWriter, r *http.Request, args interface{}) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(args); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writeJSON : write a JSON response
+func writeJSON(w http.ResponseWriter, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		klog.Errorf("error writing response: %s", err.Error())
+	}
+}
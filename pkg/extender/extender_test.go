@@ -0,0 +1,449 @@
+package extender
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadArgs : load recorded extender arguments from a fixture file
+func loadArgs(t *testing.T, fileName string, args interface{}) {
+	data, err := os.ReadFile("testdata/" + fileName)
+	if err != nil {
+		t.Fatalf("error reading fixture: %v", err)
+	}
+	if err := json.Unmarshal(data, args); err != nil {
+		t.Fatalf("error parsing fixture: %v", err)
+	}
+}
+
+// post : post arguments to a verb of the extender handler and decode the result
+func post(t *testing.T, h http.Handler, verb string, args interface{}, result interface{}) {
+	body, _ := json.Marshal(args)
+	req := httptest.NewRequest(http.MethodPost, "/"+verb, bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s status = %d %s", verb, rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), result); err != nil {
+		t.Fatalf("%s result not JSON: %v", verb, err)
+	}
+}
+
+func TestExtender(t *testing.T) {
+	e := NewExtender(nil, nil)
+	h := e.Handler()
+
+	var args ExtenderArgs
+	loadArgs(t, "extender-args.json", &args)
+	var bindArgs ExtenderBindingArgs
+	loadArgs(t, "bind-args.json", &bindArgs)
+
+	// filter: all members in a single zone (hard pack), spread over nodes
+	var filterResult ExtenderFilterResult
+	post(t, h, "filter", &args, &filterResult)
+	if len(filterResult.Error) > 0 {
+		t.Fatalf("filter error = %s", filterResult.Error)
+	}
+	placed := e.GetPlacedNodes("train")
+	total := 0
+	for name, n := range placed {
+		if !strings.HasPrefix(name, "node-b") {
+			t.Errorf("filter placed %d on node %s, want zone us-east-1b", n, name)
+		}
+		total += n
+	}
+	if total != 4 || len(filterResult.Nodes.Items) != len(placed) {
+		t.Errorf("filter placed = %v, nodes = %d", placed, len(filterResult.Nodes.Items))
+	}
+	if _, exists := filterResult.FailedNodes["node-x"]; !exists {
+		t.Errorf("filter failed nodes = %v, want node-x", filterResult.FailedNodes)
+	}
+
+	// prioritize: only nodes of the placement are scored
+	var priorities HostPriorityList
+	post(t, h, "prioritize", &args, &priorities)
+	for _, hp := range priorities {
+		if (placed[hp.Host] > 0) != (hp.Score > 0) {
+			t.Errorf("prioritize score of %s = %d, placed %d", hp.Host, hp.Score, placed[hp.Host])
+		}
+	}
+
+	// bind: members bound to nodes of the placement, until all are bound
+	var bindResult ExtenderBindingResult
+	bindArgs.Node = "node-a0"
+	post(t, h, "bind", &bindArgs, &bindResult)
+	if len(bindResult.Error) == 0 {
+		t.Errorf("bind to node-a0 succeeded, want error")
+	}
+	i := 0
+	for name, n := range placed {
+		for k := 0; k < n; k++ {
+			args.Pod.UID = "uid-" + string(rune('0'+i))
+			post(t, h, "filter", &args, &filterResult)
+			bindArgs.PodUID = args.Pod.UID
+			bindArgs.Node = name
+			bindResult = ExtenderBindingResult{}
+			post(t, h, "bind", &bindArgs, &bindResult)
+			if len(bindResult.Error) > 0 {
+				t.Errorf("bind error = %s", bindResult.Error)
+			}
+			i++
+		}
+	}
+	if names := e.GetGroupNames(); len(names) != 0 {
+		t.Errorf("groups after binding all members = %v, want none", names)
+	}
+}
+
+func TestExtender_NoGroup(t *testing.T) {
+	e := NewExtender(nil, nil)
+	var args ExtenderArgs
+	loadArgs(t, "extender-args.json", &args)
+	args.Pod.Annotations = nil
+	result := e.Filter(&args)
+	if len(result.Error) > 0 || len(result.Nodes.Items) != len(args.Nodes.Items) {
+		t.Errorf("Filter() = %v, want all nodes", result)
+	}
+}
+
+func TestExtender_TaintsAndCordon(t *testing.T) {
+	tests := []struct {
+		name        string
+		tolerations []Toleration
+		wantZone    string
+	}{
+		{name: "not tolerated", tolerations: nil, wantZone: "node-a"},
+		{name: "tolerated", tolerations: []Toleration{{Key: "gpu", Operator: "Exists", Effect: "NoSchedule"}},
+			wantZone: "node-b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewExtender(nil, nil)
+			var args ExtenderArgs
+			loadArgs(t, "extender-args.json", &args)
+			// zone us-east-1b: two tainted nodes and a cordoned node
+			for i := range args.Nodes.Items {
+				node := &args.Nodes.Items[i]
+				switch node.Name {
+				case "node-b0", "node-b1":
+					node.Spec.Taints = []Taint{{Key: "gpu", Value: "true", Effect: "NoSchedule"}}
+				case "node-b2":
+					node.Spec.Unschedulable = true
+				}
+			}
+			args.Pod.Spec.Tolerations = tt.tolerations
+			result := e.Filter(&args)
+			if len(result.Error) > 0 {
+				t.Fatalf("Filter() error = %s", result.Error)
+			}
+			placed := e.GetPlacedNodes("train")
+			if len(placed) == 0 {
+				t.Fatalf("Filter() placed no members")
+			}
+			for name := range placed {
+				if !strings.HasPrefix(name, tt.wantZone) || name == "node-b2" {
+					t.Errorf("Filter() placed on node %s, want %s*", name, tt.wantZone)
+				}
+			}
+		})
+	}
+}
+
+func TestExtender_NodeSelector(t *testing.T) {
+	e := NewExtender(nil, nil)
+	var args ExtenderArgs
+	loadArgs(t, "extender-args.json", &args)
+	args.Pod.Spec.NodeSelector = map[string]string{"topology.kubernetes.io/zone": "us-east-1a"}
+	result := e.Filter(&args)
+	if len(result.Error) > 0 {
+		t.Fatalf("Filter() error = %s", result.Error)
+	}
+	placed := e.GetPlacedNodes("train")
+	if len(placed) == 0 {
+		t.Fatalf("Filter() placed no members")
+	}
+	for name := range placed {
+		if !strings.HasPrefix(name, "node-a") {
+			t.Errorf("Filter() placed on node %s, want node-a*", name)
+		}
+	}
+}
+
+func TestExtender_HomogeneousKey(t *testing.T) {
+	e := NewExtender(nil, nil)
+	var args ExtenderArgs
+	loadArgs(t, "extender-args.json", &args)
+	// a single GPU generation within the zone
+	annotation := args.Pod.Annotations[GroupSpecAnnotation]
+	args.Pod.Annotations[GroupSpecAnnotation] = strings.Replace(annotation,
+		`"hard": true}`, `"hard": true, "homogeneous-key": "gpu"}`, 1)
+	gpus := map[string]string{"node-b0": "h100", "node-b1": "a100", "node-b2": "a100"}
+	for i := range args.Nodes.Items {
+		node := &args.Nodes.Items[i]
+		if gpu, exists := gpus[node.Name]; exists {
+			node.Labels["gpu"] = gpu
+		} else if node.Labels != nil {
+			node.Labels["gpu"] = "v100"
+		}
+	}
+	result := e.Filter(&args)
+	if len(result.Error) > 0 {
+		t.Fatalf("Filter() error = %s", result.Error)
+	}
+	placed := e.GetPlacedNodes("train")
+	if len(placed) == 0 {
+		t.Fatalf("Filter() placed no members")
+	}
+	for name := range placed {
+		if gpus[name] != "a100" {
+			t.Errorf("Filter() placed on node %s, want node-b1 or node-b2", name)
+		}
+	}
+}
+
+// bindGroup : filter and bind all members of a group of a given size in a zone,
+// returning the nodes of its placement
+func bindGroup(t *testing.T, e *Extender, groupName string, size int, zone string) map[string]int {
+	var args ExtenderArgs
+	loadArgs(t, "extender-args.json", &args)
+	args.Pod.Spec.NodeSelector = map[string]string{"topology.kubernetes.io/zone": zone}
+	return bindGroupArgs(t, e, &args, groupName, size)
+}
+
+// bindGroupArgs : filter and bind all members of a group of a given size, given the filter arguments
+// of its first pod, returning the nodes of its placement
+func bindGroupArgs(t *testing.T, e *Extender, args *ExtenderArgs, groupName string, size int) map[string]int {
+	args.Pod.Annotations[GroupAnnotation] = groupName
+	args.Pod.Annotations[GroupSpecAnnotation] = strings.Replace(args.Pod.Annotations[GroupSpecAnnotation],
+		`"size": 4`, fmt.Sprintf(`"size": %d`, size), 1)
+	args.Pod.UID = groupName + "-0"
+	if result := e.Filter(args); len(result.Error) > 0 {
+		t.Fatalf("Filter() group %s error = %s", groupName, result.Error)
+	}
+	placed := e.GetPlacedNodes(groupName)
+	i := 0
+	for name, n := range placed {
+		for k := 0; k < n; k++ {
+			args.Pod.UID = fmt.Sprintf("%s-%d", groupName, i)
+			e.Filter(args)
+			bindArgs := &ExtenderBindingArgs{PodName: args.Pod.UID, PodNamespace: "default",
+				PodUID: args.Pod.UID, Node: name}
+			if result := e.Bind(bindArgs); len(result.Error) > 0 {
+				t.Fatalf("Bind() error = %s", result.Error)
+			}
+			i++
+		}
+	}
+	return placed
+}
+
+func TestExtender_BoundGroups(t *testing.T) {
+	// zone us-east-1b: three nodes fitting 4 members each (memory)
+	e := NewExtender(nil, nil)
+	first := bindGroup(t, e, "first", 8, "us-east-1b")
+	second := bindGroup(t, e, "second", 4, "us-east-1b")
+	for name := range second {
+		if first[name]+second[name] > 4 {
+			t.Errorf("node %s hosts %d and %d members, want at most 4", name, first[name], second[name])
+		}
+	}
+
+	// the zone is full until pods of bound groups are deleted
+	var args ExtenderArgs
+	loadArgs(t, "extender-args.json", &args)
+	args.Pod.Annotations[GroupAnnotation] = "third"
+	args.Pod.Spec.NodeSelector = map[string]string{"topology.kubernetes.io/zone": "us-east-1b"}
+	if result := e.Filter(&args); len(result.Error) == 0 {
+		t.Errorf("Filter() third group on full zone, want error")
+	}
+	for i := 0; i < 4; i++ {
+		uid := fmt.Sprintf("second-%d", i)
+		if !e.Delete(&PodDeletionArgs{PodName: uid, PodNamespace: "default", PodUID: uid}) {
+			t.Errorf("Delete() pod %s unknown", uid)
+		}
+	}
+	if result := e.Filter(&args); len(result.Error) > 0 {
+		t.Errorf("Filter() third group after deleting pods error = %s", result.Error)
+	}
+}
+
+func TestExtender_PlanTimeout(t *testing.T) {
+	e := NewExtender(nil, nil)
+	now := time.Now()
+	e.now = func() time.Time { return now }
+
+	// a group of 12 fills zone us-east-1b, then a member is bound and the others are not
+	var args ExtenderArgs
+	loadArgs(t, "extender-args.json", &args)
+	args.Pod.Annotations[GroupSpecAnnotation] = strings.Replace(args.Pod.Annotations[GroupSpecAnnotation],
+		`"size": 4`, `"size": 12`, 1)
+	if result := e.Filter(&args); len(result.Error) > 0 {
+		t.Fatalf("Filter() error = %s", result.Error)
+	}
+	if result := e.Bind(&ExtenderBindingArgs{PodUID: args.Pod.UID, Node: "node-b0"}); len(result.Error) > 0 {
+		t.Fatalf("Bind() error = %s", result.Error)
+	}
+	args.Pod.UID = "uid-1"
+	e.Filter(&args)
+
+	// the placement expires, releasing the members left to bind and the filtered pod
+	now = now.Add(DefaultPlanTimeout + time.Second)
+	if result := e.Bind(&ExtenderBindingArgs{PodUID: "uid-1", Node: "node-b1"}); len(result.Error) == 0 {
+		t.Errorf("Bind() after timeout succeeded, want error")
+	}
+	if names := e.GetGroupNames(); len(names) != 0 {
+		t.Errorf("groups after timeout = %v, want none", names)
+	}
+	placed := bindGroup(t, e, "next", 11, "us-east-1b")
+	if placed["node-b0"] > 3 {
+		t.Errorf("next group placed %d on node-b0 hosting a bound member, want at most 3", placed["node-b0"])
+	}
+}
+
+func TestExtender_MaxPods(t *testing.T) {
+	e := NewExtender(nil, nil)
+	// zone us-east-1b: three nodes of 2 pods each
+	loadNodes := func(args *ExtenderArgs) {
+		for i := range args.Nodes.Items {
+			node := &args.Nodes.Items[i]
+			if strings.HasPrefix(node.Name, "node-b") {
+				node.Status.Allocatable["pods"] = "2"
+			}
+		}
+	}
+	var args ExtenderArgs
+	loadArgs(t, "extender-args.json", &args)
+	loadNodes(&args)
+	args.Pod.Spec.NodeSelector = map[string]string{"topology.kubernetes.io/zone": "us-east-1b"}
+	spec := args.Pod.Annotations[GroupSpecAnnotation]
+	first := bindGroupArgs(t, e, &args, "first", 4)
+
+	tests := []struct {
+		name      string
+		groupName string
+		size      int
+		wantErr   bool
+	}{
+		{"more members than pods left", "second", 3, true},
+		{"members up to pods left", "third", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args.Pod.Annotations[GroupAnnotation] = tt.groupName
+			args.Pod.Annotations[GroupSpecAnnotation] = strings.Replace(spec, `"size": 4`, fmt.Sprintf(`"size": %d`, tt.size), 1)
+			args.Pod.UID = tt.groupName + "-0"
+			result := e.Filter(&args)
+			if (len(result.Error) > 0) != tt.wantErr {
+				t.Fatalf("Filter() error = %q, want error %v", result.Error, tt.wantErr)
+			}
+			for name, n := range e.GetPlacedNodes(tt.groupName) {
+				if first[name]+n > 2 {
+					t.Errorf("Filter() placed %d on node %s hosting %d pods, want at most 2 pods", n, name, first[name])
+				}
+			}
+		})
+	}
+}
+
+func TestExtender_PodDemand(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []Container
+		want       map[string]int
+		wantErr    bool
+	}{
+		{
+			name: "summed over containers",
+			containers: []Container{
+				{Name: "a", Resources: ResourceRequirements{Requests: map[string]string{"cpu": "500m", "memory": "1Ki"}}},
+				{Name: "b", Resources: ResourceRequirements{Requests: map[string]string{"cpu": "2"}}},
+			},
+			want: map[string]int{"cpu": 2500, "memory": 1024},
+		},
+		{
+			name: "other resources ignored",
+			containers: []Container{
+				{Name: "a", Resources: ResourceRequirements{Requests: map[string]string{"memory": "2", "gpu": "1"}}},
+			},
+			want: map[string]int{"memory": 2},
+		},
+		{
+			name: "bad quantity",
+			containers: []Container{
+				{Name: "a", Resources: ResourceRequirements{Requests: map[string]string{"cpu": "x"}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewExtender(nil, nil)
+			got, err := e.podDemand(&Pod{Spec: PodSpec{Containers: tt.containers}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("podDemand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("podDemand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtender_SyncPods(t *testing.T) {
+	e := NewExtender(nil, nil)
+	now := time.Now()
+	e.now = func() time.Time { return now }
+	// zone us-east-1b: three nodes fitting 4 members each (memory), filled by a group of 12
+	bindGroup(t, e, "first", 12, "us-east-1b")
+
+	var args ExtenderArgs
+	loadArgs(t, "extender-args.json", &args)
+	args.Pod.Annotations[GroupAnnotation] = "second"
+	args.Pod.Spec.NodeSelector = map[string]string{"topology.kubernetes.io/zone": "us-east-1b"}
+	// pods running on the nodes of the zone, spread evenly
+	runningOn := func(numPods int) []Pod {
+		pods := make([]Pod, numPods)
+		for i := range pods {
+			pods[i] = *args.Pod
+			pods[i].UID = fmt.Sprintf("running-%d", i)
+			pods[i].Spec.NodeName = fmt.Sprintf("node-b%d", i%3)
+			pods[i].Status.Phase = "Running"
+		}
+		return pods
+	}
+	finished := runningOn(12)
+	for i := range finished {
+		finished[i].Status.Phase = PodSucceeded
+	}
+
+	steps := []struct {
+		name    string
+		pods    []Pod
+		listed  time.Time
+		wantErr bool
+	}{
+		{name: "bound pods not yet listed are kept", listed: now.Add(-time.Second), wantErr: true},
+		{name: "bound pods missing from list are released", listed: now.Add(time.Second), wantErr: false},
+		{name: "running pods hold resources", pods: runningOn(12), listed: now.Add(time.Second), wantErr: true},
+		{name: "finished pods release resources", pods: finished, listed: now.Add(time.Second), wantErr: false},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			e.SyncPods(step.pods, step.listed)
+			result := e.Filter(&args)
+			if (len(result.Error) > 0) != step.wantErr {
+				t.Errorf("Filter() error = %q, want error %v", result.Error, step.wantErr)
+			}
+			e.plans = make(map[string]*groupPlan)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package extender
+
+// Subset of the Kubernetes API and scheduler extender protocol types,
+// keeping only the fields used by the adapter (JSON field names match the upstream types)
+
+// ObjectMeta : metadata of Kubernetes objects
+type ObjectMeta struct {
+	Name        string            `json:"name,omitempty"`
+	Namespace   string            `json:"namespace,omitempty"`
+	UID         string            `json:"uid,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Pod : a Kubernetes pod
+type Pod struct {
+	ObjectMeta `json:"metadata,omitempty"`
+	Spec       PodSpec   `json:"spec,omitempty"`
+	Status     PodStatus `json:"status,omitempty"`
+}
+
+// PodSpec : spec of a pod
+type PodSpec struct {
+	Containers   []Container       `json:"containers,omitempty"`
+	Tolerations  []Toleration      `json:"tolerations,omitempty"`
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	NodeName     string            `json:"nodeName,omitempty"`
+}
+
+// PodStatus : status of a pod
+type PodStatus struct {
+	Phase string `json:"phase,omitempty"`
+}
+
+// PodList : a list of pods
+type PodList struct {
+	Items []Pod `json:"items"`
+}
+
+// Toleration : a toleration of node taints by a pod
+type Toleration struct {
+	Key      string `json:"key,omitempty"`
+	Operator string `json:"operator,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Effect   string `json:"effect,omitempty"`
+}
+
+// Container : a container in a pod
+type Container struct {
+	Name      string               `json:"name,omitempty"`
+	Resources ResourceRequirements `json:"resources,omitempty"`
+}
+
+// ResourceRequirements : resource quantities keyed by resource name
+type ResourceRequirements struct {
+	Limits   map[string]string `json:"limits,omitempty"`
+	Requests map[string]string `json:"requests,omitempty"`
+}
+
+// Node : a Kubernetes node
+type Node struct {
+	ObjectMeta `json:"metadata,omitempty"`
+	Spec       NodeSpec   `json:"spec,omitempty"`
+	Status     NodeStatus `json:"status,omitempty"`
+}
+
+// NodeSpec : spec of a node
+type NodeSpec struct {
+	Unschedulable bool    `json:"unschedulable,omitempty"`
+	Taints        []Taint `json:"taints,omitempty"`
+}
+
+// Taint : a taint on a node
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+// NodeStatus : status of a node
+type NodeStatus struct {
+	Capacity    map[string]string `json:"capacity,omitempty"`
+	Allocatable map[string]string `json:"allocatable,omitempty"`
+}
+
+// NodeList : a list of nodes
+type NodeList struct {
+	Items []Node `json:"items"`
+}
+
+// ExtenderArgs : arguments of the filter and prioritize verbs
+type ExtenderArgs struct {
+	Pod       *Pod      `json:"pod"`
+	Nodes     *NodeList `json:"nodes,omitempty"`
+	NodeNames *[]string `json:"nodenames,omitempty"`
+}
+
+// ExtenderFilterResult : result of the filter verb
+type ExtenderFilterResult struct {
+	Nodes                      *NodeList         `json:"nodes,omitempty"`
+	NodeNames                  *[]string         `json:"nodenames,omitempty"`
+	FailedNodes                map[string]string `json:"failedNodes,omitempty"`
+	FailedAndUnresolvableNodes map[string]string `json:"failedAndUnresolvableNodes,omitempty"`
+	Error                      string            `json:"error,omitempty"`
+}
+
+// HostPriority : score of a host, result of the prioritize verb
+type HostPriority struct {
+	Host  string `json:"host"`
+	Score int64  `json:"score"`
+}
+
+// HostPriorityList : list of host scores
+type HostPriorityList []HostPriority
+
+// ExtenderBindingArgs : arguments of the bind verb
+type ExtenderBindingArgs struct {
+	PodName      string `json:"podName"`
+	PodNamespace string `json:"podNamespace"`
+	PodUID       string `json:"podUID"`
+	Node         string `json:"node"`
+}
+
+// ExtenderBindingResult : result of the bind verb
+type ExtenderBindingResult struct {
+	Error string `json:"error,omitempty"`
+}
+
+// TaintEffectPreferNoSchedule : effect of taints avoided by the scheduler if possible
+const TaintEffectPreferNoSchedule = "PreferNoSchedule"
+
+// MaxExtenderPriority : the maximum score returned by the prioritize verb
+const MaxExtenderPriority int64 = 10
@@ -0,0 +1,246 @@
+package placement
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"unsafe"
+
+	"k8s.io/klog/v2"
+
+	"github.com/ibm/chic-sched/pkg/system"
+	"github.com/ibm/chic-sched/pkg/topology"
+)
+
+// Forever : the release time of a group with no expected duration
+const Forever int = math.MaxInt
+
+// Booking : a claimed group and the times it started and is expected to be released
+type Booking struct {
+	// the claimed group
+	Group *PGroup
+	// time the group started
+	Start int
+	// time the group is expected to be released (Forever if no expected duration)
+	Release int
+}
+
+// Reservation : a future slot reserved for a group blocked by the current allocation
+type Reservation struct {
+	// the blocked group
+	Group *PGroup
+	// time the group is expected to start, once enough claimed groups are released
+	Start int
+	// expected placement of the group, number of members keyed by PE ID
+	Placement map[string]int
+}
+
+// Calendar : a reservation calendar over a physical tree, keeping the expected release times
+// of claimed groups, for scheduling groups with expected durations with reservations and backfill
+type Calendar struct {
+	// physical tree
+	pTree *topology.PTree
+	// claimed groups, keyed by group ID
+	bookings map[string]*Booking
+	// reservation of a future slot for a blocked group (nil if none)
+	reservation *Reservation
+}
+
+// NewCalendar : create an empty calendar over a physical tree
+//   - returns nil if bad parameters
+func NewCalendar(pTree *topology.PTree) *Calendar {
+	if pTree == nil || pTree.GetRoot() == nil {
+		return nil
+	}
+	return &Calendar{
+		pTree:    pTree,
+		bookings: make(map[string]*Booking),
+	}
+}
+
+// GetBookings : the claimed groups, ordered by release time then group ID
+func (c *Calendar) GetBookings() []*Booking {
+	bookings := make([]*Booking, 0, len(c.bookings))
+	for _, b := range c.bookings {
+		bookings = append(bookings, b)
+	}
+	sort.Slice(bookings, func(i, j int) bool {
+		if bookings[i].Release != bookings[j].Release {
+			return bookings[i].Release < bookings[j].Release
+		}
+		return bookings[i].Group.GetID() < bookings[j].Group.GetID()
+	})
+	return bookings
+}
+
+// GetReservation : the reservation of a future slot for a blocked group (nil if none)
+func (c *Calendar) GetReservation() *Reservation {
+	return c.reservation
+}
+
+// Claim : place and claim all members of a group at a given time, booking it until its expected release
+//   - returns error if already claimed, not fully placed, or not claimed
+func (c *Calendar) Claim(pg *PGroup, now int) error {
+	if pg == nil {
+		return fmt.Errorf("PGroup is nil")
+	}
+	id := pg.GetID()
+	if _, exists := c.bookings[id]; exists {
+		return fmt.Errorf("group %s already claimed", id)
+	}
+	if !c.fits(pg) {
+		return fmt.Errorf("group %s not fully placed", id)
+	}
+	if err := pg.TryClaim(pg.GetSize(), c.pTree); err != nil {
+		pg.UnClaimAll(c.pTree)
+		return err
+	}
+	release := Forever
+	if d := pg.GetDuration(); d > 0 {
+		release = now + d
+	}
+	c.bookings[id] = &Booking{Group: pg, Start: now, Release: release}
+	if c.reservation != nil && c.reservation.Group.GetID() == id {
+		c.reservation = nil
+	}
+	return nil
+}
+
+// Release : unclaim a booked group
+//   - returns error if not booked
+func (c *Calendar) Release(groupID string) error {
+	b, exists := c.bookings[groupID]
+	if !exists {
+		return fmt.Errorf("group %s not claimed", groupID)
+	}
+	b.Group.UnClaimAll(c.pTree)
+	delete(c.bookings, groupID)
+	return nil
+}
+
+// ReleaseDue : unclaim the booked groups expected to be released by a given time,
+// and return their IDs ordered by release time
+func (c *Calendar) ReleaseDue(now int) []string {
+	ids := make([]string, 0)
+	for _, b := range c.GetBookings() {
+		if b.Release > now {
+			break
+		}
+		c.Release(b.Group.GetID())
+		ids = append(ids, b.Group.GetID())
+	}
+	return ids
+}
+
+// EarliestStart : the earliest time a group can be fully placed, given the expected release times
+// of booked groups, and its expected placement then
+//   - the placement of the group is left as expected then, unclaimed
+//   - returns error if the group cannot be placed before groups with no expected duration are released,
+//     or if members of booked groups cannot be placed back on their PEs
+func (c *Calendar) EarliestStart(pg *PGroup, now int) (reservation *Reservation, err error) {
+	if pg == nil {
+		return nil, fmt.Errorf("PGroup is nil")
+	}
+	if c.fits(pg) {
+		return c.makeReservation(pg, now), nil
+	}
+
+	// release booked groups hypothetically, in order of release time, with the nodes reserved for them
+	// at exclusive levels, restoring them when done
+	released := make(map[*system.LE]*system.PE)
+	reserved := make(map[*topology.PNode]string)
+	defer func() {
+		for le, pe := range released {
+			if placeErr := pe.TryPlaceLE(le); placeErr != nil && err == nil {
+				reservation = nil
+				err = fmt.Errorf("member %s of a booked group not restored: %s", le.GetID(), placeErr.Error())
+			}
+		}
+		for pNode, groupID := range reserved {
+			pNode.SetReservedBy(groupID)
+		}
+		c.pTree.PercolateResources()
+	}()
+	for _, b := range c.GetBookings() {
+		if b.Release == Forever {
+			break
+		}
+		for _, le := range b.Group.GetLEGroup().GetLEs() {
+			if pe := le.GetHost(); pe != nil && pe.UnPlaceLE(le) {
+				released[le] = pe
+			}
+		}
+		for _, node := range c.pTree.GetNodeListBFS() {
+			if pNode := (*topology.PNode)(unsafe.Pointer(node)); pNode.GetReservedBy() == b.Group.GetID() {
+				reserved[pNode] = b.Group.GetID()
+			}
+		}
+		c.pTree.ReleaseReserved(b.Group.GetID())
+		c.pTree.PercolateResources()
+		if c.fits(pg) {
+			return c.makeReservation(pg, b.Release), nil
+		}
+	}
+	return nil, fmt.Errorf("group %s cannot be placed before groups with no expected duration are released",
+		pg.GetID())
+}
+
+// Schedule : claim groups from a queue, in order, at a given time, and return the groups claimed
+//   - groups are claimed in order until one does not fit (first come, first served)
+//   - in backfill mode, a future slot is reserved for the first group that does not fit, at its earliest
+//     start, and later groups are claimed now only if they fit and are expected to be released
+//     by the start of the reservation
+func (c *Calendar) Schedule(queue []*PGroup, now int, backfill bool) []*PGroup {
+	claimed := make([]*PGroup, 0)
+	var blocked *PGroup
+	for _, pg := range queue {
+		if blocked == nil {
+			if err := c.Claim(pg, now); err == nil {
+				claimed = append(claimed, pg)
+				continue
+			}
+			if !backfill {
+				break
+			}
+			blocked = pg
+			reservation, err := c.EarliestStart(pg, now)
+			if err != nil {
+				klog.V(4).Infof("no reservation for group %s: %s", pg.GetID(), err.Error())
+				c.reservation = nil
+				break
+			}
+			c.reservation = reservation
+			continue
+		}
+		// backfill
+		if d := pg.GetDuration(); d <= 0 || now+d > c.reservation.Start {
+			continue
+		}
+		if err := c.Claim(pg, now); err == nil {
+			klog.V(4).Infof("group %s backfilled ahead of group %s", pg.GetID(), blocked.GetID())
+			claimed = append(claimed, pg)
+		}
+	}
+	if blocked == nil {
+		c.reservation = nil
+	}
+	return claimed
+}
+
+// fits : place a group and check if fully placed
+func (c *Calendar) fits(pg *PGroup) bool {
+	p := NewPlacer(c.pTree)
+	if _, err := p.PlaceGroup(pg); err != nil {
+		return false
+	}
+	return pg.IsFullyPlaced()
+}
+
+// makeReservation : make a reservation for a placed group at a given time
+func (c *Calendar) makeReservation(pg *PGroup, start int) *Reservation {
+	placement := make(map[string]int)
+	for _, leaf := range pg.GetLTree().GetLeaves() {
+		placement[leaf.GetID()] = (*topology.LNode)(unsafe.Pointer(leaf)).GetCount()
+	}
+	return &Reservation{Group: pg, Start: start, Placement: placement}
+}
@@ -0,0 +1,228 @@
+package placement
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// makeTimedPGroup : make a group of members of a given cpu demand and expected duration
+func makeTimedPGroup(id string, size int, cpu int, duration int) *PGroup {
+	pg := makePGroup(id, size, cpu)
+	pg.SetDuration(duration)
+	return pg
+}
+
+// allocatedOf : cpu allocated in the whole tree
+func allocatedOf(pTree *topology.PTree) int {
+	return (*topology.PNode)(unsafe.Pointer(pTree.GetRoot())).GetAllocated().GetValue()[0]
+}
+
+// makeCalendar : make a calendar over a tree of 4 PEs of 4 cpus, with groups booked at time 0
+// on 3 PEs: one released at time 10, one at time 5
+func makeCalendar(t *testing.T) *Calendar {
+	c := NewCalendar(makePTree())
+	for _, pg := range []*PGroup{makeTimedPGroup("long", 2, 4, 10), makeTimedPGroup("short", 1, 4, 5)} {
+		if err := c.Claim(pg, 0); err != nil {
+			t.Fatalf("Claim() group %s error = %v", pg.GetID(), err)
+		}
+	}
+	return c
+}
+
+// groupIDs : IDs of groups
+func groupIDs(groups []*PGroup) []string {
+	ids := make([]string, len(groups))
+	for i, pg := range groups {
+		ids[i] = pg.GetID()
+	}
+	return ids
+}
+
+func TestCalendar_Schedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		backfill bool
+		// blocked group first, then groups fitting now
+		queue       []*PGroup
+		wantClaimed []string
+		// start of the reservation (-1 if none)
+		wantStart int
+	}{
+		{
+			name:        "all fit",
+			queue:       []*PGroup{makeTimedPGroup("small", 1, 4, 3)},
+			wantClaimed: []string{"small"},
+			wantStart:   -1,
+		},
+		{
+			name:        "first come first served blocks later groups",
+			queue:       []*PGroup{makeTimedPGroup("big", 2, 4, 10), makeTimedPGroup("small", 1, 4, 3)},
+			wantClaimed: []string{},
+			wantStart:   -1,
+		},
+		{
+			name:     "backfill admits group released by the reservation",
+			backfill: true,
+			queue: []*PGroup{makeTimedPGroup("big", 2, 4, 10), makeTimedPGroup("later", 1, 4, 8),
+				makeTimedPGroup("small", 1, 4, 3)},
+			wantClaimed: []string{"small"},
+			wantStart:   5,
+		},
+		{
+			name:     "backfill refuses group without duration",
+			backfill: true,
+			queue:    []*PGroup{makeTimedPGroup("big", 2, 4, 10), makeTimedPGroup("unknown", 1, 4, 0)},
+			// reservation at the release of the short group
+			wantClaimed: []string{},
+			wantStart:   5,
+		},
+		{
+			name:     "no reservation behind groups without duration",
+			backfill: true,
+			queue:    []*PGroup{makeTimedPGroup("huge", 5, 4, 10), makeTimedPGroup("small", 1, 4, 3)},
+			// the huge group does not fit even when all groups are released
+			wantClaimed: []string{},
+			wantStart:   -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := makeCalendar(t)
+			claimed := groupIDs(c.Schedule(tt.queue, 0, tt.backfill))
+			if strings.Join(claimed, ",") != strings.Join(tt.wantClaimed, ",") {
+				t.Errorf("Schedule() claimed %v, want %v", claimed, tt.wantClaimed)
+			}
+			if got := len(c.GetBookings()); got != 2+len(tt.wantClaimed) {
+				t.Errorf("GetBookings() = %d bookings, want %d", got, 2+len(tt.wantClaimed))
+			}
+			if got := allocatedOf(c.pTree); got != 4*(3+len(tt.wantClaimed)) {
+				t.Errorf("allocated = %d, want %d", got, 4*(3+len(tt.wantClaimed)))
+			}
+			reservation := c.GetReservation()
+			if tt.wantStart < 0 {
+				if reservation != nil {
+					t.Errorf("GetReservation() = %v, want nil", reservation)
+				}
+				return
+			}
+			if reservation == nil || reservation.Group.GetID() != tt.queue[0].GetID() ||
+				reservation.Start != tt.wantStart {
+				t.Fatalf("GetReservation() = %v, want group %s at %d", reservation, tt.queue[0].GetID(), tt.wantStart)
+			}
+			numPlaced := 0
+			for _, n := range reservation.Placement {
+				numPlaced += n
+			}
+			if numPlaced != tt.queue[0].GetSize() {
+				t.Errorf("reservation placement %v, want %d members", reservation.Placement, tt.queue[0].GetSize())
+			}
+		})
+	}
+}
+
+func TestCalendar_EarliestStart(t *testing.T) {
+	c := makeCalendar(t)
+	forever := makeTimedPGroup("forever", 1, 4, 0)
+	if err := c.Claim(forever, 0); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	// the bookings are restored after releasing them hypothetically
+	reservation, err := c.EarliestStart(makeTimedPGroup("big", 3, 4, 10), 2)
+	if err != nil || reservation.Start != 10 {
+		t.Errorf("EarliestStart() = %v, %v, want start at 10", reservation, err)
+	}
+	if got := allocatedOf(c.pTree); got != 16 {
+		t.Errorf("allocated after EarliestStart() = %d, want 16", got)
+	}
+	for _, pe := range c.pTree.GetPEs() {
+		if len(pe.GetHostedIDs()) != 1 {
+			t.Errorf("PE %s hosting %v after EarliestStart(), want one member", pe.GetID(), pe.GetHostedIDs())
+		}
+	}
+
+	// a group fitting only once groups without duration are released has no start
+	if _, err := c.EarliestStart(makeTimedPGroup("all", 4, 4, 10), 2); err == nil {
+		t.Errorf("EarliestStart() error = nil, want error")
+	}
+	if got := allocatedOf(c.pTree); got != 16 {
+		t.Errorf("allocated after failed EarliestStart() = %d, want 16", got)
+	}
+}
+
+func TestCalendar_EarliestStart_Exclusive(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		cpu       int
+		wantStart int
+		wantErr   bool
+	}{
+		{name: "reserved rack released", size: 4, cpu: 4, wantStart: 5},
+		{name: "fits in the other rack now", size: 8, cpu: 1, wantStart: 0},
+		{name: "exceeds the tree", size: 4, cpu: 5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a group of one small member booked until time 5, reserving its rack
+			c := NewCalendar(makePTree())
+			exclusive := makeTimedPGroup("exclusive", 1, 1, 5)
+			lc := NewLevelConstraint("lc", 1, util.Pack, false)
+			lc.SetExclusive(true)
+			exclusive.AddLevelConstraint(lc)
+			if err := c.Claim(exclusive, 0); err != nil {
+				t.Fatalf("Claim() error = %v", err)
+			}
+			rack := (*topology.PNode)(unsafe.Pointer(c.pTree.GetNode(firstPE(exclusive)).GetParent()))
+
+			reservation, err := c.EarliestStart(makeTimedPGroup("all", tt.size, tt.cpu, 10), 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EarliestStart() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && reservation.Start != tt.wantStart {
+				t.Errorf("EarliestStart() start = %d, want %d", reservation.Start, tt.wantStart)
+			}
+
+			// the booking and its reservation are restored
+			if got := allocatedOf(c.pTree); got != 1 {
+				t.Errorf("allocated after EarliestStart() = %d, want 1", got)
+			}
+			if got := rack.GetReservedBy(); got != exclusive.GetID() {
+				t.Errorf("rack %s reserved by %q after EarliestStart(), want %s", rack.GetID(), got, exclusive.GetID())
+			}
+		})
+	}
+}
+
+func TestCalendar_ReleaseDue(t *testing.T) {
+	c := makeCalendar(t)
+	if err := c.Claim(makeTimedPGroup("forever", 1, 4, 0), 0); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	steps := []struct {
+		now          int
+		wantReleased []string
+		wantAlloc    int
+	}{
+		{now: 4, wantReleased: []string{}, wantAlloc: 16},
+		{now: 5, wantReleased: []string{"short"}, wantAlloc: 12},
+		{now: 5, wantReleased: []string{}, wantAlloc: 12},
+		{now: 100, wantReleased: []string{"long"}, wantAlloc: 4},
+	}
+	for _, step := range steps {
+		released := c.ReleaseDue(step.now)
+		if strings.Join(released, ",") != strings.Join(step.wantReleased, ",") {
+			t.Errorf("ReleaseDue(%d) = %v, want %v", step.now, released, step.wantReleased)
+		}
+		if got := allocatedOf(c.pTree); got != step.wantAlloc {
+			t.Errorf("allocated after ReleaseDue(%d) = %d, want %d", step.now, got, step.wantAlloc)
+		}
+	}
+	if err := c.Release("short"); err == nil {
+		t.Errorf("Release() of released group error = nil, want error")
+	}
+}
@@ -0,0 +1,132 @@
+package placement
+
+import (
+	"fmt"
+	"sort"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// Diagnostic : a record of a node where the placement of a group fell short
+type Diagnostic struct {
+	// ID of the physical node
+	NodeID string
+	// level of the node
+	Level int
+	// number of members that fit in the subtree of the node
+	NumFit int
+	// number of members placed in the subtree, before rolling back (if any)
+	NumPlaced int
+	// description of the shortfall
+	Reason string
+}
+
+// String : a print out of the diagnostic
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("node=%s; level=%d; numFit=%d; numPlaced=%d; %s",
+		d.NodeID, d.Level, d.NumFit, d.NumPlaced, d.Reason)
+}
+
+// ConstraintStatus : satisfaction of a level constraint by the placement of a group
+type ConstraintStatus struct {
+	// the level constraint
+	LC *LevelConstraint
+	// number of members in each partition at the level of the constraint
+	Partitions []int
+	// constraint satisfied
+	Satisfied bool
+	// description of the violation (if any)
+	Reason string
+}
+
+// CheckLevelConstraints : check the partitions of the placement of this group
+// against its level constraints, ordered by level from the top down
+//   - returns nil if unplaced
+func (pg *PGroup) CheckLevelConstraints() []*ConstraintStatus {
+	if pg.lTree == nil || pg.lTree.GetRoot() == nil {
+		return nil
+	}
+
+	// partitions are the counts of logical nodes at each level, grouped by parent
+	partitions := make(map[int][][]int)
+	var visit func(node *topology.Node, level int)
+	visit = func(node *topology.Node, level int) {
+		children := node.GetSortedChildren()
+		counts := make([]int, 0, len(children))
+		for _, child := range children {
+			if n := (*topology.LNode)(unsafe.Pointer(child)).GetCount(); n > 0 {
+				counts = append(counts, n)
+			}
+		}
+		if len(counts) > 0 {
+			partitions[level-1] = append(partitions[level-1], counts)
+		}
+		for _, child := range children {
+			visit(child, level-1)
+		}
+	}
+	// the root is the single partition at its own level
+	height := pg.lTree.GetHeight()
+	if n := (*topology.LNode)(unsafe.Pointer(pg.lTree.GetRoot())).GetCount(); n > 0 {
+		partitions[height] = [][]int{{n}}
+	}
+	visit(pg.lTree.GetRoot(), height)
+
+	levels := make([]int, 0, len(pg.lcs))
+	for level := range pg.lcs {
+		levels = append(levels, level)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(levels)))
+	statuses := make([]*ConstraintStatus, 0, len(levels))
+	for _, level := range levels {
+		lc := pg.lcs[level]
+		reason := checkPartitions(lc, partitions[level])
+		flat := make([]int, 0)
+		for _, counts := range partitions[level] {
+			flat = append(flat, counts...)
+		}
+		status := &ConstraintStatus{
+			LC:         lc,
+			Partitions: flat,
+			Satisfied:  len(reason) == 0,
+			Reason:     reason,
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// checkPartitions : check partition sizes, grouped by parent, against a level constraint;
+// returns a description of the violation (empty if satisfied)
+func checkPartitions(lc *LevelConstraint, partitions [][]int) string {
+	if len(partitions) == 0 {
+		return "no members placed"
+	}
+	for _, counts := range partitions {
+		if lc.IsHard() {
+			if lc.Affinity() == util.Pack && len(counts) > 1 {
+				return fmt.Sprintf("hard pack in %d partitions", len(counts))
+			}
+			for _, n := range counts {
+				if lc.Affinity() == util.Spread && n > 1 {
+					return fmt.Sprintf("hard spread with partition of size %d", n)
+				}
+			}
+			continue
+		}
+		if num, ok := lc.GetNumPartitions(); ok && len(counts) != num {
+			return fmt.Sprintf("%d partitions, expected %d", len(counts), num)
+		}
+		for _, n := range counts {
+			if min, max, ok := lc.GetRange(); ok && (n < min || n > max) {
+				return fmt.Sprintf("partition of size %d outside range [%d,%d]", n, min, max)
+			}
+			if factor, ok := lc.GetFactor(); ok && n%factor != 0 {
+				return fmt.Sprintf("partition of size %d not a multiple of %d", n, factor)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,46 @@
+package placement
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+func TestPGroup_CheckLevelConstraints(t *testing.T) {
+	tests := []struct {
+		name           string
+		level          int
+		affinity       util.Affinity
+		isHard         bool
+		wantPartitions []int
+		wantSatisfied  bool
+	}{
+		{name: "hard pack at root", level: 2, affinity: util.Pack, isHard: true,
+			wantPartitions: []int{4}, wantSatisfied: true},
+		{name: "soft spread at root", level: 2, affinity: util.Spread, isHard: false,
+			wantPartitions: []int{4}, wantSatisfied: true},
+		{name: "hard pack at rack", level: 1, affinity: util.Pack, isHard: true,
+			wantPartitions: []int{4}, wantSatisfied: true},
+		{name: "spread at PE", level: 0, affinity: util.Spread, isHard: false,
+			wantPartitions: []int{2, 2}, wantSatisfied: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			pg := makePGroup("pg", 4, 2)
+			pg.AddLevelConstraint(NewLevelConstraint("lc-rack", 1, util.Pack, true))
+			pg.AddLevelConstraint(NewLevelConstraint("lc", tt.level, tt.affinity, tt.isHard))
+			place(t, pTree, pg)
+			for _, status := range pg.CheckLevelConstraints() {
+				if status.LC.GetID() != "lc" {
+					continue
+				}
+				if !reflect.DeepEqual(status.Partitions, tt.wantPartitions) || status.Satisfied != tt.wantSatisfied {
+					t.Errorf("CheckLevelConstraints() = %v, %v (%s), want %v, %v", status.Partitions,
+						status.Satisfied, status.Reason, tt.wantPartitions, tt.wantSatisfied)
+				}
+			}
+		})
+	}
+}
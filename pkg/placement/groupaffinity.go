@@ -0,0 +1,129 @@
+package placement
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/topology"
+)
+
+// AntiAffinity : an anti-affinity of a placement group to another group at a given level,
+// i.e. at most maxShared members are placed under a node at the level hosting members
+// of the other group (claimed on PEs), e.g. no two HA replicas of different services in a rack
+type AntiAffinity struct {
+	// ID of the other group
+	groupID string
+	// level of the nodes shared by the groups
+	level int
+	// maximum number of members placed under a node hosting members of the other group
+	maxShared int
+}
+
+// NewAntiAffinity : create a new anti-affinity to another group
+//   - returns nil if bad parameters
+func NewAntiAffinity(groupID string, level int, maxShared int) *AntiAffinity {
+	if len(groupID) == 0 || level < 0 || maxShared < 0 {
+		return nil
+	}
+	return &AntiAffinity{
+		groupID:   groupID,
+		level:     level,
+		maxShared: maxShared,
+	}
+}
+
+// GetGroupID : get the ID of the other group
+func (aa *AntiAffinity) GetGroupID() string {
+	return aa.groupID
+}
+
+// GetLevel : get the level of the nodes shared by the groups
+func (aa *AntiAffinity) GetLevel() int {
+	return aa.level
+}
+
+// GetMaxShared : get the maximum number of members placed under a node hosting members of the other group
+func (aa *AntiAffinity) GetMaxShared() int {
+	return aa.maxShared
+}
+
+// String : a print out of the anti-affinity
+func (aa *AntiAffinity) String() string {
+	return fmt.Sprintf("AntiAffinity: group=%s; level=%d; maxShared=%d; ", aa.groupID, aa.level, aa.maxShared)
+}
+
+// GroupAffinity : an affinity of a placement group to another group at a given level,
+// i.e. members are placed under nodes at the level hosting members of the other group
+// (claimed on PEs), e.g. a compute job in the racks of its data caching group
+//   - isHard: If true then members are only placed under such nodes, otherwise (Soft) the group
+//   - is placed without the affinity if it cannot be fully placed under such nodes
+type GroupAffinity struct {
+	// ID of the other group
+	groupID string
+	// level of the nodes shared by the groups
+	level int
+	// hard or soft
+	isHard bool
+}
+
+// NewGroupAffinity : create a new affinity to another group
+//   - returns nil if bad parameters
+func NewGroupAffinity(groupID string, level int, isHard bool) *GroupAffinity {
+	if len(groupID) == 0 || level < 0 {
+		return nil
+	}
+	return &GroupAffinity{
+		groupID: groupID,
+		level:   level,
+		isHard:  isHard,
+	}
+}
+
+// GetGroupID : get the ID of the other group
+func (ga *GroupAffinity) GetGroupID() string {
+	return ga.groupID
+}
+
+// GetLevel : get the level of the nodes shared by the groups
+func (ga *GroupAffinity) GetLevel() int {
+	return ga.level
+}
+
+// IsHard : affinity is hard or soft
+func (ga *GroupAffinity) IsHard() bool {
+	return ga.isHard
+}
+
+// String : a print out of the affinity
+func (ga *GroupAffinity) String() string {
+	return fmt.Sprintf("GroupAffinity: group=%s; level=%d; isHard=%v; ", ga.groupID, ga.level, ga.isHard)
+}
+
+// countGroupMembers : number of members of a group hosted in the subtree of each node at a level,
+// keyed by node ID (nodes with no members are omitted)
+func countGroupMembers(pTree *topology.PTree, groupID string, level int) map[string]int {
+	counts := make(map[string]int)
+	pes := pTree.GetPEs()
+	for _, leaf := range pTree.GetLeaves() {
+		pe := pes[leaf.GetID()]
+		if pe == nil {
+			continue
+		}
+		n := 0
+		for _, le := range pe.GetHostedLEs() {
+			if le.GetGroupID() == groupID {
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+		for _, node := range leaf.GetPathToRoot() {
+			if (*topology.PNode)(unsafe.Pointer(node)).GetLevel() == level {
+				counts[node.GetID()] += n
+				break
+			}
+		}
+	}
+	return counts
+}
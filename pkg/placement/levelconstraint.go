@@ -21,6 +21,10 @@ import (
 //   - (makes more sense to have a number of partitions with Spread affinity)
 //   - Both range[min,max] and numPartitions may be specified; they are considered as strict constraints
 //   - factor: number allocated at level is multiple of the value of factor
+//   - homogeneousKey: all members placed under a node at the level are on PEs with the same
+//   - value of the label key (labels merged along the path from the root), chosen by the placer
+//   - exclusive: members are placed under nodes at the level hosting no LEs of other groups,
+//   - and the nodes are reserved for the group while claimed
 //   - (note that some combinations of parameter values lead to infeasible solutions)
 //
 // TODO: Add feasibility checking of range and number of partitions across levels
@@ -44,6 +48,10 @@ type LevelConstraint struct {
 	numPartitions int
 	// factor
 	factor int
+	// label key with a common value for all members under a node at the level
+	homogeneousKey string
+	// nodes at the level are exclusive to the group
+	isExclusive bool
 }
 
 var (
@@ -66,6 +74,12 @@ func NewLevelConstraint(id string, level int, affinity util.Affinity, isHard boo
 	}
 }
 
+// Clone : a copy of the level constraint
+func (lc *LevelConstraint) Clone() *LevelConstraint {
+	clone := *lc
+	return &clone
+}
+
 // GetID : the unique ID
 func (lc *LevelConstraint) GetID() string {
 	return lc.Entity.ID
@@ -138,6 +152,31 @@ func (lc *LevelConstraint) GetFactor() (int, bool) {
 	return lc.factor, true
 }
 
+// SetHomogeneousKey : set a label key whose value is the same for all members
+// placed under a node at the level (false if empty)
+func (lc *LevelConstraint) SetHomogeneousKey(key string) bool {
+	if len(key) == 0 {
+		return false
+	}
+	lc.homogeneousKey = key
+	return true
+}
+
+// GetHomogeneousKey : get the homogeneous label key and a boolean if set
+func (lc *LevelConstraint) GetHomogeneousKey() (string, bool) {
+	return lc.homogeneousKey, len(lc.homogeneousKey) > 0
+}
+
+// SetExclusive : set nodes at the level exclusive to the group
+func (lc *LevelConstraint) SetExclusive(isExclusive bool) {
+	lc.isExclusive = isExclusive
+}
+
+// IsExclusive : nodes at the level are exclusive to the group
+func (lc *LevelConstraint) IsExclusive() bool {
+	return lc.isExclusive
+}
+
 // String : a print out of the level constraint
 func (lc *LevelConstraint) String() string {
 	s := fmt.Sprintf("LC: ID=%s; level=%d; affinity=%s; isHard=%v; ",
@@ -151,5 +190,11 @@ func (lc *LevelConstraint) String() string {
 	if factor, ok := lc.GetFactor(); ok {
 		s += fmt.Sprintf("factor=%d; ", factor)
 	}
+	if key, ok := lc.GetHomogeneousKey(); ok {
+		s += fmt.Sprintf("homogeneousKey=%s; ", key)
+	}
+	if lc.isExclusive {
+		s += "exclusive; "
+	}
 	return s
 }
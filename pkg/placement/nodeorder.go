@@ -0,0 +1,116 @@
+package placement
+
+import (
+	"math"
+	"strings"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// NodeOrder : the ordering of sibling nodes with the same number of members that can fit
+type NodeOrder int
+
+const (
+	// number of members that can fit only (siblings with the same number are ordered by ID)
+	NumFitOrder NodeOrder = iota
+	// prefer the node left with the smaller dominant share after placement, i.e. the largest share of
+	// the total capacity of a resource left available
+	DominantShareOrder
+	// prefer the node left with the smaller weighted sum of shares of the total capacity of resources
+	// left available after placement
+	WeightedLeftoverOrder
+)
+
+// NodeOrderToString : get the string representation of a node order
+func NodeOrderToString(order NodeOrder) string {
+	switch order {
+	case NumFitOrder:
+		return "NumFit"
+	case DominantShareOrder:
+		return "DominantShare"
+	case WeightedLeftoverOrder:
+		return "WeightedLeftover"
+	}
+	return "Unknown"
+}
+
+// StringToNodeOrder : get the node order given its string representation (case insensitive)
+func StringToNodeOrder(s string) (NodeOrder, bool) {
+	for _, order := range []NodeOrder{NumFitOrder, DominantShareOrder, WeightedLeftoverOrder} {
+		if strings.EqualFold(s, NodeOrderToString(order)) {
+			return order, true
+		}
+	}
+	return NumFitOrder, false
+}
+
+// GetNodeOrder : get the ordering of sibling nodes with the same number of members that can fit,
+// and the weights of resources of the weighted leftover order (nil if equal weights)
+func (pg *PGroup) GetNodeOrder() (NodeOrder, []float64) {
+	return pg.nodeOrder, pg.resourceWeights
+}
+
+// SetNodeOrder : set the ordering of sibling nodes with the same number of members that can fit,
+// so as to preserve scarce resources for future groups
+//   - weights: weights of resources of the weighted leftover order, nil for equal weights
+//   - returns false if weights differ in length from the demand or are negative
+func (pg *PGroup) SetNodeOrder(order NodeOrder, weights []float64) bool {
+	if weights != nil {
+		if len(weights) != pg.demand.GetSize() {
+			return false
+		}
+		for _, w := range weights {
+			if w < 0 {
+				return false
+			}
+		}
+		weights = append([]float64{}, weights...)
+	}
+	pg.nodeOrder = order
+	pg.resourceWeights = weights
+	return true
+}
+
+// leftoverScores : the scores of the resources left available on nodes after placing members,
+// lower scores are preferred (nil if ordering by number that can fit only)
+//   - the number of members placed on a node is the number that can fit, up to the number remaining
+//   - leftover resources are normalized as shares of the total capacity of the tree
+func (p *Placer) leftoverScores(nodes []*topology.Node) map[string]float64 {
+	order, weights := p.pg.GetNodeOrder()
+	if order == NumFitOrder {
+		return nil
+	}
+	pRoot := (*topology.PNode)(unsafe.Pointer(p.pTree.GetRoot()))
+	total := pRoot.GetCapacity().GetValue()
+	demand := p.pg.GetDemand().GetValue()
+	if len(total) != len(demand) {
+		return nil
+	}
+	scores := make(map[string]float64)
+	for _, node := range nodes {
+		pNode := (*topology.PNode)(unsafe.Pointer(node))
+		k := util.Min(pNode.GetNumFit(), p.numRemaining)
+		available := pNode.GetAvailable().GetValue()
+		score := 0.0
+		for i := range total {
+			if total[i] <= 0 {
+				continue
+			}
+			share := float64(util.Max(available[i]-k*demand[i], 0)) / float64(total[i])
+			switch order {
+			case DominantShareOrder:
+				score = math.Max(score, share)
+			case WeightedLeftoverOrder:
+				w := 1.0
+				if weights != nil {
+					w = weights[i]
+				}
+				score += w * share
+			}
+		}
+		scores[pNode.GetID()] = score
+	}
+	return scores
+}
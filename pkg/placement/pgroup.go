@@ -29,6 +29,22 @@ type PGroup struct {
 	lTree *topology.LTree
 	// group of LEs
 	leGroup *system.LEGroup
+	// diagnostics of the last placement
+	diagnostics []*Diagnostic
+	// tolerations of taints on PEs
+	tolerations []system.Toleration
+	// selectors on the labels of PEs, merged along the path from the root
+	selectors []*system.Selector
+	// anti-affinities to other groups
+	antiAffinities []*AntiAffinity
+	// affinities to other groups
+	affinities []*GroupAffinity
+	// ordering of sibling nodes with the same number of members that can fit
+	nodeOrder NodeOrder
+	// weights of resources of the weighted leftover order (nil if equal weights)
+	resourceWeights []float64
+	// expected duration of the group once claimed, in time units (zero if unknown)
+	duration int
 }
 
 // NewPGroup : create a new placement group
@@ -59,6 +75,21 @@ func (pg *PGroup) GetSize() int {
 	return pg.size
 }
 
+// GetDuration : get the expected duration of the group once claimed, in time units (zero if unknown)
+func (pg *PGroup) GetDuration() int {
+	return pg.duration
+}
+
+// SetDuration : set the expected duration of the group once claimed, in time units; zero if unknown
+//   - returns false if negative
+func (pg *PGroup) SetDuration(duration int) bool {
+	if duration < 0 {
+		return false
+	}
+	pg.duration = duration
+	return true
+}
+
 // GetDemand : get resource demand
 func (pg *PGroup) GetDemand() *util.Allocation {
 	return pg.demand
@@ -118,6 +149,75 @@ func (pg *PGroup) GetLevelConstraintIDs() []string {
 	return ids
 }
 
+// AddToleration : add a toleration of taints on PEs
+func (pg *PGroup) AddToleration(toleration system.Toleration) {
+	pg.tolerations = append(pg.tolerations, toleration)
+}
+
+// GetTolerations : get the tolerations of taints on PEs
+func (pg *PGroup) GetTolerations() []system.Toleration {
+	return pg.tolerations
+}
+
+// IsEligible : members of this placement group may be placed on a PE,
+// i.e. the PE is schedulable and all its taints are tolerated
+func (pg *PGroup) IsEligible(pe *system.PE) bool {
+	return pe.IsSchedulable() && system.ToleratesAll(pg.tolerations, pe.GetTaints())
+}
+
+// AddSelector : add a selector on the labels of PEs;
+// all selectors have to match for a PE to host members
+func (pg *PGroup) AddSelector(selector *system.Selector) {
+	if selector != nil {
+		pg.selectors = append(pg.selectors, selector)
+	}
+}
+
+// GetSelectors : get the selectors on the labels of PEs
+func (pg *PGroup) GetSelectors() []*system.Selector {
+	return pg.selectors
+}
+
+// MatchesLabels : members of this placement group may be placed on a PE with given labels,
+// i.e. all selectors match the labels
+func (pg *PGroup) MatchesLabels(labels map[string]string) bool {
+	return system.MatchesAll(pg.selectors, labels)
+}
+
+// AddAntiAffinity : add an anti-affinity to another group
+func (pg *PGroup) AddAntiAffinity(aa *AntiAffinity) {
+	if aa != nil && aa.groupID != pg.GetID() {
+		pg.antiAffinities = append(pg.antiAffinities, aa)
+	}
+}
+
+// GetAntiAffinities : get the anti-affinities to other groups
+func (pg *PGroup) GetAntiAffinities() []*AntiAffinity {
+	return pg.antiAffinities
+}
+
+// AddGroupAffinity : add an affinity to another group
+func (pg *PGroup) AddGroupAffinity(ga *GroupAffinity) {
+	if ga != nil && ga.groupID != pg.GetID() {
+		pg.affinities = append(pg.affinities, ga)
+	}
+}
+
+// GetGroupAffinities : get the affinities to other groups
+func (pg *PGroup) GetGroupAffinities() []*GroupAffinity {
+	return pg.affinities
+}
+
+// hasSoftGroupAffinity : this group has a soft affinity to another group
+func (pg *PGroup) hasSoftGroupAffinity() bool {
+	for _, ga := range pg.affinities {
+		if !ga.IsHard() {
+			return true
+		}
+	}
+	return false
+}
+
 // GetLTree : get the logical tree for this placement group
 //   - returns nil if unplaced
 func (pg *PGroup) GetLTree() *topology.LTree {
@@ -129,11 +229,34 @@ func (pg *PGroup) SetLTree(lTree *topology.LTree) {
 	pg.lTree = lTree
 }
 
+// GetDiagnostics : get the diagnostics of the last placement of this placement group
+func (pg *PGroup) GetDiagnostics() []*Diagnostic {
+	return pg.diagnostics
+}
+
+// SetDiagnostics : set the diagnostics of the last placement of this placement group
+func (pg *PGroup) SetDiagnostics(diagnostics []*Diagnostic) {
+	pg.diagnostics = diagnostics
+}
+
 // GetLEGroup : get the group of LEs in this placement group
 func (pg *PGroup) GetLEGroup() *system.LEGroup {
 	return pg.leGroup
 }
 
+// GetBindings : get a map of IDs of claimed members (LEs) to IDs of their host PEs
+func (pg *PGroup) GetBindings() map[string]string {
+	bindings := make(map[string]string)
+	if pg.leGroup != nil {
+		for _, le := range pg.leGroup.GetLEs() {
+			if pe := le.GetHost(); pe != nil {
+				bindings[le.GetID()] = pe.GetID()
+			}
+		}
+	}
+	return bindings
+}
+
 // IsFullyPlaced : are all members of the group placed
 func (pg *PGroup) IsFullyPlaced() bool {
 	if pg.lTree != nil {
@@ -145,27 +268,55 @@ func (pg *PGroup) IsFullyPlaced() bool {
 	return false
 }
 
+// PruneLTree : remove leaves of the logical tree of this placement group which are
+// no longer PEs in a physical tree (e.g. after removing a subtree), and return the count removed
+func (pg *PGroup) PruneLTree(pTree *topology.PTree) int {
+	if pTree == nil || pg.lTree == nil {
+		return 0
+	}
+	pLeaves := pTree.GetLeavesMap()
+	ids := make([]string, 0)
+	for _, leaf := range pg.lTree.GetLeaves() {
+		if _, exists := pLeaves[leaf.GetID()]; !exists {
+			ids = append(ids, leaf.GetID())
+		}
+	}
+	return pg.lTree.RemoveLeaves(ids)
+}
+
 // ClaimAll : claim all members of this placement group and allocate them
 func (pg *PGroup) ClaimAll(pTree *topology.PTree) bool {
 	return pg.Claim(pg.size, pTree)
 }
 
 // Claim : claim n members of this placement group and allocate them
+//   - members exceeding the capacity of strict PEs, or not placed on PEs in the tree, are not claimed
 func (pg *PGroup) Claim(n int, pTree *topology.PTree) bool {
+	return pg.TryClaim(n, pTree) == nil
+}
+
+// TryClaim : claim n members of this placement group and allocate them
+//   - members exceeding the capacity of strict PEs, or the maximum number of LEs hosted on PEs,
+//     are not claimed, and the first such CapacityError (naming the resource) or MaxLEsError is returned
+//   - members not placed on PEs in the tree (e.g. removed), or failing otherwise, are not claimed,
+//     and the first error is returned
+//   - returns error if unplaced
+func (pg *PGroup) TryClaim(n int, pTree *topology.PTree) error {
 	lTree := pg.lTree
 	leGroup := pg.GetLEGroup()
 	if pTree == nil || lTree == nil || leGroup == nil {
-		return false
+		return fmt.Errorf("group %s not placed", pg.GetID())
 	}
 	lLeaves := lTree.GetLeaves()
 	if len(lLeaves) == 0 {
-		return false
+		return fmt.Errorf("group %s not placed", pg.GetID())
 	}
 
 	// get a map of PEs
 	serverMap := pTree.GetPEs()
 
 	// allocate LEs
+	var claimErr error
 	les := leGroup.GetLEs()
 	index := 0
 loop:
@@ -174,23 +325,47 @@ loop:
 		lNode.SetClaimed(0)
 		peID := lNode.GetID()
 		pe := serverMap[peID]
-		if pe != nil {
-			for i := 0; i < lNode.GetCount(); i++ {
-				lei := les[index]
-				index++
-				if pe.PlaceLE(lei) {
-					lNode.IncClaimed(1)
-				}
-				if index == n {
-					break loop
+		for i := 0; i < lNode.GetCount(); i++ {
+			lei := les[index]
+			index++
+			if pe == nil {
+				if claimErr == nil {
+					claimErr = fmt.Errorf("PE %s of group %s not in tree", peID, pg.GetID())
 				}
+			} else if err := pe.TryPlaceLE(lei); err == nil {
+				lNode.IncClaimed(1)
+			} else if claimErr == nil {
+				claimErr = err
+			}
+			if index == n {
+				break loop
 			}
 		}
 	}
 	lTree.PercolateClaimed()
 	lTree.SetPhysicalClaimed()
 	pTree.PercolateResources()
-	return true
+	pg.reserveExclusive(pTree)
+	return claimErr
+}
+
+// reserveExclusive : reserve the nodes, at the levels of exclusive level constraints,
+// where members are claimed
+func (pg *PGroup) reserveExclusive(pTree *topology.PTree) {
+	for level, lc := range pg.lcs {
+		if !lc.IsExclusive() {
+			continue
+		}
+		for _, node := range pg.lTree.GetNodeListBFS() {
+			if (*topology.LNode)(unsafe.Pointer(node)).GetClaimed() == 0 {
+				continue
+			}
+			if pNode := (*topology.PNode)(unsafe.Pointer(pTree.GetNode(node.GetID()))); pNode != nil &&
+				pNode.GetLevel() == level {
+				pNode.SetReservedBy(pg.GetID())
+			}
+		}
+	}
 }
 
 // UnClaimAll : unclaim all members of this placement group and deallocate them
@@ -212,6 +387,7 @@ func (pg *PGroup) UnClaimAll(pTree *topology.PTree) bool {
 	lTree.ResetClaimed(true)
 	pTree.ResetNumClaimed()
 	pTree.PercolateResources()
+	pTree.ReleaseReserved(pg.GetID())
 	return true
 }
 
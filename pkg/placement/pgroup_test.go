@@ -0,0 +1,172 @@
+package placement
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/system"
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// makePNode : make a PNode, with a PE of a given cpu capacity if a leaf
+func makePNode(id string, cpu int, children ...*topology.PNode) *topology.PNode {
+	var entity *system.Entity
+	if len(children) == 0 {
+		capacity, _ := util.NewAllocationCopy([]int{cpu})
+		entity = (*system.Entity)(unsafe.Pointer(system.NewPE(id, capacity)))
+	} else {
+		entity = &system.Entity{ID: id}
+	}
+	pNode := topology.NewPNode(topology.NewNode(entity), 0, 1)
+	for _, child := range children {
+		pNode.AddChild((*topology.Node)(unsafe.Pointer(child)))
+	}
+	return pNode
+}
+
+// makePTree : make a tree of two racks, with two PEs of 4 cpus each
+func makePTree() *topology.PTree {
+	root := makePNode("root", 0,
+		makePNode("rack-0", 0, makePNode("pe-0", 4), makePNode("pe-1", 4)),
+		makePNode("rack-1", 0, makePNode("pe-2", 4), makePNode("pe-3", 4)))
+	pTree := topology.NewPTree(topology.NewTree((*topology.Node)(unsafe.Pointer(root))))
+	pTree.SetNodeLevels()
+	pTree.PercolateResources()
+	return pTree
+}
+
+// makePGroup : make a group of members of a given cpu demand
+func makePGroup(id string, size int, cpu int) *PGroup {
+	demand, _ := util.NewAllocationCopy([]int{cpu})
+	return NewPGroup(id, size, demand)
+}
+
+// place : place a group on a tree, failing the test if not fully placed
+func place(t *testing.T, pTree *topology.PTree, pg *PGroup) {
+	if _, err := NewPlacer(pTree).PlaceGroup(pg); err != nil || !pg.IsFullyPlaced() {
+		t.Fatalf("PlaceGroup() group %s error = %v, fully placed %v", pg.GetID(), err, pg.IsFullyPlaced())
+	}
+}
+
+// placedOn : number of members of a group placed on each PE
+func placedOn(pg *PGroup) map[string]int {
+	placed := make(map[string]int)
+	for _, leaf := range pg.GetLTree().GetLeaves() {
+		placed[leaf.GetID()] = (*topology.LNode)(unsafe.Pointer(leaf)).GetCount()
+	}
+	return placed
+}
+
+// firstPE : the (sorted) first PE where a group is placed
+func firstPE(pg *PGroup) string {
+	first := ""
+	for id := range placedOn(pg) {
+		if len(first) == 0 || id < first {
+			first = id
+		}
+	}
+	return first
+}
+
+func TestPGroup_TryClaim(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		// change to the tree after placing and before claiming
+		change  func(pTree *topology.PTree, pg *PGroup)
+		wantErr string
+	}{
+		{
+			name:   "all claimed",
+			size:   4,
+			change: func(pTree *topology.PTree, pg *PGroup) {},
+		},
+		{
+			name: "capacity exceeded on strict PE",
+			size: 4,
+			change: func(pTree *topology.PTree, pg *PGroup) {
+				full, _ := util.NewAllocationCopy([]int{4})
+				pTree.GetPEs()[firstPE(pg)].SetAllocated(full)
+			},
+			wantErr: "exceeds resource[0] capacity",
+		},
+		{
+			name: "maximum LEs reached",
+			size: 4,
+			change: func(pTree *topology.PTree, pg *PGroup) {
+				pTree.GetPEs()[firstPE(pg)].SetMaxLEs(1)
+			},
+			wantErr: "exceeds maximum of 1 hosted LEs",
+		},
+		{
+			name: "PE removed from tree",
+			size: 4,
+			change: func(pTree *topology.PTree, pg *PGroup) {
+				pTree.RemoveSubtree(firstPE(pg))
+			},
+			wantErr: "not in tree",
+		},
+		{
+			name: "already claimed",
+			size: 1,
+			change: func(pTree *topology.PTree, pg *PGroup) {
+				pg.TryClaim(pg.GetSize(), pTree)
+			},
+			wantErr: "already hosted",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			pg := makePGroup("pg", tt.size, 2)
+			place(t, pTree, pg)
+			tt.change(pTree, pg)
+			err := pg.TryClaim(pg.GetSize(), pTree)
+			claimed := (*topology.LNode)(unsafe.Pointer(pg.GetLTree().GetRoot())).GetClaimed()
+			if len(tt.wantErr) == 0 {
+				if err != nil || claimed != pg.GetSize() {
+					t.Errorf("TryClaim() error = %v, claimed %d, want all claimed", err, claimed)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) || claimed == pg.GetSize() {
+				t.Errorf("TryClaim() error = %v, claimed %d, want %q", err, claimed, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPGroup_PruneLTree(t *testing.T) {
+	pTree := makePTree()
+	pg := makePGroup("pg", 4, 2)
+	place(t, pTree, pg)
+	if err := pg.TryClaim(pg.GetSize(), pTree); err != nil {
+		t.Fatalf("TryClaim() error = %v", err)
+	}
+	pe := firstPE(pg)
+	numOnPE := placedOn(pg)[pe]
+
+	// remove a PE hosting members, then prune the groups with evicted members
+	groupIDs, err := pTree.RemoveSubtree(pe)
+	if err != nil || len(groupIDs) != 1 || groupIDs[0] != pg.GetID() {
+		t.Fatalf("RemoveSubtree() = %v, %v, want [%s]", groupIDs, err, pg.GetID())
+	}
+	if got := pg.PruneLTree(pTree); got != numOnPE {
+		t.Errorf("PruneLTree() = %d, want %d", got, numOnPE)
+	}
+	lRoot := (*topology.LNode)(unsafe.Pointer(pg.GetLTree().GetRoot()))
+	if _, exists := placedOn(pg)[pe]; exists || pg.IsFullyPlaced() ||
+		lRoot.GetCount() != 4-numOnPE || lRoot.GetClaimed() != 4-numOnPE {
+		t.Errorf("PruneLTree() left placed %v, count %d, claimed %d", placedOn(pg), lRoot.GetCount(), lRoot.GetClaimed())
+	}
+
+	// the remaining members are claimed again
+	pg.UnClaimAll(pTree)
+	if err := pg.TryClaim(lRoot.GetCount(), pTree); err != nil {
+		t.Errorf("TryClaim() after pruning error = %v", err)
+	}
+	pRoot := (*topology.PNode)(unsafe.Pointer(pTree.GetRoot()))
+	if got := pRoot.GetAllocated().GetValue()[0]; got != 2*(4-numOnPE) {
+		t.Errorf("allocated after pruning = %d, want %d", got, 2*(4-numOnPE))
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"sort"
 	"unsafe"
 
+	"k8s.io/klog/v2"
+
+	"github.com/ibm/chic-sched/pkg/system"
 	"github.com/ibm/chic-sched/pkg/topology"
 	"github.com/ibm/chic-sched/pkg/util"
 )
@@ -20,6 +23,14 @@ type Placer struct {
 	numRemaining int
 	// keep track of number of claimed remaining
 	numClaimedRemaining int
+	// nodes where placement fell short
+	diagnostics []*Diagnostic
+	// IDs of PEs whose labels match the selectors of the group (nil if no selectors)
+	selected map[string]bool
+	// IDs of PEs excluded by group affinities and homogeneous level constraints
+	excluded map[string]bool
+	// soft group affinities are relaxed
+	relaxed bool
 }
 
 // NewPlacer : create a new placer
@@ -33,9 +44,26 @@ func NewPlacer(pTree *topology.PTree) *Placer {
 		pg:                  nil,
 		numRemaining:        0,
 		numClaimedRemaining: 0,
+		diagnostics:         make([]*Diagnostic, 0),
 	}
 }
 
+// GetDiagnostics : get the diagnostics of the last group placement
+func (p *Placer) GetDiagnostics() []*Diagnostic {
+	return p.diagnostics
+}
+
+// addDiagnostic : record a node where placement fell short
+func (p *Placer) addDiagnostic(pNode *topology.PNode, numPlaced int, format string, a ...interface{}) {
+	p.diagnostics = append(p.diagnostics, &Diagnostic{
+		NodeID:    pNode.GetID(),
+		Level:     pNode.GetLevel(),
+		NumFit:    pNode.GetNumFit(),
+		NumPlaced: numPlaced,
+		Reason:    fmt.Sprintf(format, a...),
+	})
+}
+
 // PlaceInit : initialize group placement
 func (p *Placer) PlaceInit(pg *PGroup) (*topology.PNode, error) {
 	if pg == nil {
@@ -47,6 +75,8 @@ func (p *Placer) PlaceInit(pg *PGroup) (*topology.PNode, error) {
 	}
 	p.pg = pg
 	p.numRemaining = pg.GetSize()
+	p.diagnostics = make([]*Diagnostic, 0)
+	pg.SetDiagnostics(p.diagnostics)
 	if p.numRemaining == 0 {
 		return pRoot, fmt.Errorf("empty group")
 	}
@@ -54,12 +84,162 @@ func (p *Placer) PlaceInit(pg *PGroup) (*topology.PNode, error) {
 	if demand == nil {
 		return pRoot, fmt.Errorf("group demand is nil")
 	}
+	// select PEs by labels
+	p.selected = nil
+	p.excluded = make(map[string]bool)
+	var peLabels map[string]map[string]string
+	if len(pg.GetSelectors()) > 0 {
+		peLabels = p.pTree.GetPELabels()
+		p.selected = make(map[string]bool)
+		for id, labels := range peLabels {
+			if pg.MatchesLabels(labels) {
+				p.selected[id] = true
+			}
+		}
+	}
+	p.excludeReserved()
+	p.excludeNonAffine()
+	p.excludeHeterogeneous(peLabels)
 	// calculate number of members that can fit on all nodes
 	// of the physical tree
-	p.pTree.PercolateNumFit(demand)
+	p.pTree.PercolateNumFitFunc(p.numFitPE)
+	p.pTree.LimitNumFit(demand, p.antiAffinityCaps())
 	return pRoot, nil
 }
 
+// antiAffinityCaps : maximum number that can fit under nodes hosting members of other groups,
+// shared by the anti-affinities of the group, mapped to node IDs
+func (p *Placer) antiAffinityCaps() map[string]int {
+	caps := make(map[string]int)
+	for _, aa := range p.pg.GetAntiAffinities() {
+		for id := range countGroupMembers(p.pTree, aa.GetGroupID(), aa.GetLevel()) {
+			if numFit, exists := caps[id]; !exists || aa.GetMaxShared() < numFit {
+				caps[id] = aa.GetMaxShared()
+				klog.V(4).Infof("anti-affinity to group %s caps numFit at node %s to %d", aa.GetGroupID(), id,
+					aa.GetMaxShared())
+			}
+		}
+	}
+	return caps
+}
+
+// excludeReserved : exclude PEs under nodes reserved for other groups, and PEs under nodes,
+// at the level of an exclusive level constraint, hosting LEs of other groups
+func (p *Placer) excludeReserved() {
+	id := p.pg.GetID()
+	for _, leaf := range p.pTree.GetLeaves() {
+		if (*topology.PNode)(unsafe.Pointer(leaf)).IsReservedForOther(id) {
+			p.excluded[leaf.GetID()] = true
+		}
+	}
+	pes := p.pTree.GetPEs()
+	for level, lc := range p.pg.lcs {
+		if !lc.IsExclusive() {
+			continue
+		}
+		for _, node := range p.pTree.GetNodeListBFS() {
+			if (*topology.PNode)(unsafe.Pointer(node)).GetLevel() != level {
+				continue
+			}
+			leaves := node.GetLeaves()
+			shared := false
+			for _, leaf := range leaves {
+				if pe := pes[leaf.GetID()]; pe != nil {
+					for _, le := range pe.GetHostedLEs() {
+						shared = shared || le.GetGroupID() != id
+					}
+				}
+			}
+			if shared {
+				for _, leaf := range leaves {
+					p.excluded[leaf.GetID()] = true
+				}
+			}
+		}
+	}
+}
+
+// excludeNonAffine : exclude PEs not under a node, at the level of a group affinity,
+// hosting members of the other group (soft affinities unless relaxed)
+func (p *Placer) excludeNonAffine() {
+	for _, ga := range p.pg.GetGroupAffinities() {
+		if !ga.IsHard() && p.relaxed {
+			continue
+		}
+		counts := countGroupMembers(p.pTree, ga.GetGroupID(), ga.GetLevel())
+		for _, leaf := range p.pTree.GetLeaves() {
+			affine := false
+			for _, node := range leaf.GetPathToRoot() {
+				if (*topology.PNode)(unsafe.Pointer(node)).GetLevel() == ga.GetLevel() {
+					affine = counts[node.GetID()] > 0
+					break
+				}
+			}
+			if !affine {
+				p.excluded[leaf.GetID()] = true
+			}
+		}
+	}
+}
+
+// excludeHeterogeneous : for each node at the level of a level constraint with a homogeneous key,
+// choose the label value with the most members that fit in the subtree of the node
+// (smallest value if tied), and exclude the PEs in the subtree with other (or no) values;
+// levels are visited from the top down
+func (p *Placer) excludeHeterogeneous(peLabels map[string]map[string]string) {
+	levels := make([]int, 0)
+	for level, lc := range p.pg.lcs {
+		if _, ok := lc.GetHomogeneousKey(); ok {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) == 0 {
+		return
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(levels)))
+	if peLabels == nil {
+		peLabels = p.pTree.GetPELabels()
+	}
+	pes := p.pTree.GetPEs()
+	for _, level := range levels {
+		key, _ := p.pg.lcs[level].GetHomogeneousKey()
+		for _, node := range p.pTree.GetNodeListBFS() {
+			if (*topology.PNode)(unsafe.Pointer(node)).GetLevel() != level {
+				continue
+			}
+			leaves := node.GetLeaves()
+			fit := make(map[string]int)
+			for _, leaf := range leaves {
+				pe := pes[leaf.GetID()]
+				if value, exists := peLabels[leaf.GetID()][key]; exists && pe != nil {
+					fit[value] += p.numFitPE(pe)
+				}
+			}
+			chosen, maxFit := "", -1
+			for value, n := range fit {
+				if n > maxFit || (n == maxFit && value < chosen) {
+					chosen, maxFit = value, n
+				}
+			}
+			for _, leaf := range leaves {
+				if value, exists := peLabels[leaf.GetID()][key]; !exists || value != chosen || maxFit < 0 {
+					p.excluded[leaf.GetID()] = true
+				}
+			}
+			klog.V(4).Infof("homogeneous %s=%s at node %s (numFit=%d)", key, chosen, node.GetID(), maxFit)
+		}
+	}
+}
+
+// numFitPE : number of members of the group that can fit on a PE
+// (zero if not eligible, not selected, or excluded)
+func (p *Placer) numFitPE(pe *system.PE) int {
+	if !p.pg.IsEligible(pe) || (p.selected != nil && !p.selected[pe.GetID()]) || p.excluded[pe.GetID()] {
+		return 0
+	}
+	return pe.NumberToFit(p.pg.GetDemand())
+}
+
 // PlaceCleanup : cleanup after group placement
 func (p *Placer) PlaceCleanup() {
 	if p.pTree != nil {
@@ -68,13 +248,45 @@ func (p *Placer) PlaceCleanup() {
 }
 
 // PlaceGroup : place a group
+//   - if not fully placed with its soft group affinities, the group is placed again without them
 func (p *Placer) PlaceGroup(pg *PGroup) (*topology.LTree, error) {
+	return p.placeRelaxing(pg, p.placeGroup)
+}
+
+// placeRelaxing : place a group by a given function, first with soft group affinities,
+// then without them if not fully placed
+func (p *Placer) placeRelaxing(pg *PGroup,
+	place func(pg *PGroup) (*topology.LTree, error)) (*topology.LTree, error) {
+
 	defer p.PlaceCleanup()
+	p.relaxed = false
+	lTree, err := place(pg)
+	if err != nil || pg.IsFullyPlaced() || !pg.hasSoftGroupAffinity() {
+		return lTree, err
+	}
+	klog.V(4).Infof("group %s not fully placed with soft group affinities, relaxing", pg.GetID())
+	p.relaxed = true
+	lTree, err = place(pg)
+	if err == nil {
+		numPlaced := (*topology.LNode)(unsafe.Pointer(lTree.GetRoot())).GetCount()
+		p.addDiagnostic((*topology.PNode)(unsafe.Pointer(p.pTree.GetRoot())), numPlaced,
+			"soft group affinities not satisfied, placed without them")
+		pg.SetDiagnostics(p.diagnostics)
+	}
+	return lTree, err
+}
+
+// placeGroup : place a group, given the relaxation of soft group affinities
+func (p *Placer) placeGroup(pg *PGroup) (*topology.LTree, error) {
 	pRoot, err := p.PlaceInit(pg)
 	if err != nil {
 		return nil, err
 	}
+	if pRoot.GetNumFit() < p.numRemaining {
+		p.addDiagnostic(pRoot, 0, "only %d of %d members fit in the tree", pRoot.GetNumFit(), p.numRemaining)
+	}
 	lRoot := p.placeAtNode(pRoot, 1, p.numRemaining, 0)
+	pg.SetDiagnostics(p.diagnostics)
 	if lRoot == nil {
 		return nil, fmt.Errorf("lRoot is nil, failed placement")
 	}
@@ -101,6 +313,9 @@ func (p *Placer) placeAtNode(pNode *topology.PNode, numNodes int, numToPlace int
 	// select number in range based on node availability
 	numDesired := sr.NumberToPlace(pNode.GetNumFit())
 	if numDesired == 0 {
+		if pNode.GetNumFit() > 0 {
+			p.addDiagnostic(pNode, 0, "fit below size range %v", sr)
+		}
 		return lNode
 	}
 
@@ -111,8 +326,8 @@ func (p *Placer) placeAtNode(pNode *topology.PNode, numNodes int, numToPlace int
 		numPlaced = numDesired
 		p.numRemaining -= numPlaced
 	} else {
-		// process children of pNode
-		children := pNode.GetChildren()
+		// process children of pNode, in order of ID among those ranking the same
+		children := pNode.GetSortedChildren()
 		numChildren := len(children)
 
 		// check number of partitions and range
@@ -150,13 +365,17 @@ func (p *Placer) placeAtNode(pNode *topology.PNode, numNodes int, numToPlace int
 					numPartitionsUsed++
 				}
 			}
+		} else {
+			p.addDiagnostic(pNode, 0, "%d children for %d partitions of minimum size %d with %d desired",
+				numChildren, numPartitions, minRange, numDesired)
 		}
 	}
 	if numPlaced == 0 || sr.NumberInRange(numPlaced) {
 		lNode.SetCount(numPlaced)
 	} else {
 		// placement failed size range
-		fmt.Printf("==> Failed placement at node %s: canPlace=%d; sizeRange=%v \n", pNode.GetID(), numPlaced, sr)
+		klog.V(4).Infof("==> Failed placement at node %s: canPlace=%d; sizeRange=%v", pNode.GetID(), numPlaced, sr)
+		p.addDiagnostic(pNode, numPlaced, "placed outside size range %v, rolled back", sr)
 		lNode.RemoveChildren()
 		p.numRemaining += numPlaced
 		lNode.SetCount(0)
@@ -165,8 +384,22 @@ func (p *Placer) placeAtNode(pNode *topology.PNode, numNodes int, numToPlace int
 }
 
 // PlacePartialGroup : place a group with some members already placed (claimed resources)
+//   - if not fully placed with its soft group affinities, the group is placed again without them
 func (p *Placer) PlacePartialGroup(pg *PGroup) (*topology.LTree, error) {
-	defer p.PlaceCleanup()
+	if pg == nil {
+		return nil, fmt.Errorf("PGroup is nil")
+	}
+	partialLTree := pg.GetLTree()
+	return p.placeRelaxing(pg, func(pg *PGroup) (*topology.LTree, error) {
+		// start each attempt from the partial placement
+		pg.SetLTree(partialLTree)
+		return p.placePartialGroup(pg)
+	})
+}
+
+// placePartialGroup : place a group with some members already placed, given the relaxation
+// of soft group affinities
+func (p *Placer) placePartialGroup(pg *PGroup) (*topology.LTree, error) {
 	pRoot, err := p.PlaceInit(pg)
 	if err != nil {
 		return nil, err
@@ -192,11 +425,12 @@ func (p *Placer) PlacePartialGroup(pg *PGroup) (*topology.LTree, error) {
 	// place recursively
 	p.numClaimedRemaining = pRoot.GetNumClaimed()
 	lRoot := p.placePartialGroupAtNode(pRoot, 1, p.numRemaining, 0)
+	pg.SetDiagnostics(p.diagnostics)
 	if lRoot == nil {
 		return nil, fmt.Errorf("lRoot is nil, failed placement")
 	}
 	if p.numClaimedRemaining > 0 {
-		fmt.Println("Not all claimed instances were re-placed.")
+		klog.V(4).Infoln("Not all claimed instances were re-placed.")
 	}
 	tree := topology.NewTree(&lRoot.Node)
 	lTree := topology.NewLTree(tree)
@@ -234,8 +468,8 @@ func (p *Placer) placePartialGroupAtNode(pNode *topology.PNode, numNodes int, nu
 		p.numClaimedRemaining -= numClaimedAndPlaced
 		lNode.SetClaimed(numClaimedAndPlaced)
 	} else {
-		// process children of pNode
-		children := pNode.GetChildren()
+		// process children of pNode, in order of ID among those ranking the same
+		children := pNode.GetSortedChildren()
 		numChildren := len(children)
 
 		// check number of partitions and range
@@ -282,16 +516,30 @@ func (p *Placer) placePartialGroupAtNode(pNode *topology.PNode, numNodes int, nu
 	return lNode
 }
 
-// sortNodes : sort a set of nodes based on constraint (assuming numFit already calculated)
+// sortNodes : sort a set of nodes based on constraint (assuming numFit already calculated);
+// nodes with the same number that can fit are ordered by the node order of the group,
+// where a node with a lower leftover score ranks as one with a higher number that can fit,
+// and nodes ranking the same keep their order
 func (p *Placer) sortNodes(nodes []*topology.Node, isPartialPlacement bool) {
-	sort.Slice(nodes, func(i, j int) bool {
+	scores := p.leftoverScores(nodes)
+	sort.SliceStable(nodes, func(i, j int) bool {
 		pNodei := (*topology.PNode)(unsafe.Pointer(nodes[i]))
 		pNodej := (*topology.PNode)(unsafe.Pointer(nodes[j]))
 		lc := p.pg.GetLevelConstraint(pNodei.GetLevel())
 		isIncreasing := lc.Affinity() == util.Spread
+		var c int
 		if isPartialPlacement {
-			return pNodei.CompareClaimed(pNodej, isIncreasing) < 0
+			c = pNodei.CompareClaimed(pNodej, isIncreasing)
+		} else {
+			c = pNodei.Compare(pNodej, isIncreasing)
+		}
+		if c == 0 && scores != nil {
+			scorei, scorej := scores[pNodei.GetID()], scores[pNodej.GetID()]
+			if isIncreasing {
+				return scorei > scorej
+			}
+			return scorei < scorej
 		}
-		return pNodei.Compare(pNodej, isIncreasing) < 0
+		return c < 0
 	})
 }
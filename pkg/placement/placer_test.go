@@ -0,0 +1,677 @@
+package placement
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/system"
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// tryPlace : place a group on a tree and check if fully placed
+func tryPlace(pTree *topology.PTree, pg *PGroup) bool {
+	_, err := NewPlacer(pTree).PlaceGroup(pg)
+	return err == nil && pg.IsFullyPlaced()
+}
+
+// checkPlacement : check the number of members of a group on each PE, failing the test if different
+func checkPlacement(t *testing.T, pg *PGroup, want map[string]int) {
+	got := placedOn(pg)
+	if len(got) != len(want) {
+		t.Errorf("group %s placed on %v, want %v", pg.GetID(), got, want)
+		return
+	}
+	for id, n := range want {
+		if got[id] != n {
+			t.Errorf("group %s placed on %v, want %v", pg.GetID(), got, want)
+			return
+		}
+	}
+}
+
+func TestPlacer_Eligibility(t *testing.T) {
+	tests := []struct {
+		name        string
+		tolerations []system.Toleration
+		wantPlaced  bool
+	}{
+		{name: "taint not tolerated", wantPlaced: false},
+		{name: "taint tolerated", tolerations: []system.Toleration{{Key: "gpu", Value: "a100"}}, wantPlaced: true},
+		{name: "any value tolerated", tolerations: []system.Toleration{{Key: "gpu", AnyValue: true}},
+			wantPlaced: true},
+		{name: "other value tolerated", tolerations: []system.Toleration{{Key: "gpu", Value: "h100"}},
+			wantPlaced: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			pes := pTree.GetPEs()
+			pes["pe-0"].SetState(system.Cordoned)
+			pes["pe-1"].SetState(system.Draining)
+			pes["pe-2"].AddTaint(system.Taint{Key: "gpu", Value: "a100"})
+
+			// members of 4 cpus need two PEs, never the cordoned or draining ones
+			pg := makePGroup("pg", 2, 4)
+			for _, tol := range tt.tolerations {
+				pg.AddToleration(tol)
+			}
+			placed := tryPlace(pTree, pg)
+			if placed != tt.wantPlaced {
+				t.Fatalf("PlaceGroup() fully placed %v, want %v", placed, tt.wantPlaced)
+			}
+			if placed {
+				checkPlacement(t, pg, map[string]int{"pe-2": 1, "pe-3": 1})
+			}
+		})
+	}
+}
+
+func TestPlacer_Selectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector *system.Selector
+		size     int
+		// nil if not fully placed
+		want map[string]int
+	}{
+		{name: "label of rack", selector: system.NewSelector("network", system.Equals, "ib"), size: 1,
+			want: map[string]int{"pe-2": 1}},
+		{name: "label of rack overridden by PE", selector: system.NewSelector("network", system.Equals, "ib"),
+			size: 2, want: nil},
+		{name: "in values", selector: system.NewSelector("network", system.In, "ib", "eth"), size: 2,
+			want: map[string]int{"pe-2": 1, "pe-3": 1}},
+		{name: "not in values", selector: system.NewSelector("network", system.NotIn, "ib"), size: 3,
+			want: map[string]int{"pe-0": 1, "pe-1": 1, "pe-3": 1}},
+		{name: "label exists", selector: system.NewSelector("gpu", system.Exists), size: 1,
+			want: map[string]int{"pe-0": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			pTree.GetNode("rack-1").Entity.SetLabel("network", "ib")
+			pTree.GetNode("pe-3").Entity.SetLabel("network", "eth")
+			pTree.GetNode("pe-0").Entity.SetLabel("gpu", "a100")
+
+			pg := makePGroup("pg", tt.size, 4)
+			pg.AddSelector(tt.selector)
+			placed := tryPlace(pTree, pg)
+			if placed != (tt.want != nil) {
+				t.Fatalf("PlaceGroup() fully placed %v, want %v", placed, tt.want != nil)
+			}
+			if placed {
+				checkPlacement(t, pg, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlacer_HomogeneousKey(t *testing.T) {
+	// ties are broken by the smaller value in rack-0, the label of rack-1 is inherited
+	mixed := map[string]string{"pe-0": "a100", "pe-1": "h100", "rack-1": "h100"}
+	tests := []struct {
+		name string
+		// values of the gen label on nodes
+		labels map[string]string
+		// level of the constraint
+		level int
+		size  int
+		// nil if not fully placed
+		want map[string]int
+	}{
+		{name: "same generation in each rack", labels: mixed, level: 1, size: 3,
+			want: map[string]int{"pe-0": 1, "pe-2": 1, "pe-3": 1}},
+		{name: "mixed generations in a rack", labels: mixed, level: 1, size: 4, want: nil},
+		{name: "same generation in the tree", labels: mixed, level: 2, size: 3,
+			want: map[string]int{"pe-1": 1, "pe-2": 1, "pe-3": 1}},
+		{name: "PEs without the label excluded", labels: map[string]string{"pe-3": "a100"}, level: 1, size: 1,
+			want: map[string]int{"pe-3": 1}},
+		{name: "too few PEs with the label", labels: map[string]string{"pe-3": "a100"}, level: 1, size: 2,
+			want: nil},
+		{name: "no PEs with the label", labels: map[string]string{}, level: 1, size: 1, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			for id, value := range tt.labels {
+				pTree.GetNode(id).Entity.SetLabel("gen", value)
+			}
+
+			pg := makePGroup("pg", tt.size, 4)
+			lc := NewLevelConstraint("lc", tt.level, util.Pack, false)
+			if lc.SetHomogeneousKey("") || !lc.SetHomogeneousKey("gen") {
+				t.Fatalf("SetHomogeneousKey() accepted an empty key or rejected gen")
+			}
+			pg.AddLevelConstraint(lc)
+			placed := tryPlace(pTree, pg)
+			if placed != (tt.want != nil) {
+				t.Fatalf("PlaceGroup() fully placed %v, want %v", placed, tt.want != nil)
+			}
+			if placed {
+				checkPlacement(t, pg, tt.want)
+			}
+		})
+	}
+}
+
+// rackOf : the rack of the (sorted) first PE where a group is placed
+func rackOf(pTree *topology.PTree, pg *PGroup) string {
+	return pTree.GetNode(firstPE(pg)).GetParent().GetID()
+}
+
+// placedUnder : number of members of a group placed under a node
+func placedUnder(pTree *topology.PTree, pg *PGroup, nodeID string) int {
+	n := 0
+	for id, count := range placedOn(pg) {
+		if pTree.GetNode(nodeID).HasLeaf(id) {
+			n += count
+		}
+	}
+	return n
+}
+
+func TestPlacer_AntiAffinity(t *testing.T) {
+	if NewAntiAffinity("other", 1, -1) != nil || NewAntiAffinity("other", -1, 0) != nil ||
+		NewAntiAffinity("", 1, 0) != nil {
+		t.Errorf("NewAntiAffinity() with bad parameters not nil")
+	}
+	tests := []struct {
+		name      string
+		claimed   bool
+		level     int
+		maxShared int
+		size      int
+		// members placed under the node at the level hosting the other group, -1 if not fully placed
+		wantShared int
+	}{
+		{name: "no members shared", claimed: true, level: 1, maxShared: 0, size: 4, wantShared: 0},
+		{name: "not enough room in other rack", claimed: true, level: 1, maxShared: 0, size: 5, wantShared: -1},
+		{name: "one member shared", claimed: true, level: 1, maxShared: 1, size: 5, wantShared: 1},
+		{name: "cap above the room in the rack", claimed: true, level: 1, maxShared: 9, size: 7, wantShared: 3},
+		{name: "other group not claimed", claimed: false, level: 1, maxShared: 0, size: 8, wantShared: 4},
+		{name: "other PE avoided", claimed: true, level: 0, maxShared: 0, size: 6, wantShared: 0},
+		{name: "other PE needed", claimed: true, level: 0, maxShared: 0, size: 7, wantShared: -1},
+		{name: "capped in the whole tree", claimed: true, level: 2, maxShared: 3, size: 3, wantShared: 3},
+		{name: "exceeding cap in the whole tree", claimed: true, level: 2, maxShared: 3, size: 4, wantShared: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			other := makePGroup("other", 1, 2)
+			place(t, pTree, other)
+			if tt.claimed {
+				if err := other.TryClaim(other.GetSize(), pTree); err != nil {
+					t.Fatalf("TryClaim() error = %v", err)
+				}
+			}
+			shared := pTree.GetNode(firstPE(other))
+			for i := 0; i < tt.level; i++ {
+				shared = shared.GetParent()
+			}
+
+			pg := makePGroup("pg", tt.size, 2)
+			pg.AddAntiAffinity(NewAntiAffinity("other", tt.level, tt.maxShared))
+			placed := tryPlace(pTree, pg)
+			if placed != (tt.wantShared >= 0) {
+				t.Fatalf("PlaceGroup() fully placed %v, want %v", placed, tt.wantShared >= 0)
+			}
+			if got := placedUnder(pTree, pg, shared.GetID()); placed && got != tt.wantShared {
+				t.Errorf("PlaceGroup() placed %d members in %s with group other, want %d", got, shared.GetID(),
+					tt.wantShared)
+			}
+		})
+	}
+}
+
+func TestPlacer_AntiAffinityMaxAllocated(t *testing.T) {
+	tests := []struct {
+		name string
+		// maximum cpus allocated in rack-0 hosting a member of the other group (-1 if unlimited)
+		maxAllocated int
+		maxShared    int
+		size         int
+		wantPlaced   bool
+	}{
+		{name: "capped PE in limited rack", maxAllocated: 5, maxShared: 0, size: 8, wantPlaced: true},
+		{name: "limited rack and free rack full", maxAllocated: 5, maxShared: 0, size: 9, wantPlaced: false},
+		{name: "cap above the limit of the rack", maxAllocated: 5, maxShared: 6, size: 8, wantPlaced: true},
+		{name: "rack full", maxAllocated: 1, maxShared: 0, size: 4, wantPlaced: true},
+		{name: "rack full and more members", maxAllocated: 1, maxShared: 0, size: 5, wantPlaced: false},
+		{name: "unlimited rack", maxAllocated: -1, maxShared: 0, size: 12, wantPlaced: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := makePNode("root", 0,
+				makePNode("rack-0", 0, makePNode("pe-0", 8), makePNode("pe-1", 8)),
+				makePNode("rack-1", 0, makePNode("pe-2", 4)))
+			pTree := topology.NewPTree(topology.NewTree((*topology.Node)(unsafe.Pointer(root))))
+			pTree.SetNodeLevels()
+			demand, _ := util.NewAllocationCopy([]int{1})
+			other := system.NewLEGroup("other", 1, demand)
+			le := system.NewLE("other-0", demand)
+			other.AddLE(le)
+			if err := pTree.GetPEs()["pe-0"].TryPlaceLE(le); err != nil {
+				t.Fatalf("TryPlaceLE() error = %v", err)
+			}
+			pTree.PercolateResources()
+			if tt.maxAllocated >= 0 {
+				maxAllocated, _ := util.NewAllocationCopy([]int{tt.maxAllocated})
+				(*topology.PNode)(unsafe.Pointer(pTree.GetNode("rack-0"))).SetMaxAllocated(maxAllocated)
+			}
+
+			// members of the group fit on pe-1 up to the limit of rack-0, and on pe-2
+			pg := makePGroup("pg", tt.size, 1)
+			pg.AddAntiAffinity(NewAntiAffinity("other", 0, tt.maxShared))
+			if placed := tryPlace(pTree, pg); placed != tt.wantPlaced {
+				t.Fatalf("PlaceGroup() fully placed %v, want %v", placed, tt.wantPlaced)
+			}
+			if got := placedOn(pg)["pe-0"]; tt.wantPlaced && got > tt.maxShared {
+				t.Errorf("PlaceGroup() placed %d members on pe-0 with group other, want at most %d", got, tt.maxShared)
+			}
+		})
+	}
+}
+
+func TestPlacer_GroupAffinity(t *testing.T) {
+	if NewGroupAffinity("other", -1, true) != nil || NewGroupAffinity("", 1, true) != nil {
+		t.Errorf("NewGroupAffinity() with bad parameters not nil")
+	}
+	tests := []struct {
+		name    string
+		otherID string
+		claimed bool
+		level   int
+		isHard  bool
+		size    int
+		// members placed under the node at the level hosting the other group, -1 if not fully placed,
+		// -2 if placed anywhere
+		wantUnder int
+	}{
+		{name: "hard affinity", otherID: "other", claimed: true, level: 1, isHard: true, size: 3, wantUnder: 3},
+		{name: "hard affinity exceeding rack", otherID: "other", claimed: true, level: 1, isHard: true, size: 4,
+			wantUnder: -1},
+		{name: "soft affinity", otherID: "other", claimed: true, level: 1, isHard: false, size: 3, wantUnder: 3},
+		{name: "soft affinity falling back", otherID: "other", claimed: true, level: 1, isHard: false, size: 4,
+			wantUnder: -2},
+		{name: "hard affinity to the PE", otherID: "other", claimed: true, level: 0, isHard: true, size: 1,
+			wantUnder: 1},
+		{name: "hard affinity exceeding PE", otherID: "other", claimed: true, level: 0, isHard: true, size: 2,
+			wantUnder: -1},
+		{name: "hard affinity to the tree", otherID: "other", claimed: true, level: 2, isHard: true, size: 7,
+			wantUnder: 7},
+		{name: "hard affinity to unclaimed group", otherID: "other", claimed: false, level: 1, isHard: true,
+			size: 1, wantUnder: -1},
+		{name: "hard affinity to missing group", otherID: "missing", claimed: true, level: 1, isHard: true,
+			size: 1, wantUnder: -1},
+		{name: "soft affinity to missing group", otherID: "missing", claimed: true, level: 1, isHard: false,
+			size: 1, wantUnder: -2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			other := makePGroup("other", 1, 2)
+			place(t, pTree, other)
+			if tt.claimed {
+				if err := other.TryClaim(other.GetSize(), pTree); err != nil {
+					t.Fatalf("TryClaim() error = %v", err)
+				}
+			}
+			affine := pTree.GetNode(firstPE(other))
+			for i := 0; i < tt.level; i++ {
+				affine = affine.GetParent()
+			}
+
+			pg := makePGroup("pg", tt.size, 2)
+			pg.AddGroupAffinity(NewGroupAffinity(tt.otherID, tt.level, tt.isHard))
+			placed := tryPlace(pTree, pg)
+			if placed != (tt.wantUnder != -1) {
+				t.Fatalf("PlaceGroup() fully placed %v, want %v", placed, tt.wantUnder != -1)
+			}
+			if got := placedUnder(pTree, pg, affine.GetID()); tt.wantUnder >= 0 && got != tt.wantUnder {
+				t.Errorf("PlaceGroup() placed %d members in %s with group other, want %d", got, affine.GetID(),
+					tt.wantUnder)
+			}
+		})
+	}
+}
+
+func TestPlacer_GroupAffinityPartial(t *testing.T) {
+	tests := []struct {
+		name       string
+		isHard     bool
+		wantPlaced bool
+	}{
+		{name: "hard affinity", isHard: true, wantPlaced: false},
+		{name: "soft affinity falling back", isHard: false, wantPlaced: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			other := makePGroup("other", 1, 2)
+			place(t, pTree, other)
+			if err := other.TryClaim(other.GetSize(), pTree); err != nil {
+				t.Fatalf("TryClaim() error = %v", err)
+			}
+
+			// two members claimed, the rack of the other group has room for three of the remaining four
+			pg := makePGroup("pg", 6, 2)
+			pg.AddGroupAffinity(NewGroupAffinity("other", 1, false))
+			place(t, pTree, pg)
+			if err := pg.TryClaim(2, pTree); err != nil {
+				t.Fatalf("TryClaim() error = %v", err)
+			}
+			if tt.isHard {
+				pg.affinities[0] = NewGroupAffinity("other", 1, true)
+			}
+			if _, err := NewPlacer(pTree).PlacePartialGroup(pg); err != nil {
+				t.Fatalf("PlacePartialGroup() error = %v", err)
+			}
+			if got := pg.IsFullyPlaced(); got != tt.wantPlaced {
+				t.Errorf("PlacePartialGroup() fully placed %v, want %v", got, tt.wantPlaced)
+			}
+			relaxed := false
+			for _, d := range pg.GetDiagnostics() {
+				relaxed = relaxed || strings.Contains(d.Reason, "soft group affinities not satisfied")
+			}
+			if relaxed != !tt.isHard {
+				t.Errorf("PlacePartialGroup() relaxed soft group affinities %v, want %v", relaxed, !tt.isHard)
+			}
+		})
+	}
+}
+
+func TestPlacer_Exclusive(t *testing.T) {
+	pTree := makePTree()
+	other := makePGroup("other", 1, 2)
+	place(t, pTree, other)
+	if err := other.TryClaim(other.GetSize(), pTree); err != nil {
+		t.Fatalf("TryClaim() error = %v", err)
+	}
+	otherRack := rackOf(pTree, other)
+
+	// exclusive racks host no members of other groups
+	newExclusive := func(size int) *PGroup {
+		pg := makePGroup("exclusive", size, 2)
+		lc := NewLevelConstraint("lc", 1, util.Pack, false)
+		lc.SetExclusive(true)
+		pg.AddLevelConstraint(lc)
+		return pg
+	}
+	if tryPlace(pTree, newExclusive(5)) {
+		t.Errorf("PlaceGroup() exclusive group placed in rack %s of group other", otherRack)
+	}
+	pg := newExclusive(2)
+	place(t, pTree, pg)
+	if got := placedUnder(pTree, pg, otherRack); got != 0 {
+		t.Errorf("PlaceGroup() exclusive group placed %d members in rack %s of group other", got, otherRack)
+	}
+	if err := pg.TryClaim(pg.GetSize(), pTree); err != nil {
+		t.Fatalf("TryClaim() error = %v", err)
+	}
+	rack := rackOf(pTree, pg)
+	if reservedBy := (*topology.PNode)(unsafe.Pointer(pTree.GetNode(rack))).GetReservedBy(); reservedBy != pg.GetID() {
+		t.Errorf("rack %s reserved by %q, want %s", rack, reservedBy, pg.GetID())
+	}
+
+	// the claimed exclusive rack is reserved, so other groups only fit in the other rack
+	if tryPlace(pTree, makePGroup("more", 4, 2)) {
+		t.Errorf("PlaceGroup() group placed in reserved rack %s", rack)
+	}
+	more := makePGroup("more", 3, 2)
+	place(t, pTree, more)
+	if got := placedUnder(pTree, more, otherRack); got != 3 {
+		t.Errorf("PlaceGroup() placed %d members in rack %s, want 3", got, otherRack)
+	}
+
+	// the rack is released when the exclusive group is unclaimed
+	pg.UnClaimAll(pTree)
+	if !tryPlace(pTree, makePGroup("more", 4, 2)) {
+		t.Errorf("PlaceGroup() group not placed after releasing rack %s", rack)
+	}
+}
+
+func TestPlacer_ExclusiveLevels(t *testing.T) {
+	tests := []struct {
+		name    string
+		claimed bool
+		level   int
+		size    int
+		// members placed under the node at the level hosting the other group, -1 if not fully placed,
+		// -2 if placed anywhere
+		wantShared int
+	}{
+		{name: "exclusive PEs", claimed: true, level: 0, size: 6, wantShared: 0},
+		{name: "exclusive PEs needing the other PE", claimed: true, level: 0, size: 7, wantShared: -1},
+		{name: "exclusive tree hosting other group", claimed: true, level: 2, size: 1, wantShared: -1},
+		{name: "other group not claimed", claimed: false, level: 1, size: 7, wantShared: -2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			other := makePGroup("other", 1, 2)
+			place(t, pTree, other)
+			if tt.claimed {
+				if err := other.TryClaim(other.GetSize(), pTree); err != nil {
+					t.Fatalf("TryClaim() error = %v", err)
+				}
+			}
+			shared := pTree.GetNode(firstPE(other))
+			for i := 0; i < tt.level; i++ {
+				shared = shared.GetParent()
+			}
+
+			pg := makePGroup("exclusive", tt.size, 2)
+			lc := NewLevelConstraint("lc", tt.level, util.Pack, false)
+			lc.SetExclusive(true)
+			pg.AddLevelConstraint(lc)
+			placed := tryPlace(pTree, pg)
+			if placed != (tt.wantShared != -1) {
+				t.Fatalf("PlaceGroup() fully placed %v, want %v", placed, tt.wantShared != -1)
+			}
+			if !placed {
+				return
+			}
+			if got := placedUnder(pTree, pg, shared.GetID()); tt.wantShared >= 0 && got != tt.wantShared {
+				t.Errorf("PlaceGroup() placed %d members in %s with group other, want %d", got, shared.GetID(),
+					tt.wantShared)
+			}
+
+			// the nodes at the level hosting the claimed group are reserved for it
+			if err := pg.TryClaim(pg.GetSize(), pTree); err != nil {
+				t.Fatalf("TryClaim() error = %v", err)
+			}
+			for id := range placedOn(pg) {
+				node := pTree.GetNode(id)
+				for i := 0; i < tt.level; i++ {
+					node = node.GetParent()
+				}
+				if reservedBy := (*topology.PNode)(unsafe.Pointer(node)).GetReservedBy(); reservedBy != pg.GetID() {
+					t.Errorf("node %s reserved by %q, want %s", node.GetID(), reservedBy, pg.GetID())
+				}
+			}
+		})
+	}
+}
+
+func TestPlacer_Devices(t *testing.T) {
+	tests := []struct {
+		name string
+		// level constraint at the NVSwitch level (nil if none)
+		lc   *LevelConstraint
+		size int
+		// number of NVSwitches hosting members, -1 if not fully placed
+		wantSwitches int
+	}{
+		{name: "packed on an NVSwitch", lc: NewLevelConstraint("lc", 1, util.Pack, true), size: 2,
+			wantSwitches: 1},
+		{name: "packed exceeding an NVSwitch", lc: NewLevelConstraint("lc", 1, util.Pack, true), size: 3,
+			wantSwitches: -1},
+		{name: "spread on NVSwitches", lc: NewLevelConstraint("lc", 1, util.Spread, true), size: 3,
+			wantSwitches: 3},
+		{name: "spread exceeding NVSwitches", lc: NewLevelConstraint("lc", 1, util.Spread, true), size: 4,
+			wantSwitches: -1},
+		{name: "all devices", lc: nil, size: 5, wantSwitches: 3},
+		{name: "more than all devices", lc: nil, size: 6, wantSwitches: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// node-0 has two NVSwitches of two GPUs, node-1 no devices (padded)
+			gpu := func(id string) *system.Device {
+				capacity, _ := util.NewAllocationCopy([]int{1})
+				return system.NewLeafDevice(system.NewPE(system.DeviceID("node-0", id), capacity))
+			}
+			root := makePNode("root", 0, makePNode("node-0", 0), makePNode("node-1", 1))
+			pTree := topology.NewPTree(topology.NewTree((*topology.Node)(unsafe.Pointer(root))))
+			pTree.GetPEs()["node-0"].SetDevices([]*system.Device{
+				system.NewDevice("node-0/nvswitch-0", gpu("gpu-0"), gpu("gpu-1")),
+				system.NewDevice("node-0/nvswitch-1", gpu("gpu-2"), gpu("gpu-3"))})
+			pTree.SetLevelNames([]string{"node"})
+			pTree.SetNodeLevels()
+			pTree.PercolateResources()
+			expanded, err := pTree.ExpandDevices([]string{"nvswitch", "gpu"})
+			if err != nil {
+				t.Fatalf("ExpandDevices() error = %v", err)
+			}
+
+			pg := makePGroup("pg", tt.size, 1)
+			if tt.lc != nil {
+				pg.AddLevelConstraint(tt.lc)
+			}
+			placed := tryPlace(expanded, pg)
+			if placed != (tt.wantSwitches >= 0) {
+				t.Fatalf("PlaceGroup() fully placed %v, want %v", placed, tt.wantSwitches >= 0)
+			}
+			if !placed {
+				return
+			}
+			switches := make(map[string]bool)
+			for peID := range placedOn(pg) {
+				node := expanded.GetNode(peID)
+				if node == nil || (*topology.PNode)(unsafe.Pointer(node)).GetLevel() != 0 {
+					t.Errorf("member placed on %s, want a leaf of the expanded tree", peID)
+					continue
+				}
+				switches[node.GetParent().GetID()] = true
+			}
+			if len(switches) != tt.wantSwitches {
+				t.Errorf("members placed under NVSwitches %v, want %d", switches, tt.wantSwitches)
+			}
+		})
+	}
+}
+
+func TestPlacer_MaxLEs(t *testing.T) {
+	all := map[string]int{"pe-0": 4, "pe-1": 4, "pe-2": 4, "pe-3": 4}
+	tests := []struct {
+		name string
+		// maximum number of LEs on each PE (zero if unlimited)
+		maxLEs int
+		// number of LEs of another group hosted on pe-0
+		hosted int
+		size   int
+		// nil if not fully placed
+		want map[string]int
+	}{
+		{name: "one member on each PE", maxLEs: 1, size: 4, want: map[string]int{"pe-0": 1, "pe-1": 1, "pe-2": 1,
+			"pe-3": 1}},
+		{name: "more members than PEs", maxLEs: 1, size: 5, want: nil},
+		{name: "PE full of other members", maxLEs: 1, hosted: 1, size: 3, want: map[string]int{"pe-1": 1, "pe-2": 1,
+			"pe-3": 1}},
+		{name: "PE full of other members and more members", maxLEs: 1, hosted: 1, size: 4, want: nil},
+		{name: "cap above capacity", maxLEs: 8, size: 16, want: all},
+		{name: "unlimited", maxLEs: 0, size: 16, want: all},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			pes := pTree.GetPEs()
+			for _, pe := range pes {
+				pe.SetMaxLEs(tt.maxLEs)
+			}
+			demand, _ := util.NewAllocationCopy([]int{1})
+			for i := 0; i < tt.hosted; i++ {
+				if err := pes["pe-0"].TryPlaceLE(system.NewLE(fmt.Sprintf("other-%d", i), demand)); err != nil {
+					t.Fatalf("TryPlaceLE() error = %v", err)
+				}
+			}
+			pTree.PercolateResources()
+
+			pg := makePGroup("pg", tt.size, 1)
+			placed := tryPlace(pTree, pg)
+			if placed != (tt.want != nil) {
+				t.Fatalf("PlaceGroup() fully placed %v, want %v", placed, tt.want != nil)
+			}
+			if placed {
+				checkPlacement(t, pg, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlacer_NodeOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		order   NodeOrder
+		weights []float64
+		// capacity of pe-0, first in ID order, with gpus; pe-1 has 8 cpus and no gpus
+		cpu    int
+		gpu    int
+		size   int
+		wantOn map[string]int
+	}{
+		{name: "number that can fit only", order: NumFitOrder, cpu: 8, gpu: 4, size: 1,
+			wantOn: map[string]int{"pe-0": 1}},
+		{name: "dominant share", order: DominantShareOrder, cpu: 8, gpu: 4, size: 1,
+			wantOn: map[string]int{"pe-1": 1}},
+		{name: "weighted leftover with equal weights", order: WeightedLeftoverOrder, cpu: 8, gpu: 4, size: 1,
+			wantOn: map[string]int{"pe-1": 1}},
+		{name: "weighted leftover of gpus", order: WeightedLeftoverOrder, weights: []float64{0, 1},
+			cpu: 8, gpu: 4, size: 1, wantOn: map[string]int{"pe-1": 1}},
+		{name: "weighted leftover of cpus only", order: WeightedLeftoverOrder, weights: []float64{1, 0},
+			cpu: 8, gpu: 4, size: 1, wantOn: map[string]int{"pe-0": 1}},
+		{name: "zero weights", order: WeightedLeftoverOrder, weights: []float64{0, 0}, cpu: 8, gpu: 4, size: 1,
+			wantOn: map[string]int{"pe-0": 1}},
+		{name: "number that can fit ranks first", order: DominantShareOrder, cpu: 16, gpu: 4, size: 1,
+			wantOn: map[string]int{"pe-0": 1}},
+		{name: "all members fitting on both", order: DominantShareOrder, cpu: 8, gpu: 4, size: 4,
+			wantOn: map[string]int{"pe-1": 4}},
+		{name: "members exceeding one node", order: DominantShareOrder, cpu: 8, gpu: 1, size: 6,
+			wantOn: map[string]int{"pe-1": 4, "pe-0": 2}},
+		{name: "no gpus in the tree", order: DominantShareOrder, cpu: 8, gpu: 0, size: 1,
+			wantOn: map[string]int{"pe-0": 1}},
+		{name: "no gpus in the tree with weights", order: WeightedLeftoverOrder, weights: []float64{1, 1},
+			cpu: 8, gpu: 0, size: 1, wantOn: map[string]int{"pe-0": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			makePE := func(id string, cpu int, gpu int) *topology.PNode {
+				capacity, _ := util.NewAllocationCopy([]int{cpu, gpu})
+				entity := (*system.Entity)(unsafe.Pointer(system.NewPE(id, capacity)))
+				return topology.NewPNode(topology.NewNode(entity), 0, 2)
+			}
+			root := topology.NewPNode(topology.NewNode(&system.Entity{ID: "root"}), 0, 2)
+			root.AddChild((*topology.Node)(unsafe.Pointer(makePE("pe-0", tt.cpu, tt.gpu))))
+			root.AddChild((*topology.Node)(unsafe.Pointer(makePE("pe-1", 8, 0))))
+			pTree := topology.NewPTree(topology.NewTree((*topology.Node)(unsafe.Pointer(root))))
+			pTree.SetNodeLevels()
+			pTree.PercolateResources()
+
+			demand, _ := util.NewAllocationCopy([]int{2, 0})
+			pg := NewPGroup("pg", tt.size, demand)
+			if !pg.SetNodeOrder(tt.order, tt.weights) {
+				t.Fatalf("SetNodeOrder() = false")
+			}
+			place(t, pTree, pg)
+			checkPlacement(t, pg, tt.wantOn)
+		})
+	}
+
+	demand, _ := util.NewAllocationCopy([]int{2, 0})
+	pg := NewPGroup("pg", 1, demand)
+	if pg.SetNodeOrder(WeightedLeftoverOrder, []float64{1}) ||
+		pg.SetNodeOrder(WeightedLeftoverOrder, []float64{1, -1}) {
+		t.Errorf("SetNodeOrder() with bad weights = true, want false")
+	}
+}
@@ -0,0 +1,68 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/topology"
+)
+
+var (
+	// DefaultBarWidth : default number of characters in a utilization bar
+	DefaultBarWidth int = 10
+)
+
+// PTreeToASCII : an indented text tree of a physical tree
+//   - each node has a bar of its utilization, with the given width (default if not positive)
+//   - nodes of the given logical trees are marked with the count of LEs placed in the subtree
+//
+// e.g.
+//
+//	root [root] [###-------]  25%
+//	├── rack-0 [rack] [#####-----]  50% <- 2
+//	│   ├── server-0 [server] [##########] 100% <- 2
+//	│   └── server-1 [server] [----------]   0%
+//	└── rack-1 [rack] [----------]   0%
+func PTreeToASCII(pTree *topology.PTree, width int, lTrees ...*topology.LTree) string {
+	var b bytes.Buffer
+	if pTree == nil || pTree.GetRoot() == nil {
+		return ""
+	}
+	if width <= 0 {
+		width = DefaultBarWidth
+	}
+	writeASCIINode(&b, pTree, pTree.GetRoot(), overlay(lTrees), width, "", "")
+	return b.String()
+}
+
+// writeASCIINode : write the line of a node, and recursively of its subtree
+//   - prefix: the prefix of the line of the node
+//   - childPrefix: the prefix of the lines of its subtree
+func writeASCIINode(b *bytes.Buffer, pTree *topology.PTree, node *topology.Node, counts map[string]int,
+	width int, prefix string, childPrefix string) {
+	pNode := (*topology.PNode)(unsafe.Pointer(node))
+	fmt.Fprintf(b, "%s%s [%s]", prefix, node.GetID(), pTree.GetLevelName(pNode.GetLevel()))
+	if !pNode.GetCapacity().IsZero() {
+		u := Utilization(pNode)
+		filled := int(u*float64(width) + 0.5)
+		if filled > width {
+			filled = width
+		}
+		fmt.Fprintf(b, " [%s%s] %3.0f%%", strings.Repeat("#", filled), strings.Repeat("-", width-filled), 100*u)
+	}
+	if count := counts[node.GetID()]; count > 0 {
+		fmt.Fprintf(b, " <- %d", count)
+	}
+	b.WriteString("\n")
+
+	children := node.GetSortedChildren()
+	for i, child := range children {
+		if i == len(children)-1 {
+			writeASCIINode(b, pTree, child, counts, width, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			writeASCIINode(b, pTree, child, counts, width, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/topology"
+)
+
+var (
+	// DotOverlayColor : color of the outline of nodes and edges where groups are placed
+	DotOverlayColor string = "blue"
+	// DotNoCapacityColor : fill color of nodes with no capacity
+	DotNoCapacityColor string = "#eeeeee"
+)
+
+// PTreeToDot : a DOT graph of a physical tree, e.g. "dot -Tsvg ptree.dot > ptree.svg"
+//   - nodes are labeled by ID, level name, and utilization
+//   - nodes are filled from green (idle) through yellow to red (fully utilized)
+//   - nodes and edges of the given logical trees are outlined, with the count of LEs placed in the subtree
+func PTreeToDot(pTree *topology.PTree, lTrees ...*topology.LTree) string {
+	var b bytes.Buffer
+	b.WriteString("digraph ptree {\n")
+	b.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [arrowhead=none];\n")
+	if pTree != nil && pTree.GetRoot() != nil {
+		writeDotNode(&b, pTree, pTree.GetRoot(), overlay(lTrees))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// writeDotNode : write the statements of a node, and recursively of its subtree
+func writeDotNode(b *bytes.Buffer, pTree *topology.PTree, node *topology.Node, counts map[string]int) {
+	pNode := (*topology.PNode)(unsafe.Pointer(node))
+	id := strconv.Quote(node.GetID())
+	label := fmt.Sprintf("%s\n%s", node.GetID(), pTree.GetLevelName(pNode.GetLevel()))
+	fillColor := DotNoCapacityColor
+	if !pNode.GetCapacity().IsZero() {
+		u := Utilization(pNode)
+		label += fmt.Sprintf("\n%.0f%%", 100*u)
+		fillColor = UtilizationColor(u)
+	}
+	attrs := ""
+	if count := counts[node.GetID()]; count > 0 {
+		label += fmt.Sprintf("\nLEs=%d", count)
+		attrs = fmt.Sprintf(", color=%q, penwidth=3", DotOverlayColor)
+	}
+	fmt.Fprintf(b, "  %s [label=%s, fillcolor=%q%s];\n", id, strconv.Quote(label), fillColor, attrs)
+
+	for _, child := range node.GetSortedChildren() {
+		edgeAttrs := ""
+		if counts[child.GetID()] > 0 {
+			edgeAttrs = fmt.Sprintf(" [color=%q, penwidth=3]", DotOverlayColor)
+		}
+		fmt.Fprintf(b, "  %s -> %s%s;\n", id, strconv.Quote(child.GetID()), edgeAttrs)
+	}
+	for _, child := range node.GetSortedChildren() {
+		writeDotNode(b, pTree, child, counts)
+	}
+}
+
+// UtilizationColor : a pastel color (hex RGB) from green (0) through yellow (0.5) to red (1)
+func UtilizationColor(u float64) string {
+	if u < 0 {
+		u = 0
+	} else if u > 1 {
+		u = 1
+	}
+	red := 255.0
+	green := 255.0
+	if u < 0.5 {
+		red = 2 * u * 255
+	} else {
+		green = 2 * (1 - u) * 255
+	}
+	// blend with white
+	return fmt.Sprintf("#%02x%02x%02x", int((red+255)/2), int((green+255)/2), int(255/2))
+}
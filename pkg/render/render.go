@@ -0,0 +1,47 @@
+package render
+
+import (
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/topology"
+)
+
+// Renderers of physical trees, with resource utilization of nodes,
+// and logical trees of placed groups overlaid on the physical tree
+//   - DOT: to be laid out by Graphviz (e.g. dot -Tsvg)
+//   - ASCII: an indented tree with a utilization bar per node
+
+// Utilization : the dominant utilization of a node, i.e. the maximum
+// over resources of allocated divided by capacity (zero if no capacity)
+func Utilization(pNode *topology.PNode) float64 {
+	if pNode == nil {
+		return 0
+	}
+	capacity := pNode.GetCapacity().GetValue()
+	allocated := pNode.GetAllocated().GetValue()
+	u := 0.0
+	for i := 0; i < len(capacity) && i < len(allocated); i++ {
+		if capacity[i] > 0 {
+			if r := float64(allocated[i]) / float64(capacity[i]); r > u {
+				u = r
+			}
+		}
+	}
+	return u
+}
+
+// overlay : counts of LEs in the subtree of physical nodes, keyed by node ID,
+// summed over the given logical trees
+func overlay(lTrees []*topology.LTree) map[string]int {
+	counts := make(map[string]int)
+	for _, lTree := range lTrees {
+		if lTree == nil {
+			continue
+		}
+		for _, node := range lTree.GetNodeListBFS() {
+			lNode := (*topology.LNode)(unsafe.Pointer(node))
+			counts[node.GetID()] += lNode.GetCount()
+		}
+	}
+	return counts
+}
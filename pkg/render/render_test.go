@@ -0,0 +1,101 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ibm/chic-sched/pkg/builder"
+	"github.com/ibm/chic-sched/pkg/placement"
+)
+
+const treeJson = `{
+  "kind": "TopologyTree",
+  "metadata": {"name": "small"},
+  "spec": {
+    "resource-names": ["cpu"],
+    "level-names": ["rack", "server"],
+    "tree": {"level": {
+      "rack-0": {"level": {
+        "server-0": {"capacity": {"cpu": 4}, "allocated": {"cpu": 4}},
+        "server-1": {"capacity": {"cpu": 4}}
+      }},
+      "rack-1": {"level": {
+        "server-2": {"capacity": {"cpu": 4}, "allocated": {"cpu": 2}}
+      }}
+    }}
+  }
+}`
+
+const groupJson = `{
+  "kind": "PlacementGroup",
+  "metadata": {"name": "pg"},
+  "spec": {"size": 2, "demand": {"cpu": 2}, "level-constraints": [
+    {"id": "lc", "level-name": "rack", "affinity": "Pack", "hard": true}
+  ]}
+}`
+
+func TestPTreeToASCII(t *testing.T) {
+	pTree, err := builder.CreateTopologyTreeFromJson(treeJson)
+	if err != nil {
+		t.Fatalf("CreateTopologyTreeFromJson() error = %v", err)
+	}
+	want := "root [root] [#####-----]  50%\n" +
+		"├── rack-0 [rack] [#####-----]  50%\n" +
+		"│   ├── server-0 [server] [##########] 100%\n" +
+		"│   └── server-1 [server] [----------]   0%\n" +
+		"└── rack-1 [rack] [#####-----]  50%\n" +
+		"    └── server-2 [server] [#####-----]  50%\n"
+	if got := PTreeToASCII(pTree, 0); got != want {
+		t.Errorf("PTreeToASCII() =\n%v, want\n%v", got, want)
+	}
+
+	// overlay of a placed group
+	pg, err := builder.CreatePGroupFromJson(groupJson, pTree.GetResourceNames(), pTree.GetLevelNames())
+	if err != nil {
+		t.Fatalf("CreatePGroupFromJson() error = %v", err)
+	}
+	if _, err := placement.NewPlacer(pTree).PlaceGroup(pg); err != nil {
+		t.Fatalf("PlaceGroup() error = %v", err)
+	}
+	got := PTreeToASCII(pTree, 4, pg.GetLTree())
+	for _, line := range []string{"root [root] [##--]  50% <- 2\n", "server-1 [server] [----]   0% <- 2\n"} {
+		if !strings.Contains(got, line) {
+			t.Errorf("PTreeToASCII() =\n%v, want line %q", got, line)
+		}
+	}
+}
+
+func TestPTreeToDot(t *testing.T) {
+	pTree, err := builder.CreateTopologyTreeFromJson(treeJson)
+	if err != nil {
+		t.Fatalf("CreateTopologyTreeFromJson() error = %v", err)
+	}
+	got := PTreeToDot(pTree)
+	for _, s := range []string{
+		"digraph ptree {\n",
+		"  \"server-0\" [label=\"server-0\\nserver\\n100%\", fillcolor=\"#ff7f7f\"];\n",
+		"  \"server-1\" [label=\"server-1\\nserver\\n0%\", fillcolor=\"#7fff7f\"];\n",
+		"  \"rack-0\" -> \"server-0\";\n",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("PTreeToDot() =\n%v, want %q", got, s)
+		}
+	}
+}
+
+func TestUtilizationColor(t *testing.T) {
+	tests := []struct {
+		u    float64
+		want string
+	}{
+		{u: 0, want: "#7fff7f"},
+		{u: 0.5, want: "#ffff7f"},
+		{u: 1, want: "#ff7f7f"},
+		{u: 2, want: "#ff7f7f"},
+	}
+	for _, tt := range tests {
+		if got := UtilizationColor(tt.u); got != tt.want {
+			t.Errorf("UtilizationColor(%v) = %v, want %v", tt.u, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/builder"
+	"github.com/ibm/chic-sched/pkg/placement"
+	"github.com/ibm/chic-sched/pkg/render"
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// A self-contained HTML report (no external assets) of placement groups on a physical tree:
+//   - the hierarchy of the physical tree with the utilization of its nodes
+//   - utilization statistics of the nodes at each level
+//   - for each group: its logical tree, bindings, satisfaction of its level constraints,
+//     and the placement diagnostics
+
+// Report : data of the report
+type Report struct {
+	// title of the report
+	Title string
+	// time of generation
+	Time string
+	// resource names
+	ResourceNames []string
+	// physical tree
+	Root *PNodeView
+	// levels from the root down
+	Levels []*LevelView
+	// placement groups
+	Groups []*GroupView
+}
+
+// PNodeView : a physical node in the report
+type PNodeView struct {
+	// ID of the node
+	ID string
+	// name of the level of the node
+	Level string
+	// dominant utilization (percent)
+	Utilization float64
+	// color of the utilization
+	Color template.CSS
+	// allocated and capacity of resources
+	Resources string
+	// number of LEs of the groups placed in the subtree
+	Count int
+	// children ordered by ID
+	Children []*PNodeView
+}
+
+// LevelView : utilization statistics (percent) of the nodes at a level
+type LevelView struct {
+	// name of the level
+	Name string
+	// number of nodes at the level
+	NumNodes int
+	// minimum, average, and maximum utilization
+	Min, Avg, Max float64
+}
+
+// GroupView : a placement group in the report
+type GroupView struct {
+	// extends placement spec
+	*util.JPlacement
+	// satisfaction of level constraints, from the top level down
+	Constraints []*ConstraintView
+	// diagnostics of the placement
+	Diagnostics []*placement.Diagnostic
+	// pairs of member and host IDs ordered by member
+	Bindings [][2]string
+}
+
+// ConstraintView : satisfaction of a level constraint in the report
+type ConstraintView struct {
+	// extends constraint status
+	*placement.ConstraintStatus
+	// name of the level of the constraint
+	Level string
+	// hardness and affinity
+	Affinity string
+	// sizes of partitions
+	Partitions string
+}
+
+// NewReport : create a report of placement groups on a physical tree
+//   - returns nil if bad parameters
+func NewReport(title string, pTree *topology.PTree, pgs []*placement.PGroup) *Report {
+	if pTree == nil || pTree.GetRoot() == nil {
+		return nil
+	}
+	r := &Report{
+		Title:         title,
+		Time:          time.Now().Format(time.RFC1123),
+		ResourceNames: pTree.GetResourceNames(),
+		Groups:        make([]*GroupView, 0, len(pgs)),
+	}
+
+	// overlay counts of all groups
+	counts := make(map[string]int)
+	for _, pg := range pgs {
+		if pg == nil || pg.GetLTree() == nil {
+			continue
+		}
+		for _, node := range pg.GetLTree().GetNodeListBFS() {
+			counts[node.GetID()] += (*topology.LNode)(unsafe.Pointer(node)).GetCount()
+		}
+	}
+	levels := make(map[int]*LevelView)
+	r.Root = newPNodeView(pTree, pTree.GetRoot(), counts, levels)
+	for level := pTree.GetHeight(); level >= 0; level-- {
+		if lv := levels[level]; lv != nil {
+			lv.Avg /= float64(lv.NumNodes)
+			r.Levels = append(r.Levels, lv)
+		}
+	}
+
+	for _, pg := range pgs {
+		if pg != nil {
+			r.Groups = append(r.Groups, newGroupView(pTree, pg))
+		}
+	}
+	return r
+}
+
+// newPNodeView : create a view of the physical subtree rooted at a node,
+// accumulating utilization statistics by level
+func newPNodeView(pTree *topology.PTree, node *topology.Node, counts map[string]int,
+	levels map[int]*LevelView) *PNodeView {
+	pNode := (*topology.PNode)(unsafe.Pointer(node))
+	u := 100 * render.Utilization(pNode)
+	v := &PNodeView{
+		ID:          node.GetID(),
+		Level:       pTree.GetLevelName(pNode.GetLevel()),
+		Utilization: u,
+		Color:       template.CSS(render.UtilizationColor(u / 100)),
+		Count:       counts[node.GetID()],
+	}
+	resources := make([]string, 0)
+	capacity := pNode.GetCapacity().GetValue()
+	allocated := pNode.GetAllocated().GetValue()
+	for i, name := range pTree.GetResourceNames() {
+		if i < len(capacity) && i < len(allocated) {
+			resources = append(resources, fmt.Sprintf("%s %d/%d", name, allocated[i], capacity[i]))
+		}
+	}
+	v.Resources = strings.Join(resources, ", ")
+
+	lv := levels[pNode.GetLevel()]
+	if lv == nil {
+		lv = &LevelView{Name: v.Level, Min: u, Max: u}
+		levels[pNode.GetLevel()] = lv
+	}
+	lv.NumNodes++
+	lv.Avg += u
+	lv.Min = math.Min(lv.Min, u)
+	lv.Max = math.Max(lv.Max, u)
+
+	children := node.GetChildren()
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].GetID() < children[j].GetID()
+	})
+	for _, child := range children {
+		v.Children = append(v.Children, newPNodeView(pTree, child, counts, levels))
+	}
+	return v
+}
+
+// newGroupView : create a view of a placement group
+func newGroupView(pTree *topology.PTree, pg *placement.PGroup) *GroupView {
+	g := &GroupView{
+		JPlacement:  builder.PlacementToSpec(pg),
+		Diagnostics: pg.GetDiagnostics(),
+	}
+	for _, status := range pg.CheckLevelConstraints() {
+		partitions := make([]string, len(status.Partitions))
+		for i, n := range status.Partitions {
+			partitions[i] = fmt.Sprint(n)
+		}
+		hardness := "soft"
+		if status.LC.IsHard() {
+			hardness = "hard"
+		}
+		g.Constraints = append(g.Constraints, &ConstraintView{
+			ConstraintStatus: status,
+			Level:            pTree.GetLevelName(status.LC.GetLevel()),
+			Affinity:         hardness + " " + util.AffinityToString(status.LC.Affinity()),
+			Partitions:       strings.Join(partitions, ", "),
+		})
+	}
+	ids := make([]string, 0, len(g.JPlacement.Bindings))
+	for id := range g.JPlacement.Bindings {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		g.Bindings = append(g.Bindings, [2]string{id, g.JPlacement.Bindings[id]})
+	}
+	return g
+}
+
+// Write : write the report as HTML
+func (r *Report) Write(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
+
+// WriteHTML : write an HTML report of placement groups on a physical tree
+func WriteHTML(w io.Writer, title string, pTree *topology.PTree, pgs ...*placement.PGroup) error {
+	r := NewReport(title, pTree, pgs)
+	if r == nil {
+		return fmt.Errorf("empty physical tree")
+	}
+	return r.Write(w)
+}
@@ -0,0 +1,65 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ibm/chic-sched/pkg/builder"
+	"github.com/ibm/chic-sched/pkg/placement"
+)
+
+func TestWriteHTML(t *testing.T) {
+	treeData, err := os.ReadFile("../../samples/testTree.json")
+	if err != nil {
+		t.Fatalf("error reading tree: %v", err)
+	}
+	groupData, err := os.ReadFile("../../samples/testGroup.json")
+	if err != nil {
+		t.Fatalf("error reading group: %v", err)
+	}
+	pTree, err := builder.CreateTopologyTreeFromJson(string(treeData))
+	if err != nil {
+		t.Fatalf("CreateTopologyTreeFromJson() error = %v", err)
+	}
+
+	// first group placed, second group too large to fit
+	pgs := make([]*placement.PGroup, 2)
+	for i := range pgs {
+		if pgs[i], err = builder.CreatePGroupFromJson(string(groupData), pTree.GetResourceNames(),
+			pTree.GetLevelNames()); err != nil {
+			t.Fatalf("CreatePGroupFromJson() error = %v", err)
+		}
+	}
+	placer := placement.NewPlacer(pTree)
+	if _, err := placer.PlaceGroup(pgs[0]); err != nil || !pgs[0].IsFullyPlaced() {
+		t.Fatalf("PlaceGroup() error = %v", err)
+	}
+	pgs[0].ClaimAll(pTree)
+	big := strings.Replace(string(groupData), `"size": 4`, `"size": 40`, 1)
+	if pgs[1], err = builder.CreatePGroupFromJson(big, pTree.GetResourceNames(), pTree.GetLevelNames()); err != nil {
+		t.Fatalf("CreatePGroupFromJson() error = %v", err)
+	}
+	placer.PlaceGroup(pgs[1])
+	if len(pgs[1].GetDiagnostics()) == 0 {
+		t.Errorf("GetDiagnostics() of group too large is empty")
+	}
+
+	var b bytes.Buffer
+	if err := WriteHTML(&b, "test report", pTree, pgs...); err != nil {
+		t.Fatalf("WriteHTML() error = %v", err)
+	}
+	html := b.String()
+	for _, s := range []string{"<title>test report</title>", "Placed 4 of 4", "of 40",
+		"<td>rack</td>", "fit in the tree", "pg0-vm0"} {
+		if !strings.Contains(html, s) {
+			t.Errorf("WriteHTML() missing %q", s)
+		}
+	}
+	for _, s := range []string{"<script src", "<link", "http://", "https://"} {
+		if strings.Contains(html, s) {
+			t.Errorf("WriteHTML() references external asset %q", s)
+		}
+	}
+}
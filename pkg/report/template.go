@@ -0,0 +1,73 @@
+package report
+
+import "html/template"
+
+// reportTemplate : the HTML template of the report, with inline style
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.6em; } h2 { font-size: 1.3em; margin-top: 2em; } h3 { font-size: 1.1em; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.6em; text-align: left; }
+th { background: #f4f4f4; }
+ul.tree { list-style: none; padding-left: 1.2em; margin: 0; border-left: 1px dotted #bbb; }
+ul.tree.top { border-left: none; padding-left: 0; }
+.node { font-family: monospace; white-space: nowrap; }
+.level { color: #888; }
+.bar { display: inline-block; width: 8em; height: 0.8em; border: 1px solid #999; vertical-align: middle; }
+.bar span { display: block; height: 100%; }
+.count { color: #00c; font-weight: bold; }
+.ok { color: #080; } .fail { color: #c00; }
+summary { cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated {{.Time}}. Resources: {{range $i, $name := .ResourceNames}}{{if $i}}, {{end}}{{$name}}{{end}}.</p>
+
+<h2>Utilization by level</h2>
+<table>
+<tr><th>level</th><th>nodes</th><th>min</th><th>avg</th><th>max</th></tr>
+{{range .Levels}}<tr><td>{{.Name}}</td><td>{{.NumNodes}}</td><td>{{printf "%.0f%%" .Min}}</td><td>{{printf "%.0f%%" .Avg}}</td><td>{{printf "%.0f%%" .Max}}</td></tr>
+{{end}}</table>
+
+<h2>Hierarchy</h2>
+<ul class="tree top">{{template "pnode" .Root}}</ul>
+
+{{range .Groups}}
+<h2>Group {{.Group}}</h2>
+<p>Placed {{.Placed}} of {{.Size}}: {{if .FullyPlaced}}<span class="ok">fully placed</span>{{else}}<span class="fail">not fully placed</span>{{end}}</p>
+{{if .LTree}}<h3>Logical tree</h3>
+<ul class="tree top">{{template "lnode" .LTree}}</ul>{{end}}
+{{if .Constraints}}<h3>Level constraints</h3>
+<table>
+<tr><th>constraint</th><th>level</th><th>affinity</th><th>partitions</th><th>status</th></tr>
+{{range .Constraints}}<tr><td>{{.LC.GetID}}</td><td>{{.Level}}</td><td>{{.Affinity}}</td><td>{{.Partitions}}</td>
+<td>{{if .Satisfied}}<span class="ok">satisfied</span>{{else}}<span class="fail">{{.Reason}}</span>{{end}}</td></tr>
+{{end}}</table>{{end}}
+<h3>Diagnostics</h3>
+{{if .Diagnostics}}<table>
+<tr><th>node</th><th>level</th><th>fit</th><th>placed</th><th>reason</th></tr>
+{{range .Diagnostics}}<tr><td>{{.NodeID}}</td><td>{{.Level}}</td><td>{{.NumFit}}</td><td>{{.NumPlaced}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+{{if .Bindings}}<details><summary>Bindings ({{len .Bindings}})</summary>
+<table>
+<tr><th>member</th><th>host</th></tr>
+{{range .Bindings}}<tr><td>{{index . 0}}</td><td>{{index . 1}}</td></tr>
+{{end}}</table></details>{{end}}
+{{end}}
+</body>
+</html>
+{{define "pnode"}}<li><span class="node">{{.ID}} <span class="level">[{{.Level}}]</span>
+<span class="bar" title="{{.Resources}}"><span style="width: {{printf "%.0f" .Utilization}}%; background: {{.Color}}"></span></span>
+{{printf "%3.0f%%" .Utilization}}{{if .Count}} <span class="count">&larr; {{.Count}}</span>{{end}}</span>
+{{if .Children}}<ul class="tree">{{range .Children}}{{template "pnode" .}}{{end}}</ul>{{end}}</li>
+{{end}}
+{{define "lnode"}}<li><span class="node">{{.ID}}: <span class="count">{{.Count}}</span>{{if .Claimed}} (claimed {{.Claimed}}){{end}}</span>
+{{if .Children}}<ul class="tree">{{range .Children}}{{template "lnode" .}}{{end}}</ul>{{end}}</li>
+{{end}}
+`))
@@ -0,0 +1,301 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+
+	"github.com/ibm/chic-sched/pkg/builder"
+	"github.com/ibm/chic-sched/pkg/placement"
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// Server : a scheduling service holding a physical tree and placement groups in memory
+//
+// Endpoints (JSON bodies mirror the spec formats):
+//   - GET  /topology : the physical tree with resources of its nodes
+//   - PUT  /topology : load (replace) the physical tree from a TopologyTree spec, dropping all groups
+//   - DELETE /topology/nodes/{id} : remove the subtree rooted at a node, evicting the members hosted there,
+//     and return the placements of the groups placed there, left with the members on the remaining PEs
+//   - GET  /groups : the placements of all groups
+//   - POST /groups : place a group given a PlacementGroup spec (claimed if query parameter claim=true,
+//     conflict if capacity exceeded)
+//   - GET  /groups/{id} : the placement of a group
+//   - DELETE /groups/{id} : unclaim and remove a group
+//   - POST /groups/{id}/claim : claim all members of a placed group (conflict if capacity exceeded)
+//   - POST /groups/{id}/unclaim : unclaim all members of a group
+type Server struct {
+	// lock protecting state
+	mu sync.Mutex
+	// the physical tree (nil if not loaded)
+	pTree *topology.PTree
+	// placed groups mapped to IDs
+	groups map[string]*placement.PGroup
+	// claimed state of groups mapped to IDs
+	claimed map[string]bool
+}
+
+// NewServer : create a new server with no topology loaded
+func NewServer() *Server {
+	return &Server{
+		pTree:   nil,
+		groups:  make(map[string]*placement.PGroup),
+		claimed: make(map[string]bool),
+	}
+}
+
+// SetPTree : set the physical tree, dropping all groups
+func (s *Server) SetPTree(pTree *topology.PTree) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setPTree(pTree)
+}
+
+// setPTree : set the physical tree, dropping all groups (assumes lock held)
+func (s *Server) setPTree(pTree *topology.PTree) {
+	s.pTree = pTree
+	s.groups = make(map[string]*placement.PGroup)
+	s.claimed = make(map[string]bool)
+}
+
+// Handler : the HTTP handler of the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/topology", s.handleTopology)
+	mux.HandleFunc("/topology/nodes/", s.handleNode)
+	mux.HandleFunc("/groups", s.handleGroups)
+	mux.HandleFunc("/groups/", s.handleGroup)
+	return mux
+}
+
+// handleTopology : get or replace the physical tree
+func (s *Server) handleTopology(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.pTree == nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("topology not loaded"))
+			return
+		}
+		writeJSON(w, http.StatusOK, builder.PTreeToSpec(s.pTree))
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		pTree, err := builder.CreateTopologyTreeFromJson(string(body))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.setPTree(pTree)
+		klog.V(4).Infof("topology loaded: %d leaves", len(pTree.GetLeafIDs()))
+		writeJSON(w, http.StatusOK, builder.PTreeToSpec(pTree))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+// handleNode : remove a node of the physical tree, pruning the placements of groups placed under it
+func (s *Server) handleNode(w http.ResponseWriter, r *http.Request) {
+	// node IDs of devices contain the device separator
+	id := strings.TrimPrefix(r.URL.Path, "/topology/nodes/")
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pTree == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("topology not loaded"))
+		return
+	}
+	if s.pTree.GetNode(id) == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("node %s not found", id))
+		return
+	}
+	evicted, err := s.pTree.RemoveSubtree(id)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	affected := make(map[string]bool)
+	for _, groupID := range evicted {
+		affected[groupID] = true
+	}
+	// groups placed but not claimed under the node have no members evicted
+	for groupID, pg := range s.groups {
+		if pg.PruneLTree(s.pTree) > 0 {
+			affected[groupID] = true
+		}
+	}
+	ids := make([]string, 0, len(affected))
+	for groupID := range affected {
+		if s.groups[groupID] != nil {
+			ids = append(ids, groupID)
+		}
+	}
+	sort.Strings(ids)
+	placements := make([]*util.JPlacement, len(ids))
+	for i, groupID := range ids {
+		placements[i] = builder.PlacementToSpec(s.groups[groupID])
+	}
+	klog.V(4).Infof("node %s removed; groups affected: %v", id, ids)
+	writeJSON(w, http.StatusOK, placements)
+}
+
+// handleGroups : list or submit groups
+func (s *Server) handleGroups(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		ids := make([]string, 0, len(s.groups))
+		for id := range s.groups {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		placements := make([]*util.JPlacement, len(ids))
+		for i, id := range ids {
+			placements[i] = builder.PlacementToSpec(s.groups[id])
+		}
+		writeJSON(w, http.StatusOK, placements)
+	case http.MethodPost:
+		s.submitGroup(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+// submitGroup : place a group and keep it if fully placed (assumes lock held)
+func (s *Server) submitGroup(w http.ResponseWriter, r *http.Request) {
+	if s.pTree == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("topology not loaded"))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	pg, err := builder.CreatePGroupFromJson(string(body), s.pTree.GetResourceNames(), s.pTree.GetLevelNames())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	id := pg.GetID()
+	if _, exists := s.groups[id]; exists {
+		writeError(w, http.StatusConflict, fmt.Errorf("group %s already exists", id))
+		return
+	}
+
+	p := placement.NewPlacer(s.pTree)
+	if _, err := p.PlaceGroup(pg); err != nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("group %s not placed: %s", id, err.Error()))
+		return
+	}
+	if !pg.IsFullyPlaced() {
+		writeError(w, http.StatusConflict, fmt.Errorf("group %s not fully placed: %d/%d",
+			id, builder.PlacementToSpec(pg).Placed, pg.GetSize()))
+		return
+	}
+	if r.URL.Query().Get("claim") == "true" {
+		if err := pg.TryClaim(pg.GetSize(), s.pTree); err != nil {
+			pg.UnClaimAll(s.pTree)
+			writeError(w, http.StatusConflict, fmt.Errorf("group %s not claimed: %s", id, err.Error()))
+			return
+		}
+		s.claimed[id] = true
+	}
+	s.groups[id] = pg
+	klog.V(4).Infof("group %s placed; claimed=%v", id, s.claimed[id])
+	writeJSON(w, http.StatusCreated, builder.PlacementToSpec(pg))
+}
+
+// handleGroup : get, delete, claim, or unclaim a group
+func (s *Server) handleGroup(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/groups/"), "/")
+	id := path[0]
+	action := ""
+	if len(path) == 2 {
+		action = path[1]
+	}
+	if len(id) == 0 || len(path) > 2 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("invalid path %s", r.URL.Path))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pg := s.groups[id]
+	if pg == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("group %s not found", id))
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, builder.PlacementToSpec(pg))
+	case action == "" && r.Method == http.MethodDelete:
+		if s.claimed[id] {
+			pg.UnClaimAll(s.pTree)
+		}
+		delete(s.groups, id)
+		delete(s.claimed, id)
+		writeJSON(w, http.StatusOK, builder.PlacementToSpec(pg))
+	case action == "claim" && r.Method == http.MethodPost:
+		if s.claimed[id] {
+			writeError(w, http.StatusConflict, fmt.Errorf("group %s already claimed", id))
+			return
+		}
+		if err := pg.TryClaim(pg.GetSize(), s.pTree); err != nil {
+			pg.UnClaimAll(s.pTree)
+			writeError(w, http.StatusConflict, fmt.Errorf("group %s not claimed: %s", id, err.Error()))
+			return
+		}
+		s.claimed[id] = true
+		writeJSON(w, http.StatusOK, builder.PlacementToSpec(pg))
+	case action == "unclaim" && r.Method == http.MethodPost:
+		if !s.claimed[id] {
+			writeError(w, http.StatusConflict, fmt.Errorf("group %s not claimed", id))
+			return
+		}
+		pg.UnClaimAll(s.pTree)
+		s.claimed[id] = false
+		writeJSON(w, http.StatusOK, builder.PlacementToSpec(pg))
+	case action == "" || action == "claim" || action == "unclaim":
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("invalid path %s", r.URL.Path))
+	}
+}
+
+// errorResponse : body of an error response
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeError : write an error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &errorResponse{Error: err.Error()})
+}
+
+// writeJSON : write a JSON response
+func writeJSON(w http.ResponseWriter, status int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		klog.Errorf("error writing response: %s", err.Error())
+	}
+}
@@ -0,0 +1,453 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// request : send a request to the server and return the response status and body
+func request(t *testing.T, ts *httptest.Server, method string, path string, body string) (int, string) {
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("error creating request: %v", err)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("error reading response: %v", err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+// sampleRacks : racks of the nodes of the sample tree
+var sampleRacks = map[string]string{"node-0": "rack-0", "node-1": "rack-0", "node-2": "rack-0",
+	"node-3": "rack-1", "node-4": "rack-1", "node-5": "rack-1"}
+
+// readSample : read a sample spec file, failing the test if missing
+func readSample(t *testing.T, name string) string {
+	data, err := os.ReadFile("../../samples/" + name)
+	if err != nil {
+		t.Fatalf("error reading sample: %v", err)
+	}
+	return string(data)
+}
+
+// newTestServer : start a server with a topology loaded, closed at the end of the test
+func newTestServer(t *testing.T, topologyTree string) *httptest.Server {
+	ts := httptest.NewServer(NewServer().Handler())
+	t.Cleanup(ts.Close)
+	if status, body := request(t, ts, http.MethodPut, "/topology", topologyTree); status != http.StatusOK {
+		t.Fatalf("PUT /topology = %d %s", status, body)
+	}
+	return ts
+}
+
+// submit : submit a group, failing the test if the status differs, and return its placement
+// (nil unless created)
+func submit(t *testing.T, ts *httptest.Server, path string, group string, wantStatus int) *util.JPlacement {
+	status, body := request(t, ts, http.MethodPost, path, group)
+	if status != wantStatus {
+		t.Fatalf("POST %s = %d %s, want %d", path, status, body, wantStatus)
+	}
+	if status != http.StatusCreated {
+		return nil
+	}
+	var jp util.JPlacement
+	if err := json.Unmarshal([]byte(body), &jp); err != nil {
+		t.Fatalf("POST %s response not JSON: %v", path, err)
+	}
+	return &jp
+}
+
+// usedRacks : racks of the sample tree where a group is bound
+func usedRacks(jp *util.JPlacement) map[string]bool {
+	used := make(map[string]bool)
+	for _, node := range jp.Bindings {
+		used[sampleRacks[node]] = true
+	}
+	return used
+}
+
+func TestServer(t *testing.T) {
+	topologyTree := readSample(t, "testTree.json")
+	group := readSample(t, "testGroup.json")
+	largeGroup := strings.Replace(group, `"size": 4`, `"size": 40`, 1)
+
+	ts := httptest.NewServer(NewServer().Handler())
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "no topology", method: http.MethodPost, path: "/groups", body: group,
+			wantStatus: http.StatusConflict, wantBody: "topology not loaded"},
+		{name: "bad topology", method: http.MethodPut, path: "/topology", body: `{"kind": "Tree"}`,
+			wantStatus: http.StatusBadRequest, wantBody: "invalid kind"},
+		{name: "load topology", method: http.MethodPut, path: "/topology", body: topologyTree,
+			wantStatus: http.StatusOK, wantBody: `"cpu":24`},
+		{name: "bad group", method: http.MethodPost, path: "/groups", body: `{"kind": "PlacementGroup"}`,
+			wantStatus: http.StatusBadRequest, wantBody: "metadata.name"},
+		{name: "group too large", method: http.MethodPost, path: "/groups", body: largeGroup,
+			wantStatus: http.StatusConflict, wantBody: "not fully placed"},
+		{name: "submit group", method: http.MethodPost, path: "/groups", body: group,
+			wantStatus: http.StatusCreated, wantBody: `"fully-placed":true`},
+		{name: "duplicate group", method: http.MethodPost, path: "/groups", body: group,
+			wantStatus: http.StatusConflict, wantBody: "already exists"},
+		{name: "claim group", method: http.MethodPost, path: "/groups/pg0/claim",
+			wantStatus: http.StatusOK, wantBody: `"bindings"`},
+		{name: "claim group again", method: http.MethodPost, path: "/groups/pg0/claim",
+			wantStatus: http.StatusConflict, wantBody: "already claimed"},
+		{name: "query topology", method: http.MethodGet, path: "/topology",
+			wantStatus: http.StatusOK, wantBody: `"cpu":40`},
+		{name: "query groups", method: http.MethodGet, path: "/groups",
+			wantStatus: http.StatusOK, wantBody: `"group":"pg0"`},
+		{name: "unclaim group", method: http.MethodPost, path: "/groups/pg0/unclaim",
+			wantStatus: http.StatusOK, wantBody: `"group":"pg0"`},
+		{name: "delete group", method: http.MethodDelete, path: "/groups/pg0",
+			wantStatus: http.StatusOK, wantBody: `"group":"pg0"`},
+		{name: "unknown group", method: http.MethodGet, path: "/groups/pg0",
+			wantStatus: http.StatusNotFound, wantBody: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := request(t, ts, tt.method, tt.path, tt.body)
+			if status != tt.wantStatus || !strings.Contains(body, tt.wantBody) {
+				t.Errorf("%s %s = %d %s, want %d %s", tt.method, tt.path, status, body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_ClaimOnSubmit(t *testing.T) {
+	ts := newTestServer(t, readSample(t, "testTree.json"))
+	jp := submit(t, ts, "/groups?claim=true", readSample(t, "testGroup.json"), http.StatusCreated)
+	if len(jp.Bindings) != jp.Size {
+		t.Errorf("POST /groups bindings = %v, want %d", jp.Bindings, jp.Size)
+	}
+}
+
+func TestServer_AntiAffinity(t *testing.T) {
+	topologyTree := readSample(t, "testTree.json")
+	group := readSample(t, "testGroup.json")
+
+	// pg0 is packed in rack-0, and 16 members of pg1 fit in rack-1
+	tests := []struct {
+		name       string
+		maxShared  int
+		wantStatus int
+		wantShared int
+	}{
+		{name: "no shared rack", maxShared: 0, wantStatus: http.StatusConflict, wantShared: 0},
+		{name: "one shared member", maxShared: 1, wantStatus: http.StatusCreated, wantShared: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, topologyTree)
+			used := usedRacks(submit(t, ts, "/groups?claim=true", group, http.StatusCreated))
+
+			pg1 := fmt.Sprintf(`{"kind": "PlacementGroup", "metadata": {"name": "pg1"},
+				"spec": {"size": 17, "demand": {"cpu": 2, "memory": 16},
+				"level-constraints": [{"id": "lc-1", "level-name": "rack", "affinity": "Pack"}],
+				"anti-affinity": [{"group": "pg0", "level-name": "rack", "max-shared": %d}]}}`, tt.maxShared)
+			jp1 := submit(t, ts, "/groups?claim=true", pg1, tt.wantStatus)
+			if jp1 == nil {
+				return
+			}
+			shared := 0
+			for _, node := range jp1.Bindings {
+				if used[sampleRacks[node]] {
+					shared++
+				}
+			}
+			if len(jp1.Bindings) != jp1.Size || shared != tt.wantShared {
+				t.Errorf("POST /groups bindings = %v, want %d in racks %v", jp1.Bindings, tt.wantShared, used)
+			}
+		})
+	}
+}
+
+func TestServer_GroupAffinity(t *testing.T) {
+	topologyTree := readSample(t, "testTree.json")
+	group := readSample(t, "testGroup.json")
+
+	// pg0 is packed in rack-0, where at most 12 members of pg1 fit, otherwise packed in rack-1
+	tests := []struct {
+		name          string
+		size          int
+		hard          bool
+		wantStatus    int
+		wantAllShared bool
+	}{
+		{name: "hard", size: 4, hard: true, wantStatus: http.StatusCreated, wantAllShared: true},
+		{name: "hard not fit", size: 17, hard: true, wantStatus: http.StatusConflict},
+		{name: "soft fallback", size: 17, hard: false, wantStatus: http.StatusCreated, wantAllShared: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, topologyTree)
+			used := usedRacks(submit(t, ts, "/groups?claim=true", group, http.StatusCreated))
+
+			pg1 := fmt.Sprintf(`{"kind": "PlacementGroup", "metadata": {"name": "pg1"},
+				"spec": {"size": %d, "demand": {"cpu": 2, "memory": 16},
+				"level-constraints": [{"id": "lc-1", "level-name": "rack", "affinity": "Pack"}],
+				"affinity": [{"group": "pg0", "level-name": "rack", "hard": %v}]}}`, tt.size, tt.hard)
+			jp1 := submit(t, ts, "/groups?claim=true", pg1, tt.wantStatus)
+			if jp1 == nil {
+				return
+			}
+			allShared := true
+			for _, node := range jp1.Bindings {
+				allShared = allShared && used[sampleRacks[node]]
+			}
+			if len(jp1.Bindings) != jp1.Size || allShared != tt.wantAllShared {
+				t.Errorf("POST /groups bindings = %v, want all in racks %v: %v", jp1.Bindings, used,
+					tt.wantAllShared)
+			}
+		})
+	}
+}
+
+func TestServer_Exclusive(t *testing.T) {
+	ts := newTestServer(t, readSample(t, "testTree.json"))
+
+	// tenant with an exclusive rack
+	tenant := `{"kind": "PlacementGroup", "metadata": {"name": "tenant"},
+		"spec": {"size": 2, "demand": {"cpu": 2, "memory": 16},
+		"level-constraints": [{"id": "lc-1", "level-name": "rack", "affinity": "Pack", "hard": true, "exclusive": true}]}}`
+	reserved := ""
+	for rack := range usedRacks(submit(t, ts, "/groups?claim=true", tenant, http.StatusCreated)) {
+		reserved = rack
+	}
+	if _, body := request(t, ts, http.MethodGet, "/topology", ""); !strings.Contains(body, `"reserved-by":"tenant"`) {
+		t.Errorf("GET /topology = %s, want rack reserved by tenant", body)
+	}
+
+	// other groups kept out of the reserved rack
+	jp0 := submit(t, ts, "/groups?claim=true", readSample(t, "testGroup.json"), http.StatusCreated)
+	for member, node := range jp0.Bindings {
+		if sampleRacks[node] == reserved {
+			t.Errorf("POST /groups placed %s on node %s in rack %s reserved by tenant", member, node, reserved)
+		}
+	}
+
+	// released when removed
+	request(t, ts, http.MethodDelete, "/groups/tenant", "")
+	if _, body := request(t, ts, http.MethodGet, "/topology", ""); strings.Contains(body, "reserved-by") {
+		t.Errorf("GET /topology = %s, want no reserved nodes", body)
+	}
+}
+
+func TestServer_ClaimExceedsCapacity(t *testing.T) {
+	ts := newTestServer(t, readSample(t, "testTree.json"))
+
+	// pg0 placed but not claimed, then all remaining capacity claimed by pg1
+	submit(t, ts, "/groups", readSample(t, "testGroup.json"), http.StatusCreated)
+	pg1 := `{"kind": "PlacementGroup", "metadata": {"name": "pg1"},
+		"spec": {"size": 36, "demand": {"cpu": 2, "memory": 16}}}`
+	submit(t, ts, "/groups?claim=true", pg1, http.StatusCreated)
+	status, body := request(t, ts, http.MethodPost, "/groups/pg0/claim", "")
+	if status != http.StatusConflict || !strings.Contains(body, "exceeds cpu capacity") {
+		t.Errorf("POST /groups/pg0/claim = %d %s, want %d", status, body, http.StatusConflict)
+	}
+	if status, _ := request(t, ts, http.MethodPost, "/groups/pg0/unclaim", ""); status != http.StatusConflict {
+		t.Errorf("POST /groups/pg0/unclaim = %d, want %d (not claimed)", status, http.StatusConflict)
+	}
+}
+
+func TestServer_Devices(t *testing.T) {
+	gpu := `{"capacity": {"gpu": 1, "cpu": 8}}`
+	server := fmt.Sprintf(`{"devices": {
+		"nvs-0": {"level": {"gpu-0": %[1]s, "gpu-1": %[1]s}},
+		"nvs-1": {"level": {"gpu-2": %[1]s, "gpu-3": %[1]s}}}}`, gpu)
+	topologyTree := fmt.Sprintf(`{"kind": "TopologyTree", "metadata": {"name": "gpus"},
+		"spec": {"resource-names": ["gpu", "cpu"], "level-names": ["node"],
+		"device-level-names": ["nvswitch", "gpu"],
+		"tree": {"level": {"node-0": %[1]s, "node-1": %[1]s}}}}`, server)
+	nvswitch := map[string]string{"gpu-0": "nvs-0", "gpu-1": "nvs-0", "gpu-2": "nvs-1", "gpu-3": "nvs-1"}
+
+	tests := []struct {
+		name     string
+		affinity string
+		want     int
+	}{
+		{name: "pack on GPUs sharing an NVSwitch", affinity: "Pack", want: 1},
+		{name: "spread across NVSwitches", affinity: "Spread", want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, topologyTree)
+			group := fmt.Sprintf(`{"kind": "PlacementGroup", "metadata": {"name": "train"},
+				"spec": {"size": 2, "demand": {"gpu": 1, "cpu": 4},
+				"level-constraints": [{"id": "lc-1", "level-name": "node", "affinity": "Pack", "hard": true},
+				{"id": "lc-2", "level-name": "nvswitch", "affinity": %q, "hard": true}]}}`, tt.affinity)
+			jp := submit(t, ts, "/groups?claim=true", group, http.StatusCreated)
+			if len(jp.Bindings) != 2 {
+				t.Fatalf("POST /groups bindings = %v, want 2 devices", jp.Bindings)
+			}
+			nodes := make(map[string]bool)
+			switches := make(map[string]bool)
+			for member, device := range jp.Bindings {
+				parts := strings.Split(device, "/")
+				if len(parts) != 2 || len(nvswitch[parts[1]]) == 0 {
+					t.Fatalf("POST /groups bound %s to %s, want a GPU device", member, device)
+				}
+				nodes[parts[0]] = true
+				switches[nvswitch[parts[1]]] = true
+			}
+			if len(nodes) != 1 || len(switches) != tt.want {
+				t.Errorf("POST /groups bindings = %v, want %d NVSwitches on one node", jp.Bindings, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_MaxMembers(t *testing.T) {
+	tree := strings.Replace(readSample(t, "testTree.json"), `"spec": {`, `"spec": {"max-members": 3,`, 1)
+	ts := newTestServer(t, tree)
+
+	// members of tiny demand spread over nodes, at most 3 per node
+	pg1 := `{"kind": "PlacementGroup", "metadata": {"name": "pg1"},
+		"spec": {"size": 10, "demand": {"cpu": 1}}}`
+	jp := submit(t, ts, "/groups?claim=true", pg1, http.StatusCreated)
+	perNode := make(map[string]int)
+	for _, node := range jp.Bindings {
+		perNode[node]++
+	}
+	for node, n := range perNode {
+		if n > 3 {
+			t.Errorf("POST /groups placed %d members on node %s, want at most 3", n, node)
+		}
+	}
+
+	// the limit counts members of all groups, 8 slots left
+	pg2 := `{"kind": "PlacementGroup", "metadata": {"name": "pg2"},
+		"spec": {"size": 9, "demand": {"cpu": 1}}}`
+	submit(t, ts, "/groups?claim=true", pg2, http.StatusConflict)
+}
+
+func TestServer_NodeOrder(t *testing.T) {
+	// racks fitting the same number of members, the first in ID order with GPUs
+	topologyTree := `{"kind": "TopologyTree", "metadata": {"name": "mixed"},
+		"spec": {"resource-names": ["cpu", "gpu"], "level-names": ["rack", "server"],
+		"tree": {"level": {
+			"rack-0": {"level": {"node-0": {"capacity": {"cpu": 8, "gpu": 4}}}},
+			"rack-1": {"level": {"node-1": {"capacity": {"cpu": 8}}}}}}}}`
+
+	tests := []struct {
+		name     string
+		order    string
+		wantNode string
+	}{
+		{name: "number that can fit", order: `"node-order": "NumFit"`, wantNode: "node-0"},
+		{name: "dominant share", order: `"node-order": "DominantShare"`, wantNode: "node-1"},
+		{name: "weighted leftover", order: `"node-order": "WeightedLeftover", "resource-weights": {"gpu": 1}`,
+			wantNode: "node-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, topologyTree)
+			group := fmt.Sprintf(`{"kind": "PlacementGroup", "metadata": {"name": "cpu-only"},
+				"spec": {"size": 2, "demand": {"cpu": 4}, %s,
+				"level-constraints": [{"id": "lc-1", "level-name": "rack", "affinity": "Pack", "hard": true}]}}`,
+				tt.order)
+			jp := submit(t, ts, "/groups?claim=true", group, http.StatusCreated)
+			for member, node := range jp.Bindings {
+				if node != tt.wantNode {
+					t.Errorf("POST /groups placed %s on %s, want %s", member, node, tt.wantNode)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_RemoveNode(t *testing.T) {
+	node := func(pool string) string {
+		return fmt.Sprintf(`{"capacity": {"cpu": 4}, "labels": {"pool": %q}}`, pool)
+	}
+	topologyTree := fmt.Sprintf(`{"kind": "TopologyTree", "metadata": {"name": "pools"},
+		"spec": {"resource-names": ["cpu"], "level-names": ["rack", "server"],
+		"tree": {"level": {"rack-0": {"level": {"node-0": %s, "node-1": %s}},
+		"rack-1": {"level": {"node-2": %s}}}}}}`, node("a"), node("b"), node("c"))
+	group := func(name string, pool string) string {
+		return fmt.Sprintf(`{"kind": "PlacementGroup", "metadata": {"name": %q},
+			"spec": {"size": 1, "demand": {"cpu": 2}, "node-selector": [{"key": "pool", "values": [%q]}]}}`,
+			name, pool)
+	}
+	ts := httptest.NewServer(NewServer().Handler())
+	defer ts.Close()
+	if status, body := request(t, ts, http.MethodDelete, "/topology/nodes/node-0", ""); status != http.StatusNotFound {
+		t.Errorf("DELETE /topology/nodes/node-0 without topology = %d %s, want %d", status, body, http.StatusNotFound)
+	}
+	if status, body := request(t, ts, http.MethodPut, "/topology", topologyTree); status != http.StatusOK {
+		t.Fatalf("PUT /topology = %d %s", status, body)
+	}
+	submit(t, ts, "/groups?claim=true", group("claimed", "a"), http.StatusCreated)
+	submit(t, ts, "/groups", group("placed", "a"), http.StatusCreated)
+	submit(t, ts, "/groups?claim=true", group("other", "c"), http.StatusCreated)
+
+	steps := []struct {
+		name       string
+		method     string
+		nodeID     string
+		wantStatus int
+		// groups affected, with no members left placed
+		wantGroups []string
+		wantCPU    int
+	}{
+		{name: "not allowed", method: http.MethodGet, nodeID: "node-0", wantStatus: http.StatusMethodNotAllowed,
+			wantCPU: 12},
+		{name: "missing node", method: http.MethodDelete, nodeID: "node-9", wantStatus: http.StatusNotFound,
+			wantCPU: 12},
+		{name: "node of claimed and placed groups", method: http.MethodDelete, nodeID: "node-0",
+			wantStatus: http.StatusOK, wantGroups: []string{"claimed", "placed"}, wantCPU: 8},
+		{name: "rack of a claimed group", method: http.MethodDelete, nodeID: "rack-1",
+			wantStatus: http.StatusOK, wantGroups: []string{"other"}, wantCPU: 4},
+		{name: "last node", method: http.MethodDelete, nodeID: "node-1", wantStatus: http.StatusConflict,
+			wantCPU: 4},
+	}
+	for _, step := range steps {
+		status, body := request(t, ts, step.method, "/topology/nodes/"+step.nodeID, "")
+		if status != step.wantStatus {
+			t.Errorf("%s: %s /topology/nodes/%s = %d %s, want %d", step.name, step.method, step.nodeID,
+				status, body, step.wantStatus)
+			continue
+		}
+		if status == http.StatusOK {
+			var placements []*util.JPlacement
+			json.Unmarshal([]byte(body), &placements)
+			groups := make([]string, len(placements))
+			for i, jp := range placements {
+				groups[i] = jp.Group
+				if jp.Placed != 0 || len(jp.Bindings) != 0 {
+					t.Errorf("%s: group %s placed %d bound %v, want none", step.name, jp.Group, jp.Placed, jp.Bindings)
+				}
+			}
+			if strings.Join(groups, ",") != strings.Join(step.wantGroups, ",") {
+				t.Errorf("%s: groups affected = %v, want %v", step.name, groups, step.wantGroups)
+			}
+		}
+		_, body = request(t, ts, http.MethodGet, "/topology", "")
+		var root util.JPNode
+		json.Unmarshal([]byte(body), &root)
+		if root.Capacity["cpu"] != step.wantCPU {
+			t.Errorf("%s: GET /topology root capacity = %v, want cpu %d", step.name, root.Capacity, step.wantCPU)
+		}
+	}
+}
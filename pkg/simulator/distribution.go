@@ -0,0 +1,144 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Distribution : a distribution of non-negative values, sampled with a given random source
+type Distribution interface {
+	// Sample : draw a value
+	Sample(rng *rand.Rand) float64
+	// String : a print out of the distribution
+	String() string
+}
+
+// Constant : a distribution of a single value
+type Constant struct {
+	value float64
+}
+
+// NewConstant : create a distribution of a single value
+//   - returns nil if bad parameters
+func NewConstant(value float64) *Constant {
+	if value < 0 {
+		return nil
+	}
+	return &Constant{value: value}
+}
+
+// Sample : draw a value
+func (d *Constant) Sample(rng *rand.Rand) float64 {
+	return d.value
+}
+
+// String : a print out of the distribution
+func (d *Constant) String() string {
+	return fmt.Sprintf("Constant(%v)", d.value)
+}
+
+// Exponential : an exponential distribution with a given mean, e.g. of the times between Poisson arrivals
+type Exponential struct {
+	mean float64
+}
+
+// NewExponential : create an exponential distribution with a given mean
+//   - returns nil if bad parameters
+func NewExponential(mean float64) *Exponential {
+	if mean <= 0 {
+		return nil
+	}
+	return &Exponential{mean: mean}
+}
+
+// Sample : draw a value
+func (d *Exponential) Sample(rng *rand.Rand) float64 {
+	return rng.ExpFloat64() * d.mean
+}
+
+// String : a print out of the distribution
+func (d *Exponential) String() string {
+	return fmt.Sprintf("Exponential(mean=%v)", d.mean)
+}
+
+// Uniform : a uniform distribution over an interval [min, max)
+type Uniform struct {
+	min float64
+	max float64
+}
+
+// NewUniform : create a uniform distribution over an interval [min, max)
+//   - returns nil if bad parameters
+func NewUniform(min float64, max float64) *Uniform {
+	if min < 0 || max < min {
+		return nil
+	}
+	return &Uniform{min: min, max: max}
+}
+
+// Sample : draw a value
+func (d *Uniform) Sample(rng *rand.Rand) float64 {
+	return d.min + rng.Float64()*(d.max-d.min)
+}
+
+// String : a print out of the distribution
+func (d *Uniform) String() string {
+	return fmt.Sprintf("Uniform(%v,%v)", d.min, d.max)
+}
+
+// Discrete : a distribution over given values with given weights, e.g. of group sizes
+type Discrete struct {
+	values []float64
+	// cumulative weights
+	cumulative []float64
+}
+
+// NewDiscrete : create a distribution over given values with given (relative) weights
+//   - returns nil if bad parameters
+func NewDiscrete(values []float64, weights []float64) *Discrete {
+	if len(values) == 0 || len(values) != len(weights) {
+		return nil
+	}
+	cumulative := make([]float64, len(weights))
+	total := 0.0
+	for i, w := range weights {
+		if w < 0 || values[i] < 0 {
+			return nil
+		}
+		total += w
+		cumulative[i] = total
+	}
+	if total <= 0 {
+		return nil
+	}
+	return &Discrete{
+		values:     append([]float64{}, values...),
+		cumulative: cumulative,
+	}
+}
+
+// Sample : draw a value
+func (d *Discrete) Sample(rng *rand.Rand) float64 {
+	x := rng.Float64() * d.cumulative[len(d.cumulative)-1]
+	for i, c := range d.cumulative {
+		if x < c {
+			return d.values[i]
+		}
+	}
+	return d.values[len(d.values)-1]
+}
+
+// String : a print out of the distribution
+func (d *Discrete) String() string {
+	return fmt.Sprintf("Discrete(%v)", d.values)
+}
+
+// sampleInt : draw a value rounded to an integer, not below a given minimum
+func sampleInt(d Distribution, rng *rand.Rand, min int) int {
+	v := int(math.Round(d.Sample(rng)))
+	if v < min {
+		return min
+	}
+	return v
+}
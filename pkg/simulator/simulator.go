@@ -0,0 +1,398 @@
+package simulator
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"unsafe"
+
+	"k8s.io/klog/v2"
+
+	"github.com/ibm/chic-sched/pkg/placement"
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// A discrete-event simulation of placement groups arriving to, and departing from, a physical tree:
+//   - groups arrive at random times with random sizes and durations, and wait in a queue
+//   - queued groups are placed and claimed in order, possibly with backfill, as resources are released
+//   - groups waiting longer than a maximum wait are rejected
+//   - time is in whole time units, sampled values are rounded
+
+// Config : configuration of a simulation
+type Config struct {
+	// seed of the random source
+	Seed int64
+	// time between arrivals of groups (at least one time unit)
+	InterArrival Distribution
+	// number of members of a group (at least one)
+	Size Distribution
+	// duration of a group once claimed (at least one time unit)
+	Duration Distribution
+	// resource demand of a member, ordered as the resources of the tree
+	Demand []int
+	// level constraints of all groups (optional), copied to each group
+	LevelConstraints []*placement.LevelConstraint
+	// maximum time a group waits in the queue before rejected (zero if unlimited)
+	MaxWait int
+	// backfill groups ahead of a blocked group, otherwise first come, first served
+	Backfill bool
+	// end time of the simulation
+	Horizon int
+	// time between samples of the metrics
+	SampleInterval int
+}
+
+// Sample : metrics of the simulation at a point in time
+type Sample struct {
+	// time of the sample
+	Time int
+	// allocated over capacity of the tree, per resource
+	Utilization []float64
+	// share of the available resources on PEs where a member does not fit
+	Fragmentation float64
+	// number of groups waiting in the queue
+	Queued int
+	// number of groups claimed
+	Running int
+	// cumulative number of groups arrived, claimed, and rejected
+	Arrived, Started, Rejected int
+	// share of the decided (claimed or rejected) groups rejected so far
+	RejectionRate float64
+	// average wait of groups claimed so far
+	MeanWait float64
+}
+
+// GroupRecord : the life of a group in the simulation
+type GroupRecord struct {
+	// ID of the group
+	ID string
+	// number of members
+	Size int
+	// duration once claimed
+	Duration int
+	// time of arrival
+	Arrival int
+	// time claimed (-1 if not claimed)
+	Start int
+	// time rejected (-1 if not rejected)
+	Rejected int
+}
+
+// Result : the outcome of a simulation
+type Result struct {
+	// time series of metrics
+	Samples []*Sample
+	// arrived groups, ordered by arrival
+	Groups []*GroupRecord
+}
+
+// Simulator : a discrete-event simulator of placement groups on a physical tree
+type Simulator struct {
+	// physical tree
+	pTree *topology.PTree
+	// configuration
+	config *Config
+	// random source
+	rng *rand.Rand
+	// calendar of claimed groups
+	calendar *placement.Calendar
+	// demand of a member
+	demand *util.Allocation
+	// pending events
+	events eventQueue
+	// sequence number of the next event
+	seq int
+	// groups waiting in the queue, ordered by arrival
+	queue []*placement.PGroup
+	// records of groups, keyed by group ID
+	records map[string]*GroupRecord
+	// result being collected
+	result *Result
+	// totals so far
+	arrived, started, rejected, totalWait int
+}
+
+// NewSimulator : create a simulator of groups on a physical tree, starting from its current allocation
+//   - returns nil if bad parameters
+func NewSimulator(pTree *topology.PTree, config *Config) *Simulator {
+	if pTree == nil || pTree.GetRoot() == nil || config == nil || config.InterArrival == nil ||
+		config.Size == nil || config.Duration == nil || config.Horizon <= 0 || config.SampleInterval <= 0 ||
+		config.MaxWait < 0 {
+		return nil
+	}
+	demand, err := util.NewAllocationCopy(config.Demand)
+	pRoot := (*topology.PNode)(unsafe.Pointer(pTree.GetRoot()))
+	if err != nil || demand.GetSize() == 0 || demand.GetSize() != pRoot.GetNumResources() || demand.IsZero() {
+		return nil
+	}
+	return &Simulator{
+		pTree:    pTree,
+		config:   config,
+		rng:      rand.New(rand.NewSource(config.Seed)),
+		calendar: placement.NewCalendar(pTree),
+		demand:   demand,
+	}
+}
+
+// Run : run the simulation up to the horizon and return the result
+//   - groups still claimed by a previous run are released, and the random source is reseeded,
+//     so that runs start from the same allocation and are reproducible
+func (s *Simulator) Run() *Result {
+	for _, b := range s.calendar.GetBookings() {
+		s.calendar.Release(b.Group.GetID())
+	}
+	s.calendar = placement.NewCalendar(s.pTree)
+	s.rng = rand.New(rand.NewSource(s.config.Seed))
+	s.seq = 0
+	s.events = make(eventQueue, 0)
+	s.queue = make([]*placement.PGroup, 0)
+	s.records = make(map[string]*GroupRecord)
+	s.result = &Result{Samples: make([]*Sample, 0), Groups: make([]*GroupRecord, 0)}
+	s.arrived, s.started, s.rejected, s.totalWait = 0, 0, 0, 0
+
+	s.push(sampleInt(s.config.InterArrival, s.rng, 1), arrivalEvent, "")
+	for t := 0; t <= s.config.Horizon; t += s.config.SampleInterval {
+		s.push(t, sampleEvent, "")
+	}
+	for len(s.events) > 0 {
+		now := s.events[0].time
+		if now > s.config.Horizon {
+			break
+		}
+		// process all events at this time: departures, arrivals and deadlines, then sample
+		isSample := false
+		for len(s.events) > 0 && s.events[0].time == now {
+			e := heap.Pop(&s.events).(*event)
+			switch e.kind {
+			case departureEvent:
+				s.calendar.ReleaseDue(now)
+			case arrivalEvent:
+				s.arrive(now)
+			case deadlineEvent:
+				// groups waiting for the maximum wait are rejected below
+			case sampleEvent:
+				isSample = true
+			}
+		}
+		s.schedule(now)
+		s.reject(now)
+		if isSample {
+			s.result.Samples = append(s.result.Samples, s.sample(now))
+		}
+	}
+	return s.result
+}
+
+// arrive : a new group arrives to the queue, and the next arrival is set
+func (s *Simulator) arrive(now int) {
+	id := fmt.Sprintf("g-%d", s.arrived)
+	size := sampleInt(s.config.Size, s.rng, 1)
+	duration := sampleInt(s.config.Duration, s.rng, 1)
+	pg := placement.NewPGroup(id, size, s.demand)
+	pg.SetDuration(duration)
+	for _, lc := range s.config.LevelConstraints {
+		pg.AddLevelConstraint(lc.Clone())
+	}
+	record := &GroupRecord{ID: id, Size: size, Duration: duration, Arrival: now, Start: -1, Rejected: -1}
+	s.records[id] = record
+	s.result.Groups = append(s.result.Groups, record)
+	s.queue = append(s.queue, pg)
+	s.arrived++
+	if s.config.MaxWait > 0 {
+		s.push(now+s.config.MaxWait, deadlineEvent, id)
+	}
+	s.push(now+sampleInt(s.config.InterArrival, s.rng, 1), arrivalEvent, "")
+}
+
+// schedule : claim groups from the queue, setting their departures
+func (s *Simulator) schedule(now int) {
+	if len(s.queue) == 0 {
+		return
+	}
+	claimed := make(map[string]bool)
+	for _, pg := range s.calendar.Schedule(s.queue, now, s.config.Backfill) {
+		id := pg.GetID()
+		claimed[id] = true
+		record := s.records[id]
+		record.Start = now
+		s.started++
+		s.totalWait += now - record.Arrival
+		s.push(now+pg.GetDuration(), departureEvent, id)
+		klog.V(4).Infof("time %d: group %s claimed after waiting %d", now, id, now-record.Arrival)
+	}
+	s.removeFromQueue(claimed)
+}
+
+// reject : reject the queued groups waiting for the maximum wait (if any)
+func (s *Simulator) reject(now int) {
+	if s.config.MaxWait == 0 {
+		return
+	}
+	rejected := make(map[string]bool)
+	for _, pg := range s.queue {
+		record := s.records[pg.GetID()]
+		if now-record.Arrival >= s.config.MaxWait {
+			rejected[pg.GetID()] = true
+			record.Rejected = now
+			s.rejected++
+			klog.V(4).Infof("time %d: group %s rejected", now, pg.GetID())
+		}
+	}
+	s.removeFromQueue(rejected)
+}
+
+// removeFromQueue : remove given groups from the queue, keeping the order
+func (s *Simulator) removeFromQueue(ids map[string]bool) {
+	if len(ids) == 0 {
+		return
+	}
+	queue := make([]*placement.PGroup, 0, len(s.queue))
+	for _, pg := range s.queue {
+		if !ids[pg.GetID()] {
+			queue = append(queue, pg)
+		}
+	}
+	s.queue = queue
+}
+
+// sample : the metrics at a given time
+func (s *Simulator) sample(now int) *Sample {
+	pRoot := (*topology.PNode)(unsafe.Pointer(s.pTree.GetRoot()))
+	capacity := pRoot.GetCapacity().GetValue()
+	allocated := pRoot.GetAllocated().GetValue()
+	utilization := make([]float64, len(capacity))
+	for i := range capacity {
+		if capacity[i] > 0 {
+			utilization[i] = float64(allocated[i]) / float64(capacity[i])
+		}
+	}
+	sample := &Sample{
+		Time:          now,
+		Utilization:   utilization,
+		Fragmentation: s.fragmentation(capacity),
+		Queued:        len(s.queue),
+		Running:       len(s.calendar.GetBookings()),
+		Arrived:       s.arrived,
+		Started:       s.started,
+		Rejected:      s.rejected,
+	}
+	if decided := s.started + s.rejected; decided > 0 {
+		sample.RejectionRate = float64(s.rejected) / float64(decided)
+	}
+	if s.started > 0 {
+		sample.MeanWait = float64(s.totalWait) / float64(s.started)
+	}
+	return sample
+}
+
+// fragmentation : the share of the available resources, as shares of the total capacity,
+// on PEs where a member does not fit
+func (s *Simulator) fragmentation(total []int) float64 {
+	free, stranded := 0.0, 0.0
+	for _, pe := range s.pTree.GetPEs() {
+		capacity := pe.GetEffectiveCapacity().GetValue()
+		allocated := pe.GetAllocated().GetValue()
+		fits := pe.IsSchedulable() && pe.NumberToFit(s.demand) > 0
+		for i := range capacity {
+			if total[i] <= 0 {
+				continue
+			}
+			share := float64(util.Max(capacity[i]-allocated[i], 0)) / float64(total[i])
+			free += share
+			if !fits {
+				stranded += share
+			}
+		}
+	}
+	if free == 0 {
+		return 0
+	}
+	return stranded / free
+}
+
+// push : add an event
+func (s *Simulator) push(time int, kind eventKind, groupID string) {
+	heap.Push(&s.events, &event{time: time, kind: kind, groupID: groupID, seq: s.seq})
+	s.seq++
+}
+
+// WriteCSV : write the time series of metrics as comma separated values, with a header line
+//   - resourceNames: names of resources, labeling the utilization columns
+func (r *Result) WriteCSV(w io.Writer, resourceNames []string) error {
+	header := []string{"time"}
+	for _, name := range resourceNames {
+		header = append(header, "util-"+name)
+	}
+	header = append(header, "fragmentation", "queued", "running", "arrived", "started", "rejected",
+		"rejection-rate", "mean-wait")
+	if _, err := fmt.Fprintln(w, strings.Join(header, ",")); err != nil {
+		return err
+	}
+	for _, sample := range r.Samples {
+		if len(sample.Utilization) != len(resourceNames) {
+			return fmt.Errorf("%d resource names, expected %d", len(resourceNames), len(sample.Utilization))
+		}
+		fields := []string{fmt.Sprint(sample.Time)}
+		for _, u := range sample.Utilization {
+			fields = append(fields, fmt.Sprintf("%.4f", u))
+		}
+		fields = append(fields, fmt.Sprintf("%.4f", sample.Fragmentation), fmt.Sprint(sample.Queued),
+			fmt.Sprint(sample.Running), fmt.Sprint(sample.Arrived), fmt.Sprint(sample.Started),
+			fmt.Sprint(sample.Rejected), fmt.Sprintf("%.4f", sample.RejectionRate),
+			fmt.Sprintf("%.4f", sample.MeanWait))
+		if _, err := fmt.Fprintln(w, strings.Join(fields, ",")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventKind : the kind of an event
+type eventKind int
+
+const (
+	// claimed groups are due to depart
+	departureEvent eventKind = iota
+	// a group arrives
+	arrivalEvent
+	// a queued group reaches its maximum wait
+	deadlineEvent
+	// metrics are sampled
+	sampleEvent
+)
+
+// event : an event at a point in time
+type event struct {
+	time    int
+	kind    eventKind
+	groupID string
+	// sequence number, ordering events at the same time
+	seq int
+}
+
+// eventQueue : a priority queue of events, ordered by time then sequence number
+type eventQueue []*event
+
+func (q eventQueue) Len() int { return len(q) }
+
+func (q eventQueue) Less(i, j int) bool {
+	if q[i].time != q[j].time {
+		return q[i].time < q[j].time
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*event)) }
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	e := old[n-1]
+	*q = old[:n-1]
+	return e
+}
@@ -0,0 +1,176 @@
+package simulator
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/placement"
+	"github.com/ibm/chic-sched/pkg/system"
+	"github.com/ibm/chic-sched/pkg/topology"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// makePNode : make a PNode at a level, with a PE of a given cpu capacity if a leaf
+func makePNode(id string, level int, cpu int, children ...*topology.PNode) *topology.PNode {
+	var entity *system.Entity
+	if len(children) == 0 {
+		capacity, _ := util.NewAllocationCopy([]int{cpu})
+		entity = (*system.Entity)(unsafe.Pointer(system.NewPE(id, capacity)))
+	} else {
+		entity = &system.Entity{ID: id}
+	}
+	pNode := topology.NewPNode(topology.NewNode(entity), level, 1)
+	for _, child := range children {
+		pNode.AddChild((*topology.Node)(unsafe.Pointer(child)))
+	}
+	return pNode
+}
+
+// makePTree : make a tree of two racks, with two PEs of 4 cpus each
+func makePTree() *topology.PTree {
+	root := makePNode("root", 2, 0,
+		makePNode("rack-0", 1, 0, makePNode("pe-0", 0, 4), makePNode("pe-1", 0, 4)),
+		makePNode("rack-1", 1, 0, makePNode("pe-2", 0, 4), makePNode("pe-3", 0, 4)))
+	pTree := topology.NewPTree(topology.NewTree((*topology.Node)(unsafe.Pointer(root))))
+	pTree.PercolateResources()
+	return pTree
+}
+
+// makeConfig : make a configuration of groups of 1 to 4 members of 1 cpu
+func makeConfig(meanInterArrival float64, maxWait int, backfill bool) *Config {
+	return &Config{
+		Seed:           7,
+		InterArrival:   NewExponential(meanInterArrival),
+		Size:           NewDiscrete([]float64{1, 2, 4}, []float64{2, 1, 1}),
+		Duration:       NewUniform(5, 20),
+		Demand:         []int{1},
+		MaxWait:        maxWait,
+		Backfill:       backfill,
+		Horizon:        500,
+		SampleInterval: 10,
+	}
+}
+
+func TestNewSimulator(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		want   bool
+	}{
+		{"valid", makeConfig(2, 10, false), true},
+		{"no config", nil, false},
+		{"no size distribution", func() *Config { c := makeConfig(2, 10, false); c.Size = nil; return c }(), false},
+		{"zero horizon", func() *Config { c := makeConfig(2, 10, false); c.Horizon = 0; return c }(), false},
+		{"negative max wait", func() *Config { c := makeConfig(2, 10, false); c.MaxWait = -1; return c }(), false},
+		{"demand size mismatch", func() *Config { c := makeConfig(2, 10, false); c.Demand = []int{1, 1}; return c }(),
+			false},
+		{"zero demand", func() *Config { c := makeConfig(2, 10, false); c.Demand = []int{0}; return c }(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewSimulator(makePTree(), tt.config) != nil; got != tt.want {
+				t.Errorf("NewSimulator() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulator_Run(t *testing.T) {
+	tests := []struct {
+		name             string
+		meanInterArrival float64
+		maxWait          int
+		backfill         bool
+		wantRejections   bool
+	}{
+		{"light load", 20, 10, false, false},
+		{"overload with short wait", 0.5, 1, false, true},
+		{"overload without wait limit", 0.5, 0, false, false},
+		{"overload with waiting", 0.5, 30, false, true},
+		{"overload with backfill", 0.5, 30, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			config := makeConfig(tt.meanInterArrival, tt.maxWait, tt.backfill)
+			sim := NewSimulator(pTree, config)
+			result := sim.Run()
+
+			if len(result.Samples) != config.Horizon/config.SampleInterval+1 {
+				t.Fatalf("Run() got %d samples, want %d", len(result.Samples), config.Horizon/config.SampleInterval+1)
+			}
+			for _, s := range result.Samples {
+				if s.Utilization[0] < 0 || s.Utilization[0] > 1 || s.Fragmentation < 0 || s.Fragmentation > 1 {
+					t.Errorf("Run() sample at %d out of range: utilization %v, fragmentation %v",
+						s.Time, s.Utilization, s.Fragmentation)
+				}
+				if s.Arrived != s.Started+s.Rejected+s.Queued {
+					t.Errorf("Run() sample at %d: arrived %d, started %d, rejected %d, queued %d",
+						s.Time, s.Arrived, s.Started, s.Rejected, s.Queued)
+				}
+			}
+			rejected := 0
+			for _, g := range result.Groups {
+				if g.Start >= 0 && tt.maxWait > 0 && g.Start-g.Arrival > tt.maxWait {
+					t.Errorf("Run() group %s waited %d, max wait %d", g.ID, g.Start-g.Arrival, tt.maxWait)
+				}
+				if g.Rejected >= 0 {
+					rejected++
+				}
+			}
+			if got := rejected > 0; got != tt.wantRejections {
+				t.Errorf("Run() rejections = %v, want %v", got, tt.wantRejections)
+			}
+
+			// same seed, same result
+			again := NewSimulator(makePTree(), config).Run()
+			if !reflect.DeepEqual(result, again) {
+				t.Errorf("Run() not reproducible with the same seed")
+			}
+
+			// a run again releases the groups claimed by the previous run
+			if rerun := sim.Run(); !reflect.DeepEqual(result, rerun) {
+				t.Errorf("Run() again not reproducible")
+			}
+		})
+	}
+}
+
+func TestSimulator_LevelConstraints(t *testing.T) {
+	config := makeConfig(0.5, 0, false)
+	lc := placement.NewLevelConstraint("lc-rack", 1, util.Spread, false)
+	config.LevelConstraints = []*placement.LevelConstraint{lc}
+	sim := NewSimulator(makePTree(), config)
+	sim.Run()
+	if len(sim.queue) < 2 {
+		t.Fatalf("Run() left %d groups queued, want at least 2", len(sim.queue))
+	}
+
+	// each group has its own copy of the level constraints
+	copies := make(map[*placement.LevelConstraint]bool)
+	for _, pg := range sim.queue {
+		got := pg.GetLevelConstraint(1)
+		if got == nil || got == lc || copies[got] || got.GetID() != "lc-rack" || got.Affinity() != util.Spread {
+			t.Fatalf("group %s level constraint = %v, want a copy of %v", pg.GetID(), got, lc)
+		}
+		copies[got] = true
+	}
+}
+
+func TestResult_WriteCSV(t *testing.T) {
+	result := NewSimulator(makePTree(), makeConfig(2, 10, false)).Run()
+	var buf bytes.Buffer
+	if err := result.WriteCSV(&buf, []string{"cpu"}); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(result.Samples)+1 || !strings.HasPrefix(lines[0], "time,util-cpu,") {
+		t.Errorf("WriteCSV() got %d lines, header %q", len(lines), lines[0])
+	}
+	if err := result.WriteCSV(&buf, []string{"cpu", "memory"}); err == nil {
+		t.Errorf("WriteCSV() with wrong resource names, want error")
+	}
+}
@@ -0,0 +1,67 @@
+package system
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// CapacityError : placing an LE on a PE exceeds the (effective) capacity of a resource
+type CapacityError struct {
+	// ID of the PE
+	PEID string
+	// ID of the LE
+	LEID string
+	// index of the resource
+	Resource int
+	// name of the resource (empty if the PE has no resource names)
+	ResourceName string
+	// demand of the LE
+	Demand int
+	// allocated on the PE
+	Allocated int
+	// effective capacity of the PE
+	Capacity int
+}
+
+// Error : a description of the error
+func (e *CapacityError) Error() string {
+	name := e.ResourceName
+	if len(name) == 0 {
+		name = fmt.Sprintf("resource[%d]", e.Resource)
+	}
+	return fmt.Sprintf("placing LE %s on PE %s exceeds %s capacity: demand=%d; allocated=%d; capacity=%d",
+		e.LEID, e.PEID, name, e.Demand, e.Allocated, e.Capacity)
+}
+
+// MaxLEsError : placing an LE on a PE exceeds the maximum number of LEs hosted on the PE
+type MaxLEsError struct {
+	// ID of the PE
+	PEID string
+	// ID of the LE
+	LEID string
+	// maximum number of hosted LEs
+	MaxLEs int
+}
+
+// Error : a description of the error
+func (e *MaxLEsError) Error() string {
+	return fmt.Sprintf("placing LE %s on PE %s exceeds maximum of %d hosted LEs", e.LEID, e.PEID, e.MaxLEs)
+}
+
+// OvercommitCapacity : the effective capacity given overcommit ratios per resource,
+// i.e. the capacity of each resource multiplied by its ratio, rounded down
+//   - returns a copy of the capacity if no ratios or unequal lengths
+func OvercommitCapacity(capacity *util.Allocation, ratios []float64) *util.Allocation {
+	effective := capacity.Clone()
+	if len(ratios) != capacity.GetSize() {
+		return effective
+	}
+	value := effective.GetValue()
+	for i, r := range ratios {
+		value[i] = int(math.Floor(float64(value[i]) * r))
+	}
+	effective.SetValue(value)
+	return effective
+}
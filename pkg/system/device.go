@@ -0,0 +1,214 @@
+package system
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// DeviceSeparator : separator of the ID of a PE and the ID of a device within it
+const DeviceSeparator = "/"
+
+// Device : a device within a PE (e.g. socket, NUMA node, GPU), part of the device tree of the PE
+//   - leaf devices are PEs with their own resources and labels, members are placed on them
+//   - interior devices group the devices below them (e.g. GPUs sharing an NVSwitch)
+type Device struct {
+	// extends Entity
+	Entity
+	// devices below this one (none if leaf)
+	children []*Device
+	// the PE of a leaf device (nil if interior)
+	pe *PE
+}
+
+// NewDevice : create an interior device grouping given devices
+//   - returns nil if bad parameters
+func NewDevice(id string, children ...*Device) *Device {
+	if len(id) == 0 || len(children) == 0 {
+		return nil
+	}
+	for _, c := range children {
+		if c == nil {
+			return nil
+		}
+	}
+	return &Device{
+		Entity:   Entity{ID: id},
+		children: append([]*Device{}, children...),
+	}
+}
+
+// NewLeafDevice : create a leaf device given its PE
+//   - returns nil if bad parameters
+func NewLeafDevice(pe *PE) *Device {
+	if pe == nil {
+		return nil
+	}
+	return &Device{
+		Entity: Entity{ID: pe.GetID()},
+		pe:     pe,
+	}
+}
+
+// Clone : make a copy of the device tree rooted at this device, with copies of the PEs of leaf devices
+//   - returns nil if leaf devices host LEs
+func (d *Device) Clone() *Device {
+	if d.IsLeaf() {
+		pe := d.pe.Clone()
+		if pe == nil {
+			return nil
+		}
+		return &Device{Entity: Entity{ID: d.ID, labels: d.GetLabels()}, pe: pe}
+	}
+	children := make([]*Device, len(d.children))
+	for i, c := range d.children {
+		if children[i] = c.Clone(); children[i] == nil {
+			return nil
+		}
+	}
+	return &Device{Entity: Entity{ID: d.ID, labels: d.GetLabels()}, children: children}
+}
+
+// IsLeaf : is this a leaf device
+func (d *Device) IsLeaf() bool {
+	return d.pe != nil
+}
+
+// GetPE : the PE of a leaf device (nil if interior)
+func (d *Device) GetPE() *PE {
+	return d.pe
+}
+
+// GetChildren : the devices below this one, sorted by ID
+func (d *Device) GetChildren() []*Device {
+	children := append([]*Device{}, d.children...)
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].GetID() < children[j].GetID()
+	})
+	return children
+}
+
+// GetDepth : the depth of the device tree rooted at this device (zero if leaf), and false if leaves
+// are at different depths
+func (d *Device) GetDepth() (int, bool) {
+	if d.IsLeaf() {
+		return 0, true
+	}
+	depth := -1
+	for _, c := range d.children {
+		cd, ok := c.GetDepth()
+		if !ok || (depth >= 0 && cd+1 != depth) {
+			return 0, false
+		}
+		depth = cd + 1
+	}
+	return depth, true
+}
+
+// GetLeaves : the leaf devices in the device tree rooted at this device, sorted by ID
+func (d *Device) GetLeaves() []*Device {
+	if d.IsLeaf() {
+		return []*Device{d}
+	}
+	leaves := make([]*Device, 0)
+	for _, c := range d.GetChildren() {
+		leaves = append(leaves, c.GetLeaves()...)
+	}
+	return leaves
+}
+
+// DeviceID : the ID of a device within a PE, qualified by the ID of the PE (e.g. node-0/gpu-1)
+func DeviceID(peID string, deviceID string) string {
+	return peID + DeviceSeparator + deviceID
+}
+
+// GetDevices : the top devices of the device tree of this PE, sorted by ID (nil if none)
+func (pe *PE) GetDevices() []*Device {
+	if pe.devices == nil {
+		return nil
+	}
+	devices := append([]*Device{}, pe.devices...)
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].GetID() < devices[j].GetID()
+	})
+	return devices
+}
+
+// HasDevices : does this PE have a device tree
+func (pe *PE) HasDevices() bool {
+	return len(pe.devices) > 0
+}
+
+// GetDeviceDepth : the number of levels of the device tree of this PE (zero if none)
+func (pe *PE) GetDeviceDepth() int {
+	if len(pe.devices) == 0 {
+		return 0
+	}
+	depth, _ := pe.devices[0].GetDepth()
+	return depth + 1
+}
+
+// GetDevicePEs : the PEs of the leaf devices of this PE, sorted by ID (nil if no devices)
+func (pe *PE) GetDevicePEs() []*PE {
+	if len(pe.devices) == 0 {
+		return nil
+	}
+	pes := make([]*PE, 0)
+	for _, d := range pe.GetDevices() {
+		for _, leaf := range d.GetLeaves() {
+			pes = append(pes, leaf.pe)
+		}
+	}
+	return pes
+}
+
+// SetDevices : set the device tree of this PE given its top devices; nil to remove
+//   - the capacity of this PE is set to the total capacity of the leaf devices
+//   - returns error if leaves at different depths, duplicate IDs,
+//     or leaf devices with number of resources different from this PE
+func (pe *PE) SetDevices(devices []*Device) error {
+	if len(devices) == 0 {
+		pe.devices = nil
+		return nil
+	}
+	depth := -1
+	ids := make(map[string]bool)
+	capacity, _ := util.NewAllocation(pe.capacity.GetSize())
+	var visit func(d *Device) error
+	visit = func(d *Device) error {
+		if d == nil {
+			return fmt.Errorf("nil device in PE %s", pe.GetID())
+		}
+		if ids[d.GetID()] {
+			return fmt.Errorf("duplicate device %s in PE %s", d.GetID(), pe.GetID())
+		}
+		ids[d.GetID()] = true
+		if d.IsLeaf() {
+			if !d.pe.capacity.SameSize(capacity) {
+				return fmt.Errorf("device %s with number of resources different from PE %s", d.GetID(), pe.GetID())
+			}
+			capacity.Add(d.pe.capacity)
+			return nil
+		}
+		for _, c := range d.children {
+			if err := visit(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, d := range devices {
+		if err := visit(d); err != nil {
+			return err
+		}
+		dd, ok := d.GetDepth()
+		if !ok || (depth >= 0 && dd != depth) {
+			return fmt.Errorf("device leaves of PE %s at different depths", pe.GetID())
+		}
+		depth = dd
+	}
+	pe.devices = append([]*Device{}, devices...)
+	pe.capacity = capacity
+	return nil
+}
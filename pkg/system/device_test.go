@@ -0,0 +1,166 @@
+package system
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// makeGPU : make a leaf device with a PE of given resources
+func makeGPU(id string, value ...int) *Device {
+	capacity, _ := util.NewAllocationCopy(value)
+	return NewLeafDevice(NewPE(id, capacity))
+}
+
+func TestDevice_GetDepth(t *testing.T) {
+	tests := []struct {
+		name      string
+		device    *Device
+		wantDepth int
+		wantOk    bool
+	}{
+		{name: "leaf", device: makeGPU("gpu-0", 1), wantDepth: 0, wantOk: true},
+		{name: "switch of GPUs", device: NewDevice("nvswitch-0", makeGPU("gpu-0", 1), makeGPU("gpu-1", 1)),
+			wantDepth: 1, wantOk: true},
+		{name: "socket of switches",
+			device: NewDevice("socket-0",
+				NewDevice("nvswitch-0", makeGPU("gpu-0", 1)),
+				NewDevice("nvswitch-1", makeGPU("gpu-1", 1))),
+			wantDepth: 2, wantOk: true},
+		{name: "leaves at different depths",
+			device:    NewDevice("socket-0", NewDevice("nvswitch-0", makeGPU("gpu-0", 1)), makeGPU("gpu-1", 1)),
+			wantDepth: 0, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			depth, ok := tt.device.GetDepth()
+			if depth != tt.wantDepth || ok != tt.wantOk {
+				t.Errorf("GetDepth() = %d, %v, want %d, %v", depth, ok, tt.wantDepth, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestPE_SetDevices(t *testing.T) {
+	tests := []struct {
+		name         string
+		devices      []*Device
+		wantDepth    int
+		wantCapacity []int
+		wantErr      string
+	}{
+		{name: "no devices", devices: nil, wantDepth: 0, wantCapacity: []int{0, 0}},
+		{name: "GPUs", devices: []*Device{makeGPU("gpu-0", 1, 8), makeGPU("gpu-1", 1, 8)},
+			wantDepth: 1, wantCapacity: []int{2, 16}},
+		{name: "switches of GPUs",
+			devices: []*Device{
+				NewDevice("nvswitch-0", makeGPU("gpu-0", 1, 8), makeGPU("gpu-1", 1, 8)),
+				NewDevice("nvswitch-1", makeGPU("gpu-2", 1, 8))},
+			wantDepth: 2, wantCapacity: []int{3, 24}},
+		{name: "nil device", devices: []*Device{makeGPU("gpu-0", 1, 8), nil},
+			wantErr: "nil device"},
+		{name: "duplicate IDs",
+			devices: []*Device{NewDevice("nvswitch-0", makeGPU("gpu-0", 1, 8)), makeGPU("gpu-0", 1, 8)},
+			wantErr: "duplicate device gpu-0"},
+		{name: "different depths",
+			devices: []*Device{NewDevice("nvswitch-0", makeGPU("gpu-0", 1, 8)), makeGPU("gpu-1", 1, 8)},
+			wantErr: "different depths"},
+		{name: "different number of resources", devices: []*Device{makeGPU("gpu-0", 1)},
+			wantErr: "number of resources"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capacity, _ := util.NewAllocation(2)
+			pe := NewPE("node-0", capacity)
+			err := pe.SetDevices(tt.devices)
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("SetDevices() error = %v, want %q", err, tt.wantErr)
+				}
+				if pe.HasDevices() {
+					t.Errorf("SetDevices() kept devices after error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetDevices() error = %v", err)
+			}
+			if got := pe.GetDeviceDepth(); got != tt.wantDepth {
+				t.Errorf("GetDeviceDepth() = %d, want %d", got, tt.wantDepth)
+			}
+			got := pe.GetCapacity().GetValue()
+			if got[0] != tt.wantCapacity[0] || got[1] != tt.wantCapacity[1] {
+				t.Errorf("GetCapacity() = %v, want %v", got, tt.wantCapacity)
+			}
+		})
+	}
+}
+
+func TestPE_Clone(t *testing.T) {
+	capacity, _ := util.NewAllocationCopy([]int{4})
+	pe := NewPE("pe-0", capacity)
+	pe.SetLabel("zone", "a")
+	pe.AddTaint(Taint{Key: "gpu", Value: "a100"})
+	clone := pe.Clone()
+	if clone == nil || clone.GetID() != "pe-0" || clone.GetCapacity().GetValue()[0] != 4 {
+		t.Fatalf("Clone() = %v", clone)
+	}
+
+	// changes to the clone leave the PE unchanged
+	clone.SetState(Cordoned)
+	clone.AddTaint(Taint{Key: "maintenance"})
+	clone.SetLabel("zone", "b")
+	demand, _ := util.NewAllocationCopy([]int{1})
+	if !clone.PlaceLE(NewLE("le-0", demand)) {
+		t.Fatalf("PlaceLE() on clone failed")
+	}
+	if zone, _ := pe.GetLabel("zone"); pe.GetState() != Schedulable || len(pe.GetTaints()) != 1 ||
+		zone != "a" || pe.GetAllocated().GetValue()[0] != 0 {
+		t.Errorf("PE changed by clone: %s", pe.String())
+	}
+
+	// a PE hosting LEs is not cloned
+	if clone.Clone() != nil {
+		t.Errorf("Clone() of PE hosting LEs not nil")
+	}
+}
+
+func TestPE_Clone_Devices(t *testing.T) {
+	capacity, _ := util.NewAllocationCopy([]int{0})
+	pe := NewPE("pe-0", capacity)
+	if err := pe.SetDevices([]*Device{NewDevice("nvswitch-0", makeGPU("gpu-0", 1), makeGPU("gpu-1", 1)),
+		NewDevice("nvswitch-1", makeGPU("gpu-2", 1))}); err != nil {
+		t.Fatalf("SetDevices() error = %v", err)
+	}
+	clone := pe.Clone()
+	if clone == nil || clone.GetDeviceDepth() != 2 || len(clone.GetDevicePEs()) != 3 {
+		t.Fatalf("Clone() = %v", clone)
+	}
+
+	// the device tree of the clone is a copy
+	original, copied := pe.GetDevicePEs(), clone.GetDevicePEs()
+	for i := range copied {
+		if copied[i] == original[i] || copied[i].GetID() != original[i].GetID() {
+			t.Errorf("clone device %s shares PE with %s", copied[i].GetID(), original[i].GetID())
+		}
+	}
+	demand, _ := util.NewAllocationCopy([]int{1})
+	if err := copied[0].TryPlaceLE(NewLE("le-0", demand)); err != nil {
+		t.Fatalf("TryPlaceLE() on clone device error = %v", err)
+	}
+	if len(original[0].GetHostedIDs()) != 0 {
+		t.Errorf("original device %s hosting %v", original[0].GetID(), original[0].GetHostedIDs())
+	}
+	if clone.SetDevices(nil); !pe.HasDevices() {
+		t.Errorf("original PE lost its devices with the clone")
+	}
+
+	// a PE with devices hosting LEs is not cloned
+	if err := original[2].TryPlaceLE(NewLE("le-1", demand)); err != nil {
+		t.Fatalf("TryPlaceLE() error = %v", err)
+	}
+	if pe.Clone() != nil {
+		t.Errorf("Clone() of PE with device hosting LEs not nil")
+	}
+}
@@ -1,15 +1,63 @@
 package system
 
+import "sort"
+
 // Entity : basic element unit
 type Entity struct {
 	// unique id
 	ID string
-
-	// TODO: add common attributes
-
+	// labels (key value attributes)
+	labels map[string]string
 }
 
 // GetID : the unique ID
 func (e *Entity) GetID() string {
 	return e.ID
 }
+
+// SetLabel : set the value of a label, replacing an existing value;
+// returns false if the key is empty
+func (e *Entity) SetLabel(key string, value string) bool {
+	if len(key) == 0 {
+		return false
+	}
+	if e.labels == nil {
+		e.labels = make(map[string]string)
+	}
+	e.labels[key] = value
+	return true
+}
+
+// GetLabel : get the value of a label and whether it exists
+func (e *Entity) GetLabel(key string) (string, bool) {
+	value, exists := e.labels[key]
+	return value, exists
+}
+
+// RemoveLabel : remove a label; returns false if not found
+func (e *Entity) RemoveLabel(key string) bool {
+	if _, exists := e.labels[key]; !exists {
+		return false
+	}
+	delete(e.labels, key)
+	return true
+}
+
+// GetLabels : get a copy of the labels
+func (e *Entity) GetLabels() map[string]string {
+	labels := make(map[string]string, len(e.labels))
+	for k, v := range e.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// GetLabelKeys : get the keys of the labels, sorted
+func (e *Entity) GetLabelKeys() []string {
+	keys := make([]string, 0, len(e.labels))
+	for k := range e.labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -14,6 +14,8 @@ type LE struct {
 	demand *util.Allocation
 	// hosting LE (nil if not hosted)
 	host *PE
+	// ID of the group of this LE (empty if not in a group)
+	groupID string
 }
 
 // NewLE : create a new LE
@@ -41,6 +43,17 @@ func (le *LE) SetHost(pe *PE) {
 	le.host = pe
 }
 
+// GetGroupID : get the ID of the group of this LE
+//   - empty if not in a group
+func (le *LE) GetGroupID() string {
+	return le.groupID
+}
+
+// SetGroupID : set the ID of the group of this LE
+func (le *LE) SetGroupID(groupID string) {
+	le.groupID = groupID
+}
+
 // UNDONE:
 
 // String : a print out of the LE
@@ -40,15 +40,18 @@ func (leg *LEGroup) AddLE(le *LE) bool {
 		return false
 	}
 	leg.group[le.GetID()] = le
+	le.SetGroupID(leg.GetID())
 	leg.size++
 	return true
 }
 
 // RemoveLE : remove an LE from this group
 func (leg *LEGroup) RemoveLE(leID string) bool {
-	if _, exists := leg.group[leID]; !exists {
+	le, exists := leg.group[leID]
+	if !exists {
 		return false
 	}
+	le.SetGroupID("")
 	delete(leg.group, leID)
 	leg.size--
 	return true
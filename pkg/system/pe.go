@@ -17,6 +17,22 @@ type PE struct {
 	allocated *util.Allocation
 	// hosted LEs
 	hosted map[string]*LE
+	// scheduling state
+	state PEState
+	// taints, keyed by taint key
+	taints map[string]Taint
+	// reject placement of LEs exceeding the effective capacity
+	strict bool
+	// overcommit ratios of resources (nil if none)
+	overcommit []float64
+	// resources reserved for the system, not available to LEs (nil if none)
+	reserved *util.Allocation
+	// top devices of the device tree within the PE (nil if none)
+	devices []*Device
+	// maximum number of hosted LEs, over all groups (zero if unlimited)
+	maxLEs int
+	// names of resources (nil if unknown)
+	resourceNames []string
 }
 
 // NewPE : create a new PE
@@ -33,14 +49,230 @@ func NewPE(id string, capacity *util.Allocation) *PE {
 		capacity:  capacity.Clone(),
 		allocated: allocated,
 		hosted:    make(map[string]*LE),
+		state:     Schedulable,
+		taints:    make(map[string]Taint),
+		strict:    true,
 	}
 }
 
+// Clone : make a copy of this PE, with copies of its resources, labels, taints, and device tree
+//   - returns nil if it or its leaf devices host LEs
+func (pe *PE) Clone() *PE {
+	if len(pe.hosted) > 0 {
+		return nil
+	}
+	var devices []*Device
+	if pe.devices != nil {
+		devices = make([]*Device, len(pe.devices))
+		for i, d := range pe.devices {
+			if devices[i] = d.Clone(); devices[i] == nil {
+				return nil
+			}
+		}
+	}
+	clone := &PE{
+		Entity:    Entity{ID: pe.ID, labels: pe.GetLabels()},
+		capacity:  pe.capacity.Clone(),
+		allocated: pe.allocated.Clone(),
+		hosted:    make(map[string]*LE),
+		state:     pe.state,
+		taints:    make(map[string]Taint, len(pe.taints)),
+		strict:    pe.strict,
+		devices:   devices,
+		maxLEs:    pe.maxLEs,
+	}
+	if pe.resourceNames != nil {
+		clone.resourceNames = append([]string{}, pe.resourceNames...)
+	}
+	for key, taint := range pe.taints {
+		clone.taints[key] = taint
+	}
+	if pe.overcommit != nil {
+		clone.overcommit = append([]float64{}, pe.overcommit...)
+	}
+	if pe.reserved != nil {
+		clone.reserved = pe.reserved.Clone()
+	}
+	return clone
+}
+
+// GetResourceNames : get the names of resources (nil if unknown)
+func (pe *PE) GetResourceNames() []string {
+	return pe.resourceNames
+}
+
+// SetResourceNames : set the names of resources, naming the resource of capacity errors; nil if unknown
+//   - returns false if the number of names differs from the number of resources
+func (pe *PE) SetResourceNames(resourceNames []string) bool {
+	if resourceNames == nil {
+		pe.resourceNames = nil
+		return true
+	}
+	if len(resourceNames) != pe.capacity.GetSize() {
+		return false
+	}
+	pe.resourceNames = append([]string{}, resourceNames...)
+	return true
+}
+
+// IsStrict : placement of LEs exceeding the effective capacity is rejected
+func (pe *PE) IsStrict() bool {
+	return pe.strict
+}
+
+// SetStrict : set the strict mode (the default), where placement of LEs exceeding
+// the effective capacity is rejected, otherwise resource overflow is allowed
+func (pe *PE) SetStrict(strict bool) {
+	pe.strict = strict
+}
+
+// GetOvercommit : get the overcommit ratios of resources (nil if none)
+func (pe *PE) GetOvercommit() []float64 {
+	return pe.overcommit
+}
+
+// SetOvercommit : set the overcommit ratios of resources, e.g. [4, 1] for cpu oversubscribed 4x
+// and memory not oversubscribed; nil to remove
+//   - returns false if length different from capacity or a non-positive ratio
+func (pe *PE) SetOvercommit(ratios []float64) bool {
+	if ratios == nil {
+		pe.overcommit = nil
+		return true
+	}
+	if len(ratios) != pe.capacity.GetSize() {
+		return false
+	}
+	for _, r := range ratios {
+		if r <= 0 {
+			return false
+		}
+	}
+	pe.overcommit = append([]float64{}, ratios...)
+	return true
+}
+
+// GetReserved : get resources reserved for the system (nil if none)
+func (pe *PE) GetReserved() *util.Allocation {
+	return pe.reserved
+}
+
+// SetReserved : set resources reserved for the system, not available to LEs; nil to remove
+//   - makes a copy of reserved
+//   - returns false if length of reserved different from capacity
+func (pe *PE) SetReserved(reserved *util.Allocation) bool {
+	if reserved == nil {
+		pe.reserved = nil
+		return true
+	}
+	if !pe.capacity.SameSize(reserved) {
+		return false
+	}
+	pe.reserved = reserved.Clone()
+	return true
+}
+
+// GetEffectiveCapacity : get resource capacity multiplied by the overcommit ratios,
+// less the reserved resources (not below zero)
+func (pe *PE) GetEffectiveCapacity() *util.Allocation {
+	effective := OvercommitCapacity(pe.capacity, pe.overcommit)
+	if pe.reserved != nil {
+		value := effective.GetValue()
+		reserved := pe.reserved.GetValue()
+		for i := range value {
+			value[i] = util.Max(value[i]-reserved[i], 0)
+		}
+		effective.SetValue(value)
+	}
+	return effective
+}
+
+// GetMaxLEs : get the maximum number of hosted LEs, over all groups (zero if unlimited)
+func (pe *PE) GetMaxLEs() int {
+	return pe.maxLEs
+}
+
+// SetMaxLEs : set the maximum number of hosted LEs, over all groups, e.g. max pods on a node;
+// zero for unlimited
+//   - returns false if negative
+func (pe *PE) SetMaxLEs(maxLEs int) bool {
+	if maxLEs < 0 {
+		return false
+	}
+	pe.maxLEs = maxLEs
+	return true
+}
+
+// NumberToFit : number of LEs of a given demand that can fit on this PE, given the effective capacity
+// and the maximum number of hosted LEs
+func (pe *PE) NumberToFit(demand *util.Allocation) int {
+	numFit := demand.NumberToFit(pe.allocated, pe.GetEffectiveCapacity())
+	if pe.maxLEs > 0 {
+		numFit = util.Min(numFit, util.Max(pe.maxLEs-len(pe.hosted), 0))
+	}
+	return numFit
+}
+
+// GetState : get the scheduling state
+func (pe *PE) GetState() PEState {
+	return pe.state
+}
+
+// SetState : set the scheduling state
+func (pe *PE) SetState(state PEState) {
+	pe.state = state
+}
+
+// IsSchedulable : new LEs may be placed on this PE
+func (pe *PE) IsSchedulable() bool {
+	return pe.state == Schedulable
+}
+
+// AddTaint : add a taint, replacing a taint with the same key
+func (pe *PE) AddTaint(taint Taint) bool {
+	if len(taint.Key) == 0 {
+		return false
+	}
+	pe.taints[taint.Key] = taint
+	return true
+}
+
+// RemoveTaint : remove the taint with a given key
+func (pe *PE) RemoveTaint(key string) bool {
+	if _, exists := pe.taints[key]; !exists {
+		return false
+	}
+	delete(pe.taints, key)
+	return true
+}
+
+// GetTaints : a list of the taints, sorted by key
+func (pe *PE) GetTaints() []Taint {
+	taints := make([]Taint, 0, len(pe.taints))
+	for _, taint := range pe.taints {
+		taints = append(taints, taint)
+	}
+	sort.Slice(taints, func(i, j int) bool {
+		return taints[i].Key < taints[j].Key
+	})
+	return taints
+}
+
 // GetCapacity : get resource capacity
 func (pe *PE) GetCapacity() *util.Allocation {
 	return pe.capacity
 }
 
+// SetCapacity : set resource capacity
+//   - makes a copy of resource capacity
+//   - returns false if length of capacity different from current one
+func (pe *PE) SetCapacity(capacity *util.Allocation) bool {
+	if !pe.capacity.SameSize(capacity) {
+		return false
+	}
+	pe.capacity = capacity.Clone()
+	return true
+}
+
 // GetAllocated : get resource allocated
 func (pe *PE) GetAllocated() *util.Allocation {
 	return pe.allocated
@@ -63,35 +295,126 @@ func (pe *PE) AddAllocated(allocated *util.Allocation) {
 }
 
 // PlaceLE : place an LE on this PE
-//   - assuming allowed resource overflow
+//   - resource overflow allowed only if not strict
 func (pe *PE) PlaceLE(le *LE) bool {
+	return pe.TryPlaceLE(le) == nil
+}
+
+// TryPlaceLE : place an LE on this PE
+//   - returns a CapacityError if strict and the LE exceeds the effective capacity of a resource
+//   - returns a MaxLEsError if the maximum number of hosted LEs is reached
+//   - returns error if bad LE or already hosted
+func (pe *PE) TryPlaceLE(le *LE) error {
 	if le == nil || le.demand == nil || !le.demand.SameSize(pe.capacity) {
-		return false
+		return fmt.Errorf("invalid LE for PE %s", pe.GetID())
 	}
 	leID := le.GetID()
 	if _, exists := pe.hosted[leID]; exists {
-		return false
+		return fmt.Errorf("LE %s already hosted on PE %s", leID, pe.GetID())
+	}
+	if pe.maxLEs > 0 && len(pe.hosted) >= pe.maxLEs {
+		return &MaxLEsError{PEID: pe.GetID(), LEID: leID, MaxLEs: pe.maxLEs}
+	}
+	if pe.strict {
+		demand := le.demand.GetValue()
+		allocated := pe.allocated.GetValue()
+		capacity := pe.GetEffectiveCapacity().GetValue()
+		for i := range demand {
+			if demand[i] > 0 && demand[i] > capacity[i]-allocated[i] {
+				e := &CapacityError{PEID: pe.GetID(), LEID: leID, Resource: i,
+					Demand: demand[i], Allocated: allocated[i], Capacity: capacity[i]}
+				if pe.resourceNames != nil {
+					e.ResourceName = pe.resourceNames[i]
+				}
+				return e
+			}
+		}
+	}
+	if err := pe.allocated.AddChecked(le.demand); err != nil {
+		return fmt.Errorf("placing LE %s on PE %s: %s", leID, pe.GetID(), err.Error())
 	}
-	pe.allocated.Add(le.demand)
 	pe.hosted[leID] = le
 	le.SetHost(pe)
-	pe.hosted[leID] = le
-	return true
+	return nil
 }
 
 // UnPlaceLE : unplace an LE from this PE
+//   - allocated resources are kept non-negative
 func (pe *PE) UnPlaceLE(le *LE) bool {
+	unplaced, _ := pe.unPlaceLE(le)
+	return unplaced
+}
+
+// TryUnPlaceLE : unplace an LE from this PE
+//   - returns error if bad LE or not hosted
+//   - returns error if the demand of the LE exceeds the allocated resources,
+//     in which case the LE is unplaced and the exceeded allocated resources are set to zero
+func (pe *PE) TryUnPlaceLE(le *LE) error {
+	_, err := pe.unPlaceLE(le)
+	return err
+}
+
+// unPlaceLE : unplace an LE from this PE, returning true if unplaced
+// and error if bad LE, not hosted, or allocated resources underflow
+func (pe *PE) unPlaceLE(le *LE) (bool, error) {
 	if le == nil || le.demand == nil || !le.demand.SameSize(pe.capacity) {
-		return false
+		return false, fmt.Errorf("invalid LE for PE %s", pe.GetID())
 	}
 	leID := le.GetID()
 	if _, exists := pe.hosted[leID]; !exists {
-		return false
+		return false, fmt.Errorf("LE %s not hosted on PE %s", leID, pe.GetID())
+	}
+	err := pe.allocated.SubtractChecked(le.demand)
+	if err != nil {
+		allocated := pe.allocated.GetValue()
+		for i, d := range le.demand.GetValue() {
+			allocated[i] = util.Max(allocated[i]-d, 0)
+		}
+		err = fmt.Errorf("unplacing LE %s from PE %s: %s", leID, pe.GetID(), err.Error())
 	}
-	pe.allocated.Subtract(le.demand)
 	delete(pe.hosted, leID)
 	le.SetHost(nil)
-	return true
+	return true, err
+}
+
+// Validate : check the consistency of the resources of this PE
+//   - capacity, allocated, and reserved have the same length and no negative values
+//   - allocated covers the demand of the hosted LEs
+//   - allocated does not exceed the effective capacity if strict
+func (pe *PE) Validate() error {
+	n := pe.capacity.GetSize()
+	if pe.allocated == nil || pe.allocated.GetSize() != n {
+		return fmt.Errorf("PE %s: allocated length differs from capacity", pe.GetID())
+	}
+	if pe.reserved != nil && pe.reserved.GetSize() != n {
+		return fmt.Errorf("PE %s: reserved length differs from capacity", pe.GetID())
+	}
+	if err := pe.capacity.Validate(); err != nil {
+		return fmt.Errorf("PE %s: capacity %s", pe.GetID(), err.Error())
+	}
+	if err := pe.allocated.Validate(); err != nil {
+		return fmt.Errorf("PE %s: allocated %s", pe.GetID(), err.Error())
+	}
+	if pe.reserved != nil {
+		if err := pe.reserved.Validate(); err != nil {
+			return fmt.Errorf("PE %s: reserved %s", pe.GetID(), err.Error())
+		}
+	}
+	hosted, _ := util.NewAllocation(n)
+	for _, le := range pe.hosted {
+		if err := hosted.AddChecked(le.demand); err != nil {
+			return fmt.Errorf("PE %s: demand of LE %s: %s", pe.GetID(), le.GetID(), err.Error())
+		}
+	}
+	if !hosted.LessOrEqual(pe.allocated) {
+		return fmt.Errorf("PE %s: allocated %v less than demand %v of hosted LEs", pe.GetID(),
+			pe.allocated, hosted)
+	}
+	if pe.strict && !pe.allocated.LessOrEqual(pe.GetEffectiveCapacity()) {
+		return fmt.Errorf("PE %s: allocated %v exceeds effective capacity %v", pe.GetID(),
+			pe.allocated, pe.GetEffectiveCapacity())
+	}
+	return nil
 }
 
 // GetHostedIDs : a sorted list of IDs of all LEs hosted by this PE
@@ -106,12 +429,32 @@ func (pe *PE) GetHostedIDs() []string {
 	return ids
 }
 
+// GetHostedLEs : a list of all LEs hosted by this PE, sorted by ID
+func (pe *PE) GetHostedLEs() []*LE {
+	ids := pe.GetHostedIDs()
+	les := make([]*LE, len(ids))
+	for i, leID := range ids {
+		les[i] = pe.hosted[leID]
+	}
+	return les
+}
+
 // UNDONE:
 
 // TODO: add host add/remove LE
 
 // String : a print out of the PE
 func (pe *PE) String() string {
-	return fmt.Sprintf("PE: ID=%s; cap=%v; alloc=%v; hosted=%v", pe.GetID(), pe.capacity, pe.allocated,
+	s := fmt.Sprintf("PE: ID=%s; cap=%v; alloc=%v; hosted=%v", pe.GetID(), pe.capacity, pe.allocated,
 		pe.GetHostedIDs())
+	if pe.maxLEs > 0 {
+		s += fmt.Sprintf("; maxLEs=%d", pe.maxLEs)
+	}
+	if pe.state != Schedulable {
+		s += fmt.Sprintf("; state=%s", PEStateToString(pe.state))
+	}
+	if len(pe.taints) > 0 {
+		s += fmt.Sprintf("; taints=%v", pe.GetTaints())
+	}
+	return s
 }
@@ -0,0 +1,185 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// makePE : make a PE of given resource capacity
+func makePE(id string, value ...int) *PE {
+	capacity, _ := util.NewAllocationCopy(value)
+	return NewPE(id, capacity)
+}
+
+// makeLE : make an LE of given resource demand
+func makeLE(id string, value ...int) *LE {
+	demand, _ := util.NewAllocationCopy(value)
+	return NewLE(id, demand)
+}
+
+func TestPE_TryPlaceLE(t *testing.T) {
+	tests := []struct {
+		name string
+		// change to the PE hosting le-0 of demand [2, 2]
+		change        func(pe *PE)
+		le            *LE
+		wantErr       string
+		wantCapacity  bool
+		wantAllocated []int
+	}{
+		{name: "fits", change: func(pe *PE) {}, le: makeLE("le-1", 2, 2), wantAllocated: []int{4, 4}},
+		{name: "exceeds capacity if strict", change: func(pe *PE) {}, le: makeLE("le-1", 2, 3),
+			wantErr: "exceeds resource[1] capacity", wantCapacity: true, wantAllocated: []int{2, 2}},
+		{name: "exceeds named capacity", change: func(pe *PE) { pe.SetResourceNames([]string{"cpu", "memory"}) },
+			le: makeLE("le-1", 2, 3), wantErr: "exceeds memory capacity", wantCapacity: true,
+			wantAllocated: []int{2, 2}},
+		{name: "overflows if not strict", change: func(pe *PE) { pe.SetStrict(false) }, le: makeLE("le-1", 2, 3),
+			wantAllocated: []int{4, 5}},
+		{name: "fits overcommitted capacity", change: func(pe *PE) { pe.SetOvercommit([]float64{1, 2}) },
+			le: makeLE("le-1", 2, 3), wantAllocated: []int{4, 5}},
+		{name: "exceeds capacity less reserved",
+			change: func(pe *PE) {
+				reserved, _ := util.NewAllocationCopy([]int{1, 0})
+				pe.SetReserved(reserved)
+			},
+			le: makeLE("le-1", 2, 2), wantErr: "exceeds resource[0] capacity", wantCapacity: true,
+			wantAllocated: []int{2, 2}},
+		{name: "already hosted", change: func(pe *PE) {}, le: makeLE("le-0", 1, 1), wantErr: "already hosted",
+			wantAllocated: []int{2, 2}},
+		{name: "different number of resources", change: func(pe *PE) {}, le: makeLE("le-1", 1),
+			wantErr: "invalid LE", wantAllocated: []int{2, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pe := makePE("pe-0", 4, 4)
+			if err := pe.TryPlaceLE(makeLE("le-0", 2, 2)); err != nil {
+				t.Fatalf("TryPlaceLE() error = %v", err)
+			}
+			tt.change(pe)
+			err := pe.TryPlaceLE(tt.le)
+			if len(tt.wantErr) == 0 && err != nil {
+				t.Errorf("TryPlaceLE() error = %v", err)
+			}
+			if len(tt.wantErr) > 0 && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("TryPlaceLE() error = %v, want %q", err, tt.wantErr)
+			}
+			var capacityErr *CapacityError
+			if errors.As(err, &capacityErr) != tt.wantCapacity {
+				t.Errorf("TryPlaceLE() error = %v, want capacity error %v", err, tt.wantCapacity)
+			}
+			if got := pe.GetAllocated().GetValue(); got[0] != tt.wantAllocated[0] || got[1] != tt.wantAllocated[1] {
+				t.Errorf("allocated = %v, want %v", got, tt.wantAllocated)
+			}
+			if hosted := tt.le.GetHost() == pe; hosted != (len(tt.wantErr) == 0) {
+				t.Errorf("LE hosted %v, error = %v", hosted, err)
+			}
+		})
+	}
+}
+
+func TestPE_PlaceLE_Strict(t *testing.T) {
+	pe := makePE("pe-0", 4)
+	if !pe.PlaceLE(makeLE("le-0", 3)) {
+		t.Fatalf("PlaceLE() = false, want true")
+	}
+	if pe.PlaceLE(makeLE("le-1", 2)) {
+		t.Errorf("PlaceLE() exceeding capacity = true, want false")
+	}
+	if got := pe.GetHostedIDs(); len(got) != 1 {
+		t.Errorf("GetHostedIDs() = %v, want [le-0]", got)
+	}
+	if err := pe.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestPE_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		// change to the PE of capacity 4 hosting an LE of demand 2
+		change  func(pe *PE)
+		wantErr string
+	}{
+		{name: "valid", change: func(pe *PE) {}},
+		{name: "allocated exceeds capacity if strict",
+			change: func(pe *PE) {
+				allocated, _ := util.NewAllocationCopy([]int{5})
+				pe.SetAllocated(allocated)
+			},
+			wantErr: "exceeds effective capacity"},
+		{name: "allocated exceeds capacity if not strict",
+			change: func(pe *PE) {
+				pe.SetStrict(false)
+				allocated, _ := util.NewAllocationCopy([]int{5})
+				pe.SetAllocated(allocated)
+			}},
+		{name: "allocated less than hosted demand",
+			change: func(pe *PE) {
+				allocated, _ := util.NewAllocationCopy([]int{1})
+				pe.SetAllocated(allocated)
+			},
+			wantErr: "less than demand"},
+		{name: "negative allocated",
+			change: func(pe *PE) {
+				pe.UnPlaceLE(pe.GetHostedLEs()[0])
+				allocated, _ := util.NewAllocationCopy([]int{-1})
+				pe.SetAllocated(allocated)
+			},
+			wantErr: "allocated element 0 has negative value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pe := makePE("pe-0", 4)
+			if err := pe.TryPlaceLE(makeLE("le-0", 2)); err != nil {
+				t.Fatalf("TryPlaceLE() error = %v", err)
+			}
+			tt.change(pe)
+			err := pe.Validate()
+			if len(tt.wantErr) == 0 && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if len(tt.wantErr) > 0 && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPE_NumberToFit(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxLEs  int
+		numLEs  int
+		wantFit int
+	}{
+		{name: "unlimited", maxLEs: 0, numLEs: 1, wantFit: 7},
+		{name: "limited by maximum LEs", maxLEs: 3, numLEs: 1, wantFit: 2},
+		{name: "limited by capacity", maxLEs: 10, numLEs: 1, wantFit: 7},
+		{name: "maximum LEs reached", maxLEs: 2, numLEs: 2, wantFit: 0},
+		{name: "maximum LEs below hosted", maxLEs: 1, numLEs: 2, wantFit: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pe := makePE("pe-0", 8)
+			for i := 0; i < tt.numLEs; i++ {
+				if err := pe.TryPlaceLE(makeLE(fmt.Sprintf("le-%d", i), 1)); err != nil {
+					t.Fatalf("TryPlaceLE() error = %v", err)
+				}
+			}
+			if !pe.SetMaxLEs(tt.maxLEs) {
+				t.Fatalf("SetMaxLEs(%d) = false", tt.maxLEs)
+			}
+			demand, _ := util.NewAllocationCopy([]int{1})
+			if got := pe.NumberToFit(demand); got != tt.wantFit {
+				t.Errorf("NumberToFit() = %d, want %d", got, tt.wantFit)
+			}
+		})
+	}
+	if makePE("pe-0", 8).SetMaxLEs(-1) {
+		t.Errorf("SetMaxLEs(-1) = true, want false")
+	}
+}
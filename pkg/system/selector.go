@@ -0,0 +1,127 @@
+package system
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SelectorOperator : the operator of a selector on labels
+type SelectorOperator int
+
+const (
+	// label value equals the single value
+	Equals SelectorOperator = iota
+	// label value is one of the values
+	In
+	// label missing or its value is none of the values
+	NotIn
+	// label exists, with any value
+	Exists
+)
+
+// SelectorOperatorToString : get the string representation of a selector operator
+func SelectorOperatorToString(op SelectorOperator) string {
+	switch op {
+	case Equals:
+		return "Equals"
+	case In:
+		return "In"
+	case NotIn:
+		return "NotIn"
+	case Exists:
+		return "Exists"
+	}
+	return "Unknown"
+}
+
+// StringToSelectorOperator : get the selector operator given its string representation (case insensitive)
+func StringToSelectorOperator(s string) (SelectorOperator, bool) {
+	for _, op := range []SelectorOperator{Equals, In, NotIn, Exists} {
+		if strings.EqualFold(s, SelectorOperatorToString(op)) {
+			return op, true
+		}
+	}
+	return Equals, false
+}
+
+// Selector : an expression on the value of a label, e.g. gpu=a100 or network in (ib, roce)
+type Selector struct {
+	Key      string
+	Operator SelectorOperator
+	Values   []string
+}
+
+// NewSelector : create a selector
+//   - returns nil if bad parameters: empty key, Equals with other than one value,
+//     In and NotIn with no values, or Exists with values
+func NewSelector(key string, op SelectorOperator, values ...string) *Selector {
+	if len(key) == 0 {
+		return nil
+	}
+	switch op {
+	case Equals:
+		if len(values) != 1 {
+			return nil
+		}
+	case In, NotIn:
+		if len(values) == 0 {
+			return nil
+		}
+	case Exists:
+		if len(values) > 0 {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return &Selector{
+		Key:      key,
+		Operator: op,
+		Values:   append([]string{}, values...),
+	}
+}
+
+// Matches : check if labels satisfy the selector
+func (s *Selector) Matches(labels map[string]string) bool {
+	value, exists := labels[s.Key]
+	switch s.Operator {
+	case Equals, In:
+		return exists && s.hasValue(value)
+	case NotIn:
+		return !exists || !s.hasValue(value)
+	case Exists:
+		return exists
+	}
+	return false
+}
+
+// hasValue : check if a value is one of the values of the selector
+func (s *Selector) hasValue(value string) bool {
+	for _, v := range s.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// String : a print out of the selector
+func (s *Selector) String() string {
+	switch s.Operator {
+	case Equals:
+		return fmt.Sprintf("%s=%s", s.Key, strings.Join(s.Values, ""))
+	case Exists:
+		return s.Key
+	}
+	return fmt.Sprintf("%s %s (%s)", s.Key, SelectorOperatorToString(s.Operator), strings.Join(s.Values, ","))
+}
+
+// MatchesAll : check if labels satisfy all selectors
+func MatchesAll(selectors []*Selector, labels map[string]string) bool {
+	for _, s := range selectors {
+		if !s.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package system
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PEState : the scheduling state of a PE
+type PEState int
+
+const (
+	// new LEs may be placed on the PE
+	Schedulable PEState = iota
+	// no new LEs placed on the PE, hosted LEs keep running
+	Cordoned
+	// no new LEs placed on the PE, hosted LEs are to be moved
+	Draining
+	// PE failed, hosted LEs are lost
+	Failed
+)
+
+// PEStateToString : get the string representation of a PE state
+func PEStateToString(s PEState) string {
+	switch s {
+	case Schedulable:
+		return "Schedulable"
+	case Cordoned:
+		return "Cordoned"
+	case Draining:
+		return "Draining"
+	case Failed:
+		return "Failed"
+	}
+	return "Unknown"
+}
+
+// StringToPEState : get the PE state given its string representation (case insensitive)
+func StringToPEState(s string) (PEState, bool) {
+	for _, state := range []PEState{Schedulable, Cordoned, Draining, Failed} {
+		if strings.EqualFold(s, PEStateToString(state)) {
+			return state, true
+		}
+	}
+	return Schedulable, false
+}
+
+// Taint : a key value pair marking a PE, such that only LEs of groups tolerating it are placed on the PE
+type Taint struct {
+	Key   string
+	Value string
+}
+
+// String : a print out of the taint
+func (t Taint) String() string {
+	return fmt.Sprintf("%s=%s", t.Key, t.Value)
+}
+
+// Toleration : tolerate taints with a given key, and a given value unless any value is tolerated
+//   - an empty key with any value tolerates all taints
+type Toleration struct {
+	Key   string
+	Value string
+	// tolerate any value of the key
+	AnyValue bool
+}
+
+// Tolerates : check if the toleration tolerates a taint
+func (tol Toleration) Tolerates(taint Taint) bool {
+	if len(tol.Key) == 0 {
+		return tol.AnyValue
+	}
+	return tol.Key == taint.Key && (tol.AnyValue || tol.Value == taint.Value)
+}
+
+// ToleratesAll : check if all taints are tolerated by some toleration
+func ToleratesAll(tolerations []Toleration, taints []Taint) bool {
+	for _, taint := range taints {
+		tolerated := false
+		for _, tol := range tolerations {
+			if tolerated = tol.Tolerates(taint); tolerated {
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,207 @@
+package topology
+
+import (
+	"fmt"
+	"strconv"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/system"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// HasDevices : do PEs of the tree have device trees
+func (pTree *PTree) HasDevices() bool {
+	if pTree.root == nil || (*PNode)(unsafe.Pointer(pTree.root)).GetNumResources() == 0 {
+		return false
+	}
+	for _, pe := range pTree.GetPEs() {
+		if pe.HasDevices() {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandDevices : create a tree where PEs with device trees are interior nodes and their
+// leaf devices are the PEs, so that members are placed on, and bound to, individual devices
+//   - device levels are added below the levels of the tree, named by the given device level names
+//     (default names if nil), as many as the largest device depth
+//   - PEs without devices stay leaves, and device trees of smaller depth hang from their PE,
+//     padded to the leaf level by nodes with IDs of the PE qualified by the level name (e.g. node-6/nvswitch)
+//   - nodes are copied with their labels, reservations, and maximum allocated; PEs without devices
+//     and interior devices by reference
+//   - leaf devices are copies of their PEs, with the state and taints of their PE; LEs are placed
+//     on the copies, so the device PEs of this tree are never updated by placements on the expanded tree
+//   - returns error if no devices, device IDs already in the tree, leaf devices hosting LEs,
+//     or number of device level names different from the device depth
+func (pTree *PTree) ExpandDevices(deviceLevelNames []string) (*PTree, error) {
+	if !pTree.HasDevices() {
+		return nil, fmt.Errorf("no devices in tree")
+	}
+	depth := 0
+	for _, pe := range pTree.GetPEs() {
+		if d := pe.GetDeviceDepth(); d > depth {
+			depth = d
+		}
+	}
+	if deviceLevelNames != nil && len(deviceLevelNames) != depth {
+		return nil, fmt.Errorf("%d device level names, expected %d", len(deviceLevelNames), depth)
+	}
+	var levelNames []string
+	if len(pTree.levelNames) > 0 {
+		if deviceLevelNames == nil {
+			return nil, fmt.Errorf("missing device level names")
+		}
+		levelNames = append(append([]string{}, pTree.levelNames...), deviceLevelNames...)
+	}
+	e := newDeviceExpander(pTree, levelNames, depth)
+	pRoot, err := e.copyNode(pTree.root)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := NewPTree(NewTree((*Node)(unsafe.Pointer(pRoot))))
+	if pTree.resourceNames != nil {
+		expanded.SetResourceNames(pTree.resourceNames)
+	}
+	expanded.overcommit = pTree.overcommit
+	expanded.maxLEs = pTree.maxLEs
+	expanded.levelNames = levelNames
+	expanded.deviceDepth = depth
+	expanded.SetNodeLevels()
+	expanded.PercolateResources()
+	return expanded, nil
+}
+
+// deviceExpander : copies nodes of a tree, expanding PEs into their devices
+type deviceExpander struct {
+	// names of levels of the expanded tree, from the top down (nil if default names)
+	levelNames []string
+	// number of device levels
+	depth int
+	// number of resources of nodes
+	numResources int
+	// IDs of nodes in the expanded tree
+	ids map[string]bool
+}
+
+// newDeviceExpander : create an expander of nodes into a tree with given level names
+// and number of device levels, with IDs of nodes already in a tree
+func newDeviceExpander(pTree *PTree, levelNames []string, depth int) *deviceExpander {
+	e := &deviceExpander{
+		levelNames:   levelNames,
+		depth:        depth,
+		numResources: (*PNode)(unsafe.Pointer(pTree.root)).GetNumResources(),
+		ids:          make(map[string]bool),
+	}
+	for _, id := range pTree.GetNodeIDs() {
+		e.ids[id] = true
+	}
+	return e
+}
+
+// pad : pad nodes by a chain of nodes from the level above the nodes up to a given level
+func (e *deviceExpander) pad(id string, from int, to int, nodes []*PNode) ([]*PNode, error) {
+	for level := from; level <= to; level++ {
+		padID := system.DeviceID(id, levelName(e.levelNames, level))
+		if e.ids[padID] {
+			return nil, fmt.Errorf("padding node %s of PE %s already in tree", padID, id)
+		}
+		e.ids[padID] = true
+		padNode := NewPNode(NewNode(&system.Entity{ID: padID}), 0, e.numResources)
+		for _, node := range nodes {
+			padNode.AddChild((*Node)(unsafe.Pointer(node)))
+		}
+		nodes = []*PNode{padNode}
+	}
+	return nodes, nil
+}
+
+// copyNode : copy a node of a tree, expanding PEs into their devices
+func (e *deviceExpander) copyNode(node *Node) (*PNode, error) {
+	pNode := (*PNode)(unsafe.Pointer(node))
+	pCopy := NewPNode(NewNode(node.Entity), 0, e.numResources)
+	pCopy.reservedBy = pNode.reservedBy
+	if pNode.maxAllocated != nil {
+		pCopy.maxAllocated = pNode.maxAllocated.Clone()
+	}
+	if node.IsLeaf() {
+		pe := (*system.PE)(unsafe.Pointer(node.Entity))
+		if !pe.HasDevices() {
+			// the PE stays a leaf, below padding nodes up to its own level
+			padded, err := e.pad(pe.GetID(), 1, e.depth, []*PNode{pCopy})
+			if err != nil {
+				return nil, err
+			}
+			return padded[0], nil
+		}
+		if pe.GetDeviceDepth() > e.depth {
+			return nil, fmt.Errorf("PE %s with device depth %d, expected at most %d", pe.GetID(),
+				pe.GetDeviceDepth(), e.depth)
+		}
+		devices := make([]*PNode, 0)
+		for _, device := range pe.GetDevices() {
+			child, err := e.copyDevice(pe, device)
+			if err != nil {
+				return nil, err
+			}
+			devices = append(devices, child)
+		}
+		// devices of smaller depth are padded up to the level below the PE
+		devices, err := e.pad(pe.GetID(), pe.GetDeviceDepth(), e.depth-1, devices)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range devices {
+			pCopy.AddChild((*Node)(unsafe.Pointer(child)))
+		}
+		return pCopy, nil
+	}
+	for _, child := range node.GetChildren() {
+		cCopy, err := e.copyNode(child)
+		if err != nil {
+			return nil, err
+		}
+		pCopy.AddChild((*Node)(unsafe.Pointer(cCopy)))
+	}
+	return pCopy, nil
+}
+
+// copyDevice : make a subtree of nodes of a device of a PE
+func (e *deviceExpander) copyDevice(pe *system.PE, d *system.Device) (*PNode, error) {
+	if e.ids[d.GetID()] {
+		return nil, fmt.Errorf("device %s of PE %s already in tree", d.GetID(), pe.GetID())
+	}
+	e.ids[d.GetID()] = true
+	if d.IsLeaf() {
+		devicePE := d.GetPE().Clone()
+		if devicePE == nil {
+			return nil, fmt.Errorf("device %s of PE %s hosting LEs", d.GetID(), pe.GetID())
+		}
+		if pe.GetState() != system.Schedulable {
+			devicePE.SetState(pe.GetState())
+		}
+		for _, taint := range pe.GetTaints() {
+			devicePE.AddTaint(taint)
+		}
+		return NewPNode(NewNode((*system.Entity)(unsafe.Pointer(devicePE))), 0, e.numResources), nil
+	}
+	pNode := NewPNode(NewNode(&d.Entity), 0, e.numResources)
+	for _, c := range d.GetChildren() {
+		child, err := e.copyDevice(pe, c)
+		if err != nil {
+			return nil, err
+		}
+		pNode.AddChild((*Node)(unsafe.Pointer(child)))
+	}
+	return pNode, nil
+}
+
+// levelName : the name of a level (leaves are at level 0) given the names of levels from the top down;
+// default name if not named
+func levelName(levelNames []string, level int) string {
+	if index := len(levelNames) - 1 - level; index >= 0 && index < len(levelNames) {
+		return levelNames[index]
+	}
+	return util.DefaultLevelName + strconv.Itoa(level)
+}
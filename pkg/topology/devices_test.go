@@ -0,0 +1,216 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/system"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// makeGPU : make a leaf device of one GPU, qualified by the ID of its PE
+func makeGPU(peID string, id string) *system.Device {
+	capacity, _ := util.NewAllocationCopy([]int{1})
+	return system.NewLeafDevice(system.NewPE(system.DeviceID(peID, id), capacity))
+}
+
+// makeDeviceTree : make a tree of nodes with GPUs under NVSwitches, GPUs, and no devices, respectively
+func makeDeviceTree() *PTree {
+	root := makePNode("root", 0, makePNode("node-0", 2), makePNode("node-1", 2), makePNode("node-2", 2))
+	pTree := NewPTree(NewTree((*Node)(unsafe.Pointer(root))))
+	pes := pTree.GetPEs()
+	pes["node-0"].SetDevices([]*system.Device{
+		system.NewDevice("node-0/nvswitch-0", makeGPU("node-0", "gpu-0"), makeGPU("node-0", "gpu-1")),
+		system.NewDevice("node-0/nvswitch-1", makeGPU("node-0", "gpu-2"))})
+	pes["node-1"].SetDevices([]*system.Device{makeGPU("node-1", "gpu-0"), makeGPU("node-1", "gpu-1")})
+	pTree.SetLevelNames([]string{"node"})
+	pTree.SetNodeLevels()
+	pTree.PercolateResources()
+	return pTree
+}
+
+func TestPTree_ExpandDevices(t *testing.T) {
+	tests := []struct {
+		name             string
+		pTree            *PTree
+		deviceLevelNames []string
+		wantErr          string
+	}{
+		{name: "no devices", pTree: makePTree(), wantErr: "no devices"},
+		{name: "missing device level names", pTree: makeDeviceTree(), wantErr: "missing device level names"},
+		{name: "device level names different from depth", pTree: makeDeviceTree(),
+			deviceLevelNames: []string{"gpu"}, wantErr: "1 device level names, expected 2"},
+		{name: "device in tree",
+			pTree: func() *PTree {
+				pTree := makeDeviceTree()
+				pTree.GetPEs()["node-1"].SetDevices([]*system.Device{makeGPU("node-0", "gpu-0")})
+				return pTree
+			}(),
+			deviceLevelNames: []string{"nvswitch", "gpu"}, wantErr: "already in tree"},
+		{name: "device with ID of padding node",
+			pTree: func() *PTree {
+				pTree := makeDeviceTree()
+				pTree.GetPEs()["node-1"].SetDevices([]*system.Device{makeGPU("node-2", "nvswitch")})
+				return pTree
+			}(),
+			deviceLevelNames: []string{"nvswitch", "gpu"}, wantErr: "node-2/nvswitch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.pTree.ExpandDevices(tt.deviceLevelNames)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ExpandDevices() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPTree_ExpandDevices_Padded(t *testing.T) {
+	pTree := makeDeviceTree()
+	expanded, err := pTree.ExpandDevices([]string{"nvswitch", "gpu"})
+	if err != nil {
+		t.Fatalf("ExpandDevices() error = %v", err)
+	}
+	if got := expanded.GetHeight(); got != 3 {
+		t.Errorf("GetHeight() = %d, want 3", got)
+	}
+
+	// all leaves at the leaf level, under the path of nodes at the levels above
+	wantPaths := map[string][]string{
+		"node-0/gpu-0": {"node-0/nvswitch-0", "node-0", "root"},
+		"node-0/gpu-1": {"node-0/nvswitch-0", "node-0", "root"},
+		"node-0/gpu-2": {"node-0/nvswitch-1", "node-0", "root"},
+		"node-1/gpu-0": {"node-1/nvswitch", "node-1", "root"},
+		"node-1/gpu-1": {"node-1/nvswitch", "node-1", "root"},
+		"node-2":       {"node-2/nvswitch", "node-2/node", "root"},
+	}
+	leaves := expanded.GetLeavesMap()
+	if len(leaves) != len(wantPaths) {
+		t.Errorf("GetLeafIDs() = %v", expanded.GetLeafIDs())
+	}
+	for id, wantPath := range wantPaths {
+		leaf := leaves[id]
+		if leaf == nil {
+			t.Errorf("leaf %s not in tree", id)
+			continue
+		}
+		if level := (*PNode)(unsafe.Pointer(leaf)).GetLevel(); level != 0 {
+			t.Errorf("leaf %s at level %d, want 0", id, level)
+		}
+		path := leaf.GetPathToRoot()[1:]
+		for i, node := range path {
+			if i >= len(wantPath) || node.GetID() != wantPath[i] {
+				t.Errorf("leaf %s with node %s at level %d, want path %v", id, node.GetID(), i+1, wantPath)
+				break
+			}
+		}
+	}
+	if got := expanded.GetLevelName(1); got != "nvswitch" {
+		t.Errorf("GetLevelName(1) = %s, want nvswitch", got)
+	}
+	if got := capacityOf(expanded, "root"); got != 7 {
+		t.Errorf("root capacity = %d, want 7", got)
+	}
+}
+
+func TestPTree_ExpandDevices_StateAndTaints(t *testing.T) {
+	pTree := makeDeviceTree()
+	pe := pTree.GetPEs()["node-0"]
+	pe.SetState(system.Cordoned)
+	pe.AddTaint(system.Taint{Key: "maintenance"})
+	expanded, err := pTree.ExpandDevices([]string{"nvswitch", "gpu"})
+	if err != nil {
+		t.Fatalf("ExpandDevices() error = %v", err)
+	}
+
+	// leaf devices of the expanded tree take the state and taints of their PE
+	devicePE := expanded.GetPEs()["node-0/gpu-0"]
+	if devicePE.GetState() != system.Cordoned || len(devicePE.GetTaints()) != 1 {
+		t.Errorf("expanded device %s, want cordoned and tainted", devicePE.String())
+	}
+	if other := expanded.GetPEs()["node-1/gpu-0"]; !other.IsSchedulable() || len(other.GetTaints()) != 0 {
+		t.Errorf("expanded device %s, want schedulable and untainted", other.String())
+	}
+
+	// leaf devices of the original PE are unchanged
+	for _, original := range pe.GetDevicePEs() {
+		if !original.IsSchedulable() || len(original.GetTaints()) != 0 {
+			t.Errorf("original device %s changed by ExpandDevices()", original.String())
+		}
+	}
+}
+
+func TestPTree_ExpandDevices_AddSubtree(t *testing.T) {
+	// makeNode : make a PE node with given devices
+	makeNode := func(id string, devices ...*system.Device) *PNode {
+		pNode := makePNode(id, 0)
+		if len(devices) > 0 {
+			(*system.PE)(unsafe.Pointer(pNode.Entity)).SetDevices(devices)
+		}
+		return pNode
+	}
+	tests := []struct {
+		name     string
+		parentID string
+		subtree  *PNode
+		// path to the root of a leaf of the subtree
+		leafID       string
+		wantPath     []string
+		wantCapacity int
+		wantErr      string
+	}{
+		{name: "PE with shallower devices", parentID: "root",
+			subtree: makeNode("node-3", makeGPU("node-3", "gpu-0"), makeGPU("node-3", "gpu-1")),
+			leafID:  "node-3/gpu-0", wantPath: []string{"node-3/nvswitch", "node-3", "root"}, wantCapacity: 9},
+		{name: "PE with devices of full depth", parentID: "root",
+			subtree: makeNode("node-3", system.NewDevice("node-3/nvswitch-0", makeGPU("node-3", "gpu-0"))),
+			leafID:  "node-3/gpu-0", wantPath: []string{"node-3/nvswitch-0", "node-3", "root"}, wantCapacity: 8},
+		{name: "PE without devices", parentID: "root", subtree: makePNode("node-3", 4),
+			leafID: "node-3", wantPath: []string{"node-3/nvswitch", "node-3/node", "root"}, wantCapacity: 11},
+		{name: "devices deeper than tree", parentID: "root",
+			subtree: makeNode("node-3", system.NewDevice("node-3/nvlink",
+				system.NewDevice("node-3/nvswitch", makeGPU("node-3", "gpu-0")))),
+			wantErr: "device depth 3"},
+		{name: "device in tree", parentID: "root", subtree: makeNode("node-3", makeGPU("node-0", "gpu-0")),
+			wantErr: "already in tree"},
+		{name: "device parent", parentID: "node-1", subtree: makePNode("node-3", 4), wantErr: "is a device"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded, err := makeDeviceTree().ExpandDevices([]string{"nvswitch", "gpu"})
+			if err != nil {
+				t.Fatalf("ExpandDevices() error = %v", err)
+			}
+			err = expanded.AddSubtree(tt.parentID, tt.subtree)
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("AddSubtree() error = %v, want %q", err, tt.wantErr)
+				}
+				if got := capacityOf(expanded, "root"); got != 7 {
+					t.Errorf("root capacity = %d, want 7", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddSubtree() error = %v", err)
+			}
+			leaf := expanded.GetLeavesMap()[tt.leafID]
+			if leaf == nil {
+				t.Fatalf("leaf %s not in tree %v", tt.leafID, expanded.GetLeafIDs())
+			}
+			if level := (*PNode)(unsafe.Pointer(leaf)).GetLevel(); level != 0 {
+				t.Errorf("leaf %s at level %d, want 0", tt.leafID, level)
+			}
+			for i, node := range leaf.GetPathToRoot()[1:] {
+				if i >= len(tt.wantPath) || node.GetID() != tt.wantPath[i] {
+					t.Errorf("leaf %s with node %s at level %d, want path %v", tt.leafID, node.GetID(), i+1, tt.wantPath)
+					break
+				}
+			}
+			if got := capacityOf(expanded, "root"); got != tt.wantCapacity {
+				t.Errorf("root capacity = %d, want %d", got, tt.wantCapacity)
+			}
+		})
+	}
+}
@@ -60,6 +60,38 @@ func (lTree *LTree) SetPhysicalClaimed() {
 	}
 }
 
+// RemoveLeaves : remove leaves with given IDs, updating count and claimed of their ancestors,
+// and return the count removed
+//   - ancestors (other than the root) left with no children are also removed
+func (lTree *LTree) RemoveLeaves(leafIDs []string) int {
+	leavesMap := lTree.GetLeavesMap()
+	numRemoved := 0
+	for _, id := range leafIDs {
+		leaf := leavesMap[id]
+		if leaf == nil || leaf.IsRoot() {
+			continue
+		}
+		lLeaf := (*LNode)(unsafe.Pointer(leaf))
+		count := lLeaf.GetCount()
+		claimed := lLeaf.GetClaimed()
+		numRemoved += count
+		path := leaf.GetPathToRoot()
+		for _, node := range path[1:] {
+			lNode := (*LNode)(unsafe.Pointer(node))
+			lNode.SetCount(lNode.GetCount() - count)
+			lNode.IncClaimed(-claimed)
+		}
+		// prune nodes left with no children
+		node := leaf
+		for !node.IsRoot() && (node == leaf || node.IsLeaf()) {
+			parent := node.GetParent()
+			parent.RemoveChild(node)
+			node = parent
+		}
+	}
+	return numRemoved
+}
+
 // String : a print out of the logical tree
 func (lTree *LTree) String() string {
 	var b bytes.Buffer
@@ -67,6 +67,15 @@ func (n *Node) GetChildren() []*Node {
 	return children
 }
 
+// GetSortedChildren : the children of this node, ordered by ID
+func (n *Node) GetSortedChildren() []*Node {
+	children := n.GetChildren()
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].GetID() < children[j].GetID()
+	})
+	return children
+}
+
 // IsRoot : is this node the root of the tree
 func (n *Node) IsRoot() bool {
 	return n.parent == nil
@@ -165,6 +174,19 @@ func (n *Node) GetPathToRoot() []*Node {
 	return path
 }
 
+// GetPathLabels : the labels of the nodes on the path from the root to this node,
+// where labels of a node override those of its ancestors
+func (n *Node) GetPathLabels() map[string]string {
+	labels := make(map[string]string)
+	path := n.GetPathToRoot()
+	for i := len(path) - 1; i >= 0; i-- {
+		for k, v := range path[i].Entity.GetLabels() {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
 // ToMap : create a map of the subtree rooted at this node
 func (n *Node) ToMap() TreeMap {
 	m := make(map[string]TreeMap)
@@ -174,6 +174,28 @@ func TestNode_GetChildren(t *testing.T) {
 		})
 	}
 }
+
+func TestNode_GetSortedChildren(t *testing.T) {
+	tests := []struct {
+		name string
+		node *Node
+		want []*Node
+	}{
+		{name: "children-A", node: nodeA, want: []*Node{nodeB, nodeC}},
+		{name: "children-B", node: nodeB, want: []*Node{}},
+		{name: "children-C", node: nodeC, want: []*Node{nodeD}},
+	}
+	resetNodes()
+	connectNodes()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.node.GetSortedChildren(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Node.GetSortedChildren() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestNode_AddChild(t *testing.T) {
 	type fields struct {
 		node *Node
@@ -2,6 +2,7 @@ package topology
 
 import (
 	"fmt"
+	"math"
 	"unsafe"
 
 	"github.com/ibm/chic-sched/pkg/util"
@@ -22,8 +23,17 @@ type PNode struct {
 	numFit int
 	// number of group instances that are claimed
 	numClaimed int
+	// ID of the group the subtree is reserved for (empty if not reserved)
+	reservedBy string
+	// maximum resources allocated in the subtree (nil if unlimited)
+	maxAllocated *util.Allocation
 }
 
+var (
+	// Unlimited : value of a resource in the maximum allocated of a node with no limit on the resource
+	Unlimited int = math.MaxInt
+)
+
 // NewPNode : create a new physical node with zero capacity and allocated resources
 //   - returns nil if bad parameters
 func NewPNode(node *Node, level int, numResources int) *PNode {
@@ -166,8 +176,72 @@ func (pNode *PNode) CompareClaimed(oNode *PNode, isIncreasing bool) int {
 	return util.BoolValue(below)
 }
 
+// GetReservedBy : get the ID of the group the subtree of this node is reserved for (empty if not reserved)
+func (pNode *PNode) GetReservedBy() string {
+	return pNode.reservedBy
+}
+
+// SetReservedBy : reserve the subtree of this node for a group (empty to release)
+func (pNode *PNode) SetReservedBy(groupID string) {
+	pNode.reservedBy = groupID
+}
+
+// IsReservedForOther : check if this node or one of its ancestors is reserved for a group other than a given group
+func (pNode *PNode) IsReservedForOther(groupID string) bool {
+	for _, node := range pNode.GetPathToRoot() {
+		reservedBy := (*PNode)(unsafe.Pointer(node)).reservedBy
+		if len(reservedBy) > 0 && reservedBy != groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMaxAllocated : get the maximum resources allocated in the subtree of this node (nil if unlimited)
+func (pNode *PNode) GetMaxAllocated() *util.Allocation {
+	return pNode.maxAllocated
+}
+
+// SetMaxAllocated : set the maximum resources allocated in the subtree of this node,
+// e.g. the power budget of a rack, where resources with no limit have the Unlimited value; nil to remove
+//   - makes a copy of the maximum
+//   - returns false if length different from number of resources
+func (pNode *PNode) SetMaxAllocated(maxAllocated *util.Allocation) bool {
+	if maxAllocated == nil {
+		pNode.maxAllocated = nil
+		return true
+	}
+	if maxAllocated.GetSize() != pNode.GetNumResources() {
+		return false
+	}
+	pNode.maxAllocated = maxAllocated.Clone()
+	return true
+}
+
+// numFitMaxAllocated : number of a demand fitting in the subtree of this node given the
+// maximum allocated, and false if unlimited
+func (pNode *PNode) numFitMaxAllocated(demand *util.Allocation) (int, bool) {
+	if pNode.maxAllocated == nil || !demand.SameSize(pNode.maxAllocated) {
+		return 0, false
+	}
+	numFit, limited := math.MaxInt32, false
+	maxAllocated := pNode.maxAllocated.GetValue()
+	allocated := pNode.allocated.GetValue()
+	for i, d := range demand.GetValue() {
+		if d > 0 && maxAllocated[i] != Unlimited {
+			numFit = util.Min(numFit, util.Max(maxAllocated[i]-allocated[i], 0)/d)
+			limited = true
+		}
+	}
+	return numFit, limited
+}
+
 // String : a print out of the physical node
 func (pNode *PNode) String() string {
-	return fmt.Sprintf("pNode: ID=%s; level=%d; cap=%v; alloc=%v; numClaimed=%d",
+	s := fmt.Sprintf("pNode: ID=%s; level=%d; cap=%v; alloc=%v; numClaimed=%d",
 		pNode.GetID(), pNode.level, pNode.capacity, pNode.allocated, pNode.numClaimed)
+	if len(pNode.reservedBy) > 0 {
+		s += "; reservedBy=" + pNode.reservedBy
+	}
+	return s
 }
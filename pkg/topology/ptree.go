@@ -3,6 +3,8 @@ package topology
 import (
 	"bytes"
 	"fmt"
+	"sort"
+	"strconv"
 	"unsafe"
 
 	"github.com/ibm/chic-sched/pkg/system"
@@ -15,6 +17,16 @@ import (
 type PTree struct {
 	// extends Tree
 	Tree
+	// names of resources (optional)
+	resourceNames []string
+	// names of levels, from the top (below root) down to the leaves (optional)
+	levelNames []string
+	// overcommit ratios of resources for all PEs (nil if none)
+	overcommit []float64
+	// maximum number of LEs hosted on PEs with no maximum of their own (zero if unlimited)
+	maxLEs int
+	// number of device levels of a tree with expanded devices (zero if not expanded)
+	deviceDepth int
 }
 
 // NewPTree : create a new physical tree
@@ -28,6 +40,49 @@ func NewPTree(tree *Tree) *PTree {
 	}
 }
 
+// GetResourceNames : get the names of resources (nil if not set)
+func (pTree *PTree) GetResourceNames() []string {
+	return pTree.resourceNames
+}
+
+// SetResourceNames : set the names of resources, also in PEs of the tree and PEs added later
+func (pTree *PTree) SetResourceNames(resourceNames []string) {
+	pTree.resourceNames = make([]string, len(resourceNames))
+	copy(pTree.resourceNames, resourceNames)
+	for _, pe := range pTree.GetPEs() {
+		pe.SetResourceNames(pTree.resourceNames)
+	}
+}
+
+// GetLevelNames : get the names of levels, from the top (below root) down to the leaves (nil if not set)
+func (pTree *PTree) GetLevelNames() []string {
+	return pTree.levelNames
+}
+
+// SetLevelNames : set the names of levels, from the top (below root) down to the leaves
+func (pTree *PTree) SetLevelNames(levelNames []string) {
+	pTree.levelNames = make([]string, len(levelNames))
+	copy(pTree.levelNames, levelNames)
+}
+
+// GetLevelName : get the name of a given level (leaves are at level 0);
+// default names are used if level names are not set
+func (pTree *PTree) GetLevelName(level int) string {
+	height := pTree.GetHeight()
+	if level == height {
+		return util.DefaultRootName
+	}
+	if len(pTree.levelNames) == height {
+		if index := height - 1 - level; index >= 0 && index < height {
+			return pTree.levelNames[index]
+		}
+	}
+	if level >= 0 && level < len(util.DefaultLevelNames) {
+		return util.DefaultLevelNames[level]
+	}
+	return util.DefaultLevelName + strconv.FormatInt(int64(level), 10)
+}
+
 // GetPEs : get a map of all PEs (leaf nodes)
 func (pTree *PTree) GetPEs() map[string]*system.PE {
 	pLeaves := pTree.GetLeaves()
@@ -74,13 +129,14 @@ func (pTree *PTree) ResetResources() {
 }
 
 // PercolateResources : set allocation and capacity of all nodes, from the leaves up to the root
+//   - the capacity of a node is the effective capacity (given overcommit ratios) of the PEs in its subtree
 func (pTree *PTree) PercolateResources() {
 	pTree.ResetResources()
 	leaves := pTree.GetLeaves()
 	for _, leaf := range leaves {
 		pe := (*system.PE)(unsafe.Pointer(leaf.Entity))
 		allocated := pe.GetAllocated()
-		capacity := pe.GetCapacity()
+		capacity := pe.GetEffectiveCapacity()
 		path := leaf.GetPathToRoot()
 		for i, node := range path {
 			pNode := (*PNode)(unsafe.Pointer(node))
@@ -95,13 +151,52 @@ func (pTree *PTree) PercolateResources() {
 	}
 }
 
-// PercolateNumFit : set number that can fit given demand for all nodes, from the leaves up to the root
+// PercolateNumFit : set number that can fit given demand for all nodes, from the leaves up to the root,
+// limited by the maximum number of LEs hosted on PEs and the maximum allocated of nodes
 func (pTree *PTree) PercolateNumFit(demand *util.Allocation) {
+	pTree.PercolateNumFitFunc(func(pe *system.PE) int {
+		return pe.NumberToFit(demand)
+	})
+	pTree.LimitNumFit(demand, nil)
+}
+
+// LimitNumFit : limit the number that can fit given demand in nodes with a maximum allocated,
+// and in nodes with a cap on the number, recomputing the number in each node from the bottom up
+// as the sum of the limited numbers in its children
+//   - caps: maximum number that can fit mapped to node IDs (nil if none)
+//   - assumes number that can fit already percolated
+func (pTree *PTree) LimitNumFit(demand *util.Allocation, caps map[string]int) {
+	if pTree.root == nil {
+		return
+	}
+	nodes := pTree.GetNodeListBFS()
+	// deepest first, so that nodes see the limited numbers of their children
+	for i := len(nodes) - 1; i >= 0; i-- {
+		pNode := (*PNode)(unsafe.Pointer(nodes[i]))
+		if !pNode.IsLeaf() {
+			pNode.numFit = 0
+			for _, child := range pNode.GetChildren() {
+				pNode.numFit += (*PNode)(unsafe.Pointer(child)).numFit
+			}
+		}
+		if numFit, ok := pNode.numFitMaxAllocated(demand); ok {
+			pNode.numFit = util.Min(pNode.numFit, numFit)
+		}
+		if numFit, ok := caps[pNode.GetID()]; ok {
+			pNode.numFit = util.Min(pNode.numFit, numFit)
+		}
+		pNode.numFit = util.Max(pNode.numFit, 0)
+	}
+}
+
+// PercolateNumFitFunc : set number that can fit for all nodes, from the leaves up to the root,
+// given a function of the number that can fit on a PE
+func (pTree *PTree) PercolateNumFitFunc(numFitPE func(pe *system.PE) int) {
 	pTree.ResetNumFit()
 	leaves := pTree.GetLeaves()
 	for _, leaf := range leaves {
 		pe := (*system.PE)(unsafe.Pointer(leaf.Entity))
-		numFit := demand.NumberToFit(pe.GetAllocated(), pe.GetCapacity())
+		numFit := numFitPE(pe)
 		path := leaf.GetPathToRoot()
 		for i, node := range path {
 			pNode := (*PNode)(unsafe.Pointer(node))
@@ -184,7 +279,368 @@ func (pTree *PTree) CopyByLeafIDs(leafIDs []string) *PTree {
 			prevNode = curNodeCopy
 		}
 	}
-	return NewPTree(NewTree((*Node)(unsafe.Pointer(pRootCopy))))
+	pTreeCopy := NewPTree(NewTree((*Node)(unsafe.Pointer(pRootCopy))))
+	pTreeCopy.resourceNames = pTree.resourceNames
+	pTreeCopy.levelNames = pTree.levelNames
+	return pTreeCopy
+}
+
+// AddSubtree : add a subtree of PNodes, with PE leaves, as a child of a given node
+//   - levels of the subtree are set below the parent node
+//   - resources of the subtree are percolated up to the root
+//   - in a tree with expanded devices, a copy of the subtree with PEs expanded into their devices
+//     is added, as by ExpandDevices, with its leaf PEs at the level of the PEs of the tree
+//   - returns error if bad parameters, IDs already in the tree,
+//     subtree leaves not all at level 0, or PEs with different number of resources
+func (pTree *PTree) AddSubtree(parentID string, subtree *PNode) error {
+	if pTree.root == nil || subtree == nil {
+		return fmt.Errorf("empty tree or subtree")
+	}
+	parent := pTree.GetNode(parentID)
+	if parent == nil {
+		return fmt.Errorf("node %s not found", parentID)
+	}
+	pParent := (*PNode)(unsafe.Pointer(parent))
+	if pParent.GetLevel() == 0 {
+		return fmt.Errorf("node %s is a leaf", parentID)
+	}
+	subtreeNode := (*Node)(unsafe.Pointer(subtree))
+	if subtreeNode.GetParent() != nil {
+		return fmt.Errorf("subtree %s already has a parent", subtree.GetID())
+	}
+	if pTree.deviceDepth > 0 {
+		if pParent.GetLevel() <= pTree.deviceDepth {
+			return fmt.Errorf("node %s is a device", parentID)
+		}
+		expanded, err := newDeviceExpander(pTree, pTree.levelNames, pTree.deviceDepth).copyNode(subtreeNode)
+		if err != nil {
+			return err
+		}
+		subtree = expanded
+		subtreeNode = (*Node)(unsafe.Pointer(subtree))
+	}
+
+	// check IDs, depth of leaves, and resources
+	existing := make(map[string]bool)
+	for _, id := range pTree.GetNodeIDs() {
+		existing[id] = true
+	}
+	for _, id := range NewTree(subtreeNode).GetNodeIDs() {
+		if existing[id] {
+			return fmt.Errorf("node %s already in tree", id)
+		}
+	}
+	level := pParent.GetLevel() - 1
+	for _, nv := range subtreeNode.GetLeavesDepth() {
+		if nv.Value != level {
+			return fmt.Errorf("leaf %s at level %d, expected level 0", nv.Name, level-nv.Value)
+		}
+	}
+	numResources := (*PNode)(unsafe.Pointer(pTree.root)).GetNumResources()
+	for _, node := range NewTree(subtreeNode).GetNodeListBFS() {
+		if (*PNode)(unsafe.Pointer(node)).GetNumResources() != numResources {
+			return fmt.Errorf("node %s with number of resources different from tree", node.GetID())
+		}
+		if node.IsLeaf() {
+			pe := (*system.PE)(unsafe.Pointer(node.Entity))
+			if pe.GetCapacity().GetSize() != numResources {
+				return fmt.Errorf("PE %s with number of resources different from tree", node.GetID())
+			}
+		}
+	}
+	// apply the tree overcommit policy and maximum number of LEs to PEs with none of their own,
+	// and name their resources
+	for _, leaf := range subtreeNode.GetLeaves() {
+		pe := (*system.PE)(unsafe.Pointer(leaf.Entity))
+		if pTree.overcommit != nil && pe.GetOvercommit() == nil {
+			pe.SetOvercommit(pTree.overcommit)
+		}
+		if pe.GetMaxLEs() == 0 {
+			pe.SetMaxLEs(pTree.maxLEs)
+		}
+		if pTree.resourceNames != nil {
+			pe.SetResourceNames(pTree.resourceNames)
+		}
+	}
+
+	// link and update derived state
+	parent.AddChild(subtreeNode)
+	subtree.SetLevelSubtree(level)
+	subtree.percolateResourcesSubtree()
+	pTree.addToAncestors(subtree, subtree.capacity, subtree.allocated, subtree.numClaimed, true)
+	return nil
+}
+
+// RemoveSubtree : remove the subtree rooted at a given (non-root) node,
+// evicting LEs hosted on its PEs, and return the IDs of the groups of evicted LEs
+//   - ancestors left with no children are also removed
+//   - resources and numClaimed of ancestors are updated
+//   - the logical trees of the groups returned still refer to the removed PEs, callers must prune them
+//     with PGroup.PruneLTree() before claiming or reporting the groups
+//   - returns error if bad parameters, or removal leaves the tree with no PEs
+func (pTree *PTree) RemoveSubtree(nodeID string) ([]string, error) {
+	node := pTree.GetNode(nodeID)
+	if node == nil {
+		return nil, fmt.Errorf("node %s not found", nodeID)
+	}
+	if node.IsRoot() {
+		return nil, fmt.Errorf("cannot remove root node %s", nodeID)
+	}
+	// find top of subtree to remove, including ancestors left with no children
+	top := node
+	for top.GetParent().GetNumChildren() == 1 {
+		top = top.GetParent()
+		if top.IsRoot() {
+			return nil, fmt.Errorf("removing node %s leaves no PEs in tree", nodeID)
+		}
+	}
+
+	// evict hosted LEs
+	groups := make(map[string]bool)
+	for _, leaf := range node.GetLeaves() {
+		pe := (*system.PE)(unsafe.Pointer(leaf.Entity))
+		for _, le := range pe.GetHostedLEs() {
+			if len(le.GetGroupID()) > 0 {
+				groups[le.GetGroupID()] = true
+			}
+			pe.UnPlaceLE(le)
+		}
+	}
+
+	// update ancestors with resources before eviction, and unlink
+	pTop := (*PNode)(unsafe.Pointer(top))
+	pTree.addToAncestors(pTop, pTop.capacity, pTop.allocated, pTop.numClaimed, false)
+	top.GetParent().RemoveChild(top)
+
+	groupIDs := make([]string, 0, len(groups))
+	for id := range groups {
+		groupIDs = append(groupIDs, id)
+	}
+	sort.Strings(groupIDs)
+	return groupIDs, nil
+}
+
+// ReplaceCapacity : replace the resource capacity of a PE,
+// updating the capacity of its ancestors
+//   - returns error if PE not found, or capacity with different number of resources
+func (pTree *PTree) ReplaceCapacity(peID string, capacity *util.Allocation) error {
+	leaf := pTree.GetLeavesMap()[peID]
+	if leaf == nil {
+		return fmt.Errorf("PE %s not found", peID)
+	}
+	if capacity == nil {
+		return fmt.Errorf("capacity is nil")
+	}
+	pe := (*system.PE)(unsafe.Pointer(leaf.Entity))
+	old := pe.GetEffectiveCapacity()
+	if !pe.SetCapacity(capacity) {
+		return fmt.Errorf("capacity %v with number of resources different from %v", capacity, pe.GetCapacity())
+	}
+	delta := pe.GetEffectiveCapacity()
+	delta.Subtract(old)
+	for _, node := range leaf.GetPathToRoot() {
+		(*PNode)(unsafe.Pointer(node)).capacity.Add(delta)
+	}
+	return nil
+}
+
+// GetOvercommit : get the overcommit ratios of resources of the tree (nil if none)
+func (pTree *PTree) GetOvercommit() []float64 {
+	return pTree.overcommit
+}
+
+// SetOvercommit : set the overcommit ratios of resources for all PEs in the tree, and PEs added later
+// with no ratios of their own, e.g. [4, 1] for cpu oversubscribed 4x and memory not oversubscribed;
+// nil to remove
+//   - the capacity of nodes becomes the effective capacity
+//   - returns error if ratios of a length different from the number of resources or non-positive
+func (pTree *PTree) SetOvercommit(ratios []float64) error {
+	if pTree.root == nil || (*PNode)(unsafe.Pointer(pTree.root)).GetNumResources() == 0 {
+		return fmt.Errorf("empty tree or tree with no resources")
+	}
+	if ratios != nil {
+		if len(ratios) != (*PNode)(unsafe.Pointer(pTree.root)).GetNumResources() {
+			return fmt.Errorf("overcommit ratios %v with number of resources different from tree", ratios)
+		}
+		for _, r := range ratios {
+			if r <= 0 {
+				return fmt.Errorf("overcommit ratio %v must be positive", r)
+			}
+		}
+	}
+	for _, pe := range pTree.GetPEs() {
+		pe.SetOvercommit(ratios)
+	}
+	pTree.overcommit = nil
+	if ratios != nil {
+		pTree.overcommit = append([]float64{}, ratios...)
+	}
+	pTree.PercolateResources()
+	return nil
+}
+
+// GetMaxLEs : get the maximum number of LEs hosted on PEs with no maximum of their own (zero if unlimited)
+func (pTree *PTree) GetMaxLEs() int {
+	return pTree.maxLEs
+}
+
+// SetMaxLEs : set the maximum number of LEs hosted on PEs in the tree, and PEs added later,
+// with no maximum of their own; zero for unlimited
+//   - PEs with the previous maximum of the tree take the new one
+//   - returns false if negative
+func (pTree *PTree) SetMaxLEs(maxLEs int) bool {
+	if maxLEs < 0 {
+		return false
+	}
+	for _, pe := range pTree.GetPEs() {
+		if pe.GetMaxLEs() == pTree.maxLEs {
+			pe.SetMaxLEs(maxLEs)
+		}
+	}
+	pTree.maxLEs = maxLEs
+	return true
+}
+
+// SetPEOvercommit : set the overcommit ratios of resources of a PE, nil to remove
+//   - updates the capacity of the PE node and its ancestors
+//   - returns error if PE not found or invalid ratios
+func (pTree *PTree) SetPEOvercommit(peID string, ratios []float64) error {
+	leaf := pTree.GetLeavesMap()[peID]
+	if leaf == nil {
+		return fmt.Errorf("PE %s not found", peID)
+	}
+	pe := (*system.PE)(unsafe.Pointer(leaf.Entity))
+	old := pe.GetEffectiveCapacity()
+	if !pe.SetOvercommit(ratios) {
+		return fmt.Errorf("invalid overcommit ratios %v for PE %s", ratios, peID)
+	}
+	delta := pe.GetEffectiveCapacity()
+	delta.Subtract(old)
+	for _, node := range leaf.GetPathToRoot() {
+		(*PNode)(unsafe.Pointer(node)).capacity.Add(delta)
+	}
+	return nil
+}
+
+// SetPEState : set the scheduling state of all PEs in the subtree rooted at a given node,
+// and return the IDs of the PEs
+//   - returns error if node not found
+func (pTree *PTree) SetPEState(nodeID string, state system.PEState) ([]string, error) {
+	node := pTree.GetNode(nodeID)
+	if node == nil {
+		return nil, fmt.Errorf("node %s not found", nodeID)
+	}
+	ids := make([]string, 0)
+	for _, leaf := range node.GetLeaves() {
+		pe := (*system.PE)(unsafe.Pointer(leaf.Entity))
+		pe.SetState(state)
+		ids = append(ids, pe.GetID())
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Drain : set all PEs in the subtree rooted at a given node as draining,
+// and return the IDs of the groups of LEs hosted on these PEs, which are to be moved
+//   - hosted LEs are not evicted
+//   - returns error if node not found
+func (pTree *PTree) Drain(nodeID string) ([]string, error) {
+	peIDs, err := pTree.SetPEState(nodeID, system.Draining)
+	if err != nil {
+		return nil, err
+	}
+	pes := pTree.GetPEs()
+	groups := make(map[string]bool)
+	for _, id := range peIDs {
+		for _, le := range pes[id].GetHostedLEs() {
+			if len(le.GetGroupID()) > 0 {
+				groups[le.GetGroupID()] = true
+			}
+		}
+	}
+	groupIDs := make([]string, 0, len(groups))
+	for id := range groups {
+		groupIDs = append(groupIDs, id)
+	}
+	sort.Strings(groupIDs)
+	return groupIDs, nil
+}
+
+// ReleaseReserved : release all nodes reserved for a group, and return their number
+func (pTree *PTree) ReleaseReserved(groupID string) int {
+	n := 0
+	for _, node := range pTree.GetNodeListBFS() {
+		pNode := (*PNode)(unsafe.Pointer(node))
+		if pNode.reservedBy == groupID {
+			pNode.reservedBy = ""
+			n++
+		}
+	}
+	return n
+}
+
+// GetPELabels : the labels of each PE, merged along the path from the root,
+// where labels of a node override those of its ancestors, keyed by PE ID
+func (pTree *PTree) GetPELabels() map[string]map[string]string {
+	peLabels := make(map[string]map[string]string)
+	if pTree.GetRoot() == nil {
+		return peLabels
+	}
+	var visit func(node *Node, inherited map[string]string)
+	visit = func(node *Node, inherited map[string]string) {
+		labels := make(map[string]string, len(inherited))
+		for k, v := range inherited {
+			labels[k] = v
+		}
+		for k, v := range node.Entity.GetLabels() {
+			labels[k] = v
+		}
+		if node.IsLeaf() {
+			peLabels[node.GetID()] = labels
+			return
+		}
+		for _, child := range node.GetChildren() {
+			visit(child, labels)
+		}
+	}
+	visit(pTree.GetRoot(), nil)
+	return peLabels
+}
+
+// percolateResourcesSubtree : set allocation and capacity of all nodes in subtree, from its leaves up
+func (pNode *PNode) percolateResourcesSubtree() {
+	if pNode.IsLeaf() {
+		pe := (*system.PE)(unsafe.Pointer(pNode.Entity))
+		pNode.capacity = pe.GetEffectiveCapacity()
+		pNode.allocated = pe.GetAllocated().Clone()
+		return
+	}
+	pNode.capacity.SetZero()
+	pNode.allocated.SetZero()
+	for _, node := range pNode.children {
+		pChild := (*PNode)(unsafe.Pointer(node))
+		pChild.percolateResourcesSubtree()
+		pNode.capacity.Add(pChild.capacity)
+		pNode.allocated.Add(pChild.allocated)
+	}
+}
+
+// addToAncestors : add (or subtract) resources and number claimed of a subtree to its ancestors
+func (pTree *PTree) addToAncestors(pNode *PNode, capacity *util.Allocation, allocated *util.Allocation,
+	numClaimed int, isAdd bool) {
+	path := pNode.GetPathToRoot()
+	for _, node := range path[1:] {
+		pAncestor := (*PNode)(unsafe.Pointer(node))
+		if isAdd {
+			pAncestor.capacity.Add(capacity)
+			pAncestor.allocated.Add(allocated)
+			pAncestor.numClaimed += numClaimed
+		} else {
+			pAncestor.capacity.Subtract(capacity)
+			pAncestor.allocated.Subtract(allocated)
+			pAncestor.numClaimed -= numClaimed
+		}
+	}
 }
 
 // String : a print out of the physical tree
@@ -0,0 +1,451 @@
+package topology
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/ibm/chic-sched/pkg/system"
+	"github.com/ibm/chic-sched/pkg/util"
+)
+
+// makePNode : make a PNode, with a PE of a given cpu capacity if a leaf
+func makePNode(id string, cpu int, children ...*PNode) *PNode {
+	var entity *system.Entity
+	if len(children) == 0 {
+		capacity, _ := util.NewAllocationCopy([]int{cpu})
+		entity = (*system.Entity)(unsafe.Pointer(system.NewPE(id, capacity)))
+	} else {
+		entity = &system.Entity{ID: id}
+	}
+	pNode := NewPNode(NewNode(entity), 0, 1)
+	for _, child := range children {
+		pNode.AddChild((*Node)(unsafe.Pointer(child)))
+	}
+	return pNode
+}
+
+// makePTree : make a tree of two racks, with two and one PEs of 4 cpus
+func makePTree() *PTree {
+	root := makePNode("root", 0,
+		makePNode("rack-0", 0, makePNode("pe-0", 4), makePNode("pe-1", 4)),
+		makePNode("rack-1", 0, makePNode("pe-2", 4)))
+	pTree := NewPTree(NewTree((*Node)(unsafe.Pointer(root))))
+	pTree.SetNodeLevels()
+	pTree.PercolateResources()
+	return pTree
+}
+
+// capacityOf : cpu capacity of a node in a tree (-1 if not found)
+func capacityOf(pTree *PTree, id string) int {
+	node := pTree.GetNode(id)
+	if node == nil {
+		return -1
+	}
+	return (*PNode)(unsafe.Pointer(node)).GetCapacity().GetValue()[0]
+}
+
+func TestPTree_AddSubtree(t *testing.T) {
+	tests := []struct {
+		name         string
+		parentID     string
+		subtree      *PNode
+		wantCapacity int
+		wantErr      bool
+	}{
+		{name: "add rack", parentID: "root",
+			subtree:      makePNode("rack-2", 0, makePNode("pe-3", 8)),
+			wantCapacity: 20, wantErr: false},
+		{name: "add PE", parentID: "rack-1", subtree: makePNode("pe-3", 8), wantCapacity: 20, wantErr: false},
+		{name: "duplicate ID", parentID: "root",
+			subtree: makePNode("rack-2", 0, makePNode("pe-0", 8)), wantCapacity: 12, wantErr: true},
+		{name: "wrong depth", parentID: "root", subtree: makePNode("pe-3", 8), wantCapacity: 12, wantErr: true},
+		{name: "leaf parent", parentID: "pe-0", subtree: makePNode("pe-3", 8), wantCapacity: 12, wantErr: true},
+		{name: "missing parent", parentID: "rack-9", subtree: makePNode("pe-3", 8), wantCapacity: 12, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			err := pTree.AddSubtree(tt.parentID, tt.subtree)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PTree.AddSubtree() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := capacityOf(pTree, "root"); got != tt.wantCapacity {
+				t.Errorf("root capacity = %v, want %v", got, tt.wantCapacity)
+			}
+			if !tt.wantErr && tt.subtree.GetLevel() != (*PNode)(unsafe.Pointer(pTree.GetNode(tt.parentID))).GetLevel()-1 {
+				t.Errorf("subtree level = %v", tt.subtree.GetLevel())
+			}
+		})
+	}
+}
+
+func TestPTree_SetMaxLEs(t *testing.T) {
+	pTree := makePTree()
+	pes := pTree.GetPEs()
+	pes["pe-0"].SetMaxLEs(5)
+	if pTree.SetMaxLEs(-1) {
+		t.Errorf("SetMaxLEs(-1) = true, want false")
+	}
+	steps := []struct {
+		name   string
+		change func()
+		want   map[string]int
+	}{
+		{name: "tree default", change: func() { pTree.SetMaxLEs(3) },
+			want: map[string]int{"pe-0": 5, "pe-1": 3, "pe-2": 3}},
+		{name: "added PEs take the default",
+			change: func() {
+				own := makePNode("pe-4", 4)
+				(*system.PE)(unsafe.Pointer(own.Entity)).SetMaxLEs(2)
+				if err := pTree.AddSubtree("root", makePNode("rack-2", 0, makePNode("pe-3", 4), own)); err != nil {
+					t.Fatalf("AddSubtree() error = %v", err)
+				}
+			},
+			want: map[string]int{"pe-0": 5, "pe-1": 3, "pe-2": 3, "pe-3": 3, "pe-4": 2}},
+		{name: "new tree default", change: func() { pTree.SetMaxLEs(4) },
+			want: map[string]int{"pe-0": 5, "pe-1": 4, "pe-2": 4, "pe-3": 4, "pe-4": 2}},
+		{name: "unlimited", change: func() { pTree.SetMaxLEs(0) },
+			want: map[string]int{"pe-0": 5, "pe-1": 0, "pe-2": 0, "pe-3": 0, "pe-4": 2}},
+	}
+	for _, step := range steps {
+		step.change()
+		got := make(map[string]int)
+		for id, pe := range pTree.GetPEs() {
+			got[id] = pe.GetMaxLEs()
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: maximum LEs of PEs = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestPTree_SetResourceNames(t *testing.T) {
+	pTree := makePTree()
+	pTree.SetResourceNames([]string{"gpu"})
+	if err := pTree.AddSubtree("rack-1", makePNode("pe-3", 1)); err != nil {
+		t.Fatalf("AddSubtree() error = %v", err)
+	}
+	for id, pe := range pTree.GetPEs() {
+		if got := pe.GetResourceNames(); !reflect.DeepEqual(got, []string{"gpu"}) {
+			t.Errorf("resource names of %s = %v, want [gpu]", id, got)
+		}
+	}
+
+	// capacity errors of added PEs name the resource
+	demand, _ := util.NewAllocationCopy([]int{2})
+	err := pTree.GetPEs()["pe-3"].TryPlaceLE(system.NewLE("le-0", demand))
+	if capErr, ok := err.(*system.CapacityError); !ok || capErr.ResourceName != "gpu" ||
+		!strings.Contains(capErr.Error(), "exceeds gpu capacity") {
+		t.Errorf("TryPlaceLE() error = %v, want gpu CapacityError", err)
+	}
+}
+
+func TestPTree_RemoveSubtree(t *testing.T) {
+	tests := []struct {
+		name         string
+		nodeID       string
+		wantGroups   []string
+		wantNodeIDs  []string
+		wantCapacity int
+		wantErr      bool
+	}{
+		{name: "remove PE", nodeID: "pe-0", wantGroups: []string{"g"},
+			wantNodeIDs: []string{"pe-1", "pe-2", "rack-0", "rack-1", "root"}, wantCapacity: 8, wantErr: false},
+		{name: "remove last PE in rack", nodeID: "pe-2", wantGroups: []string{},
+			wantNodeIDs: []string{"pe-0", "pe-1", "rack-0", "root"}, wantCapacity: 8, wantErr: false},
+		{name: "remove rack", nodeID: "rack-0", wantGroups: []string{"g"},
+			wantNodeIDs: []string{"pe-2", "rack-1", "root"}, wantCapacity: 4, wantErr: false},
+		{name: "remove root", nodeID: "root",
+			wantNodeIDs: []string{"pe-0", "pe-1", "pe-2", "rack-0", "rack-1", "root"}, wantCapacity: 12, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			demand, _ := util.NewAllocationCopy([]int{1})
+			leg := system.NewLEGroup("g", 1, demand)
+			le := system.NewLE("g-0", demand)
+			leg.AddLE(le)
+			pTree.GetPEs()["pe-0"].PlaceLE(le)
+			pTree.PercolateResources()
+
+			got, err := pTree.RemoveSubtree(tt.nodeID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PTree.RemoveSubtree() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.wantGroups) {
+				t.Errorf("PTree.RemoveSubtree() = %v, want %v", got, tt.wantGroups)
+			}
+			ids := pTree.GetNodeIDs()
+			sort.Strings(ids)
+			if !reflect.DeepEqual(ids, tt.wantNodeIDs) {
+				t.Errorf("node IDs = %v, want %v", ids, tt.wantNodeIDs)
+			}
+			if got := capacityOf(pTree, "root"); got != tt.wantCapacity {
+				t.Errorf("root capacity = %v, want %v", got, tt.wantCapacity)
+			}
+			if len(tt.wantGroups) > 0 && le.GetHost() != nil {
+				t.Errorf("LE still hosted on %s", le.GetHost().GetID())
+			}
+		})
+	}
+}
+
+func TestPTree_ReplaceCapacity(t *testing.T) {
+	tests := []struct {
+		name         string
+		peID         string
+		capacity     []int
+		wantCapacity int
+		wantErr      bool
+	}{
+		{name: "increase", peID: "pe-2", capacity: []int{10}, wantCapacity: 10, wantErr: false},
+		{name: "decrease", peID: "pe-2", capacity: []int{1}, wantCapacity: 1, wantErr: false},
+		{name: "wrong size", peID: "pe-2", capacity: []int{1, 2}, wantCapacity: 4, wantErr: true},
+		{name: "not a PE", peID: "rack-1", capacity: []int{1}, wantCapacity: 4, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			capacity, _ := util.NewAllocationCopy(tt.capacity)
+			if err := pTree.ReplaceCapacity(tt.peID, capacity); (err != nil) != tt.wantErr {
+				t.Errorf("PTree.ReplaceCapacity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := capacityOf(pTree, "rack-1"); got != tt.wantCapacity {
+				t.Errorf("rack capacity = %v, want %v", got, tt.wantCapacity)
+			}
+			if got := capacityOf(pTree, "root"); got != 8+tt.wantCapacity {
+				t.Errorf("root capacity = %v, want %v", got, 8+tt.wantCapacity)
+			}
+		})
+	}
+}
+
+func TestLTree_RemoveLeaves(t *testing.T) {
+	pTree := makePTree()
+	lNodes := make(map[string]*LNode)
+	for _, id := range pTree.GetNodeIDs() {
+		lNodes[id] = NewLNode((*PNode)(unsafe.Pointer(pTree.GetNode(id))), 0)
+	}
+	link := func(parent string, child string, count int) {
+		lNodes[child].SetCount(count)
+		lNodes[child].SetClaimed(count)
+		lNodes[parent].AddChild(&lNodes[child].Node)
+	}
+	link("rack-0", "pe-0", 2)
+	link("rack-0", "pe-1", 1)
+	link("rack-1", "pe-2", 1)
+	link("root", "rack-0", 3)
+	link("root", "rack-1", 1)
+	lNodes["root"].SetCount(4)
+	lTree := NewLTree(NewTree(&lNodes["root"].Node))
+	lTree.PercolateClaimed()
+
+	if got := lTree.RemoveLeaves([]string{"pe-0", "pe-2", "pe-9"}); got != 3 {
+		t.Errorf("LTree.RemoveLeaves() = %v, want %v", got, 3)
+	}
+	ids := lTree.GetNodeIDs()
+	sort.Strings(ids)
+	if want := []string{"pe-1", "rack-0", "root"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("node IDs = %v, want %v", ids, want)
+	}
+	if lNodes["root"].GetCount() != 1 || lNodes["root"].GetClaimed() != 1 {
+		t.Errorf("root count = %d, claimed = %d, want 1, 1", lNodes["root"].GetCount(), lNodes["root"].GetClaimed())
+	}
+}
+
+func TestPTree_Drain(t *testing.T) {
+	tests := []struct {
+		name       string
+		nodeID     string
+		wantGroups []string
+		wantErr    bool
+	}{
+		{name: "drain rack", nodeID: "rack-0", wantGroups: []string{"g"}, wantErr: false},
+		{name: "drain idle PE", nodeID: "pe-2", wantGroups: []string{}, wantErr: false},
+		{name: "missing node", nodeID: "rack-9", wantGroups: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			demand, _ := util.NewAllocationCopy([]int{1})
+			leg := system.NewLEGroup("g", 1, demand)
+			le := system.NewLE("g-0", demand)
+			leg.AddLE(le)
+			pTree.GetPEs()["pe-1"].PlaceLE(le)
+
+			got, err := pTree.Drain(tt.nodeID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PTree.Drain() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.wantGroups) {
+				t.Errorf("PTree.Drain() = %v, want %v", got, tt.wantGroups)
+			}
+			if tt.wantErr {
+				return
+			}
+			// drained PEs excluded from numFit
+			pTree.PercolateNumFitFunc(func(pe *system.PE) int {
+				if !pe.IsSchedulable() {
+					return 0
+				}
+				return demand.NumberToFit(pe.GetAllocated(), pe.GetCapacity())
+			})
+			for _, leaf := range pTree.GetNode(tt.nodeID).GetLeaves() {
+				if n := (*PNode)(unsafe.Pointer(leaf)).GetNumFit(); n != 0 {
+					t.Errorf("numFit of drained PE %s = %d, want 0", leaf.GetID(), n)
+				}
+			}
+		})
+	}
+}
+
+func TestPTree_GetPELabels(t *testing.T) {
+	pTree := makePTree()
+	pTree.GetRoot().Entity.SetLabel("network", "eth")
+	pTree.GetNode("rack-0").Entity.SetLabel("network", "ib")
+	pTree.GetNode("pe-1").Entity.SetLabel("gpu", "a100")
+	want := map[string]map[string]string{
+		"pe-0": {"network": "ib"},
+		"pe-1": {"network": "ib", "gpu": "a100"},
+		"pe-2": {"network": "eth"},
+	}
+	if got := pTree.GetPELabels(); !reflect.DeepEqual(got, want) {
+		t.Errorf("PTree.GetPELabels() = %v, want %v", got, want)
+	}
+	if got := pTree.GetNode("pe-1").GetPathLabels(); !reflect.DeepEqual(got, want["pe-1"]) {
+		t.Errorf("Node.GetPathLabels() = %v, want %v", got, want["pe-1"])
+	}
+}
+
+func TestPTree_SetOvercommit(t *testing.T) {
+	tests := []struct {
+		name       string
+		ratios     []float64
+		peID       string
+		peRatios   []float64
+		wantErr    bool
+		wantPEErr  bool
+		wantRoot   int
+		wantNumFit int
+	}{
+		{name: "no overcommit", ratios: nil, wantRoot: 12, wantNumFit: 3},
+		{name: "tree overcommit", ratios: []float64{2}, wantRoot: 24, wantNumFit: 6},
+		{name: "PE override", ratios: []float64{2}, peID: "pe-2", peRatios: []float64{1}, wantRoot: 20,
+			wantNumFit: 5},
+		{name: "fractional", ratios: []float64{1.5}, wantRoot: 18, wantNumFit: 6},
+		{name: "undercommit below demand", ratios: []float64{0.5}, wantRoot: 6, wantNumFit: 0},
+		{name: "PE override of no overcommit", ratios: nil, peID: "pe-0", peRatios: []float64{3}, wantRoot: 20,
+			wantNumFit: 6},
+		{name: "zero ratio", ratios: []float64{0}, wantErr: true, wantRoot: 12, wantNumFit: 3},
+		{name: "negative ratio", ratios: []float64{-2}, wantErr: true, wantRoot: 12, wantNumFit: 3},
+		{name: "bad length", ratios: []float64{2, 1}, wantErr: true, wantRoot: 12, wantNumFit: 3},
+		{name: "bad PE ratio", ratios: []float64{2}, peID: "pe-2", peRatios: []float64{0}, wantPEErr: true,
+			wantRoot: 24, wantNumFit: 6},
+		{name: "missing PE", ratios: []float64{2}, peID: "pe-9", peRatios: []float64{1}, wantPEErr: true,
+			wantRoot: 24, wantNumFit: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			err := pTree.SetOvercommit(tt.ratios)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PTree.SetOvercommit() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(tt.peID) > 0 {
+				if err := pTree.SetPEOvercommit(tt.peID, tt.peRatios); (err != nil) != tt.wantPEErr {
+					t.Errorf("PTree.SetPEOvercommit() error = %v, wantErr %v", err, tt.wantPEErr)
+				}
+			}
+			if got := capacityOf(pTree, "root"); got != tt.wantRoot {
+				t.Errorf("root capacity = %d, want %d", got, tt.wantRoot)
+			}
+			demand, _ := util.NewAllocationCopy([]int{3})
+			pTree.PercolateNumFit(demand)
+			if got := (*PNode)(unsafe.Pointer(pTree.GetRoot())).GetNumFit(); got != tt.wantNumFit {
+				t.Errorf("root numFit = %d, want %d", got, tt.wantNumFit)
+			}
+
+			// strict placement up to the effective capacity
+			pe := pTree.GetPEs()["pe-0"]
+			numPlaced := 0
+			for i := 0; i < 10; i++ {
+				if err := pe.TryPlaceLE(system.NewLE("le-"+string(rune('a'+i)), demand)); err != nil {
+					if _, ok := err.(*system.CapacityError); !ok {
+						t.Errorf("PE.TryPlaceLE() error = %v, want CapacityError", err)
+					}
+					break
+				}
+				numPlaced++
+			}
+			if want := pe.GetEffectiveCapacity().GetValue()[0] / 3; numPlaced != want {
+				t.Errorf("PE.TryPlaceLE() placed %d, want %d", numPlaced, want)
+			}
+		})
+	}
+}
+
+func TestPTree_LimitNumFit(t *testing.T) {
+	tests := []struct {
+		name         string
+		reserved     int
+		maxAllocated int
+		// caps on the number that can fit, applied after percolation
+		caps     map[string]int
+		wantRack int
+		wantRoot int
+	}{
+		{name: "no limits", reserved: 0, maxAllocated: -1, wantRack: 8, wantRoot: 12},
+		{name: "reserved on PE", reserved: 3, maxAllocated: -1, wantRack: 5, wantRoot: 9},
+		{name: "max allocated in rack", reserved: 0, maxAllocated: 6, wantRack: 6, wantRoot: 10},
+		{name: "both", reserved: 3, maxAllocated: 3, wantRack: 3, wantRoot: 7},
+		{name: "unlimited", reserved: 0, maxAllocated: Unlimited, wantRack: 8, wantRoot: 12},
+		{name: "cap below max allocated", reserved: 0, maxAllocated: 6, caps: map[string]int{"rack-0": 2},
+			wantRack: 2, wantRoot: 6},
+		{name: "cap above max allocated", reserved: 0, maxAllocated: 6, caps: map[string]int{"rack-0": 7},
+			wantRack: 6, wantRoot: 10},
+		{name: "caps on PE and rack", reserved: 0, maxAllocated: -1, caps: map[string]int{"pe-0": 1, "rack-0": 3},
+			wantRack: 3, wantRoot: 7},
+		{name: "caps on rack and root", reserved: 0, maxAllocated: 6, caps: map[string]int{"rack-0": 1, "root": 6},
+			wantRack: 1, wantRoot: 5},
+		{name: "negative cap", reserved: 0, maxAllocated: -1, caps: map[string]int{"rack-0": -1},
+			wantRack: 0, wantRoot: 4},
+		{name: "all reserved on PE", reserved: 4, maxAllocated: -1, wantRack: 4, wantRoot: 8},
+		{name: "reserved above capacity", reserved: 9, maxAllocated: -1, wantRack: 4, wantRoot: 8},
+		{name: "zero max allocated", reserved: 0, maxAllocated: 0, wantRack: 0, wantRoot: 4},
+		{name: "max allocated above capacity", reserved: 0, maxAllocated: 20, wantRack: 8, wantRoot: 12},
+		{name: "zero cap on root", reserved: 0, maxAllocated: -1, caps: map[string]int{"root": 0},
+			wantRack: 8, wantRoot: 0},
+		{name: "cap on missing node", reserved: 0, maxAllocated: -1, caps: map[string]int{"rack-9": 0},
+			wantRack: 8, wantRoot: 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pTree := makePTree()
+			reserved, _ := util.NewAllocationCopy([]int{tt.reserved})
+			pTree.GetPEs()["pe-0"].SetReserved(reserved)
+			pTree.PercolateResources()
+			rack := (*PNode)(unsafe.Pointer(pTree.GetNode("rack-0")))
+			if tt.maxAllocated >= 0 {
+				maxAllocated, _ := util.NewAllocationCopy([]int{tt.maxAllocated})
+				if !rack.SetMaxAllocated(maxAllocated) {
+					t.Fatalf("PNode.SetMaxAllocated() failed")
+				}
+			}
+			demand, _ := util.NewAllocationCopy([]int{1})
+			pTree.PercolateNumFit(demand)
+			pTree.LimitNumFit(demand, tt.caps)
+			if got := rack.GetNumFit(); got != tt.wantRack {
+				t.Errorf("rack numFit = %d, want %d", got, tt.wantRack)
+			}
+			if got := (*PNode)(unsafe.Pointer(pTree.GetRoot())).GetNumFit(); got != tt.wantRoot {
+				t.Errorf("root numFit = %d, want %d", got, tt.wantRoot)
+			}
+		})
+	}
+}
@@ -6,8 +6,13 @@ import (
 	"math"
 )
 
+// Infinite : the result of dividing by a zero element of an allocation
+const Infinite int = math.MaxInt32
+
 // Allocation : an allocation of an (ordered) array of resources
 // (names of resources are left out for efficiency)
+//   - values are of type int, assuming a 64-bit platform for large quantities (e.g. memory in bytes);
+//     on 32-bit platforms, 64-bit values out of the range of int are rejected by the int64 conversions
 type Allocation struct {
 	// values of the allocation
 	x []int
@@ -33,6 +38,28 @@ func NewAllocationCopy(value []int) (*Allocation, error) {
 	return a, nil
 }
 
+// NewAllocationInt64 : create an allocation given an array of 64-bit values
+//   - returns error if a value is out of the range of int
+func NewAllocationInt64(value []int64) (*Allocation, error) {
+	x := make([]int, len(value))
+	for i, v := range value {
+		if v > math.MaxInt || v < math.MinInt {
+			return nil, fmt.Errorf("value %d out of range", v)
+		}
+		x[i] = int(v)
+	}
+	return NewAllocationCopy(x)
+}
+
+// GetValueInt64 : get a copy of the array of values as 64-bit values
+func (a *Allocation) GetValueInt64() []int64 {
+	v := make([]int64, len(a.x))
+	for i := 0; i < len(a.x); i++ {
+		v[i] = int64(a.x[i])
+	}
+	return v
+}
+
 // GetSize : get the size (length) of the values array
 func (a *Allocation) GetSize() int {
 	return len(a.x)
@@ -79,7 +106,8 @@ func (a *Allocation) Subtract(other *Allocation) bool {
 	return true
 }
 
-// Divide : divide this allocation by another allocation (false if unequal lengths)
+// Divide : divide this allocation by another allocation (error if unequal lengths);
+// dividing by a zero element results in the Infinite value
 func (a *Allocation) Divide(other *Allocation) (*Allocation, error) {
 	if !a.SameSize(other) {
 		return nil, fmt.Errorf("allocations have different sizes")
@@ -88,7 +116,7 @@ func (a *Allocation) Divide(other *Allocation) (*Allocation, error) {
 	v := other.GetValue()
 	for i := 0; i < len(a.x); i++ {
 		if v[i] == 0 {
-			r[i] = math.MaxInt32
+			r[i] = Infinite
 		} else {
 			r[i] = a.x[i] / v[i]
 		}
@@ -103,6 +131,89 @@ func (a *Allocation) Scale(value int) {
 	}
 }
 
+// AddChecked : add another allocation to this one;
+// returns error, leaving this allocation unchanged, if unequal lengths or an element overflows
+func (a *Allocation) AddChecked(other *Allocation) error {
+	if other == nil || !a.SameSize(other) {
+		return fmt.Errorf("allocations have different sizes")
+	}
+	r := make([]int, len(a.x))
+	v := other.GetValue()
+	for i := 0; i < len(a.x); i++ {
+		r[i] = a.x[i] + v[i]
+		if (v[i] > 0 && r[i] < a.x[i]) || (v[i] < 0 && r[i] > a.x[i]) {
+			return fmt.Errorf("element %d overflows adding %d to %d", i, v[i], a.x[i])
+		}
+	}
+	copy(a.x, r)
+	return nil
+}
+
+// SubtractChecked : subtract another allocation from this one;
+// returns error, leaving this allocation unchanged, if unequal lengths or an element becomes negative
+func (a *Allocation) SubtractChecked(other *Allocation) error {
+	if other == nil || !a.SameSize(other) {
+		return fmt.Errorf("allocations have different sizes")
+	}
+	r := make([]int, len(a.x))
+	v := other.GetValue()
+	for i := 0; i < len(a.x); i++ {
+		if v[i] < 0 {
+			return fmt.Errorf("element %d subtracts negative value %d", i, v[i])
+		}
+		if v[i] > a.x[i] {
+			return fmt.Errorf("element %d underflows subtracting %d from %d", i, v[i], a.x[i])
+		}
+		r[i] = a.x[i] - v[i]
+	}
+	copy(a.x, r)
+	return nil
+}
+
+// ScaleChecked : multiply elements by a given non-negative value;
+// returns error, leaving this allocation unchanged, if an element overflows
+func (a *Allocation) ScaleChecked(value int) error {
+	if value < 0 {
+		return fmt.Errorf("negative scale %d", value)
+	}
+	r := make([]int, len(a.x))
+	for i := 0; i < len(a.x); i++ {
+		if value > 0 && (a.x[i] > math.MaxInt/value || a.x[i] < math.MinInt/value) {
+			return fmt.Errorf("element %d overflows scaling %d by %d", i, a.x[i], value)
+		}
+		r[i] = a.x[i] * value
+	}
+	copy(a.x, r)
+	return nil
+}
+
+// DivideChecked : divide this allocation by another allocation;
+// returns error if unequal lengths or dividing by a zero element
+func (a *Allocation) DivideChecked(other *Allocation) (*Allocation, error) {
+	if other == nil || !a.SameSize(other) {
+		return nil, fmt.Errorf("allocations have different sizes")
+	}
+	r := make([]int, len(a.x))
+	v := other.GetValue()
+	for i := 0; i < len(a.x); i++ {
+		if v[i] == 0 {
+			return nil, fmt.Errorf("element %d divides by zero", i)
+		}
+		r[i] = a.x[i] / v[i]
+	}
+	return NewAllocationCopy(r)
+}
+
+// Validate : check that all element values are non-negative
+func (a *Allocation) Validate() error {
+	for i := 0; i < len(a.x); i++ {
+		if a.x[i] < 0 {
+			return fmt.Errorf("element %d has negative value %d", i, a.x[i])
+		}
+	}
+	return nil
+}
+
 // Minimum : min value in the allocation array
 func (a *Allocation) Minimum() int {
 	min := 0
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"math"
 	"reflect"
 	"testing"
 )
@@ -436,3 +437,75 @@ func TestAllocation_StringPretty(t *testing.T) {
 		})
 	}
 }
+
+func TestAllocation_Checked(t *testing.T) {
+	big := math.MaxInt - 1
+	tests := []struct {
+		name    string
+		x       []int
+		op      func(a *Allocation) error
+		want    []int
+		wantErr bool
+	}{
+		{name: "add",
+			x:    []int{1, 2},
+			op:   func(a *Allocation) error { return a.AddChecked(&Allocation{x: []int{3, 4}}) },
+			want: []int{4, 6},
+		},
+		{name: "add overflow",
+			x:       []int{1, big},
+			op:      func(a *Allocation) error { return a.AddChecked(&Allocation{x: []int{1, 2}}) },
+			want:    []int{1, big},
+			wantErr: true,
+		},
+		{name: "add different sizes",
+			x:       []int{1, 2},
+			op:      func(a *Allocation) error { return a.AddChecked(&Allocation{x: []int{1}}) },
+			want:    []int{1, 2},
+			wantErr: true,
+		},
+		{name: "subtract",
+			x:    []int{4, 6},
+			op:   func(a *Allocation) error { return a.SubtractChecked(&Allocation{x: []int{4, 1}}) },
+			want: []int{0, 5},
+		},
+		{name: "subtract underflow",
+			x:       []int{4, 6},
+			op:      func(a *Allocation) error { return a.SubtractChecked(&Allocation{x: []int{1, 7}}) },
+			want:    []int{4, 6},
+			wantErr: true,
+		},
+		{name: "scale overflow",
+			x:       []int{2, big / 2},
+			op:      func(a *Allocation) error { return a.ScaleChecked(3) },
+			want:    []int{2, big / 2},
+			wantErr: true,
+		},
+		{name: "large memory in bytes",
+			x:    []int{4, 64 << 30},
+			op:   func(a *Allocation) error { return a.ScaleChecked(4) },
+			want: []int{16, 256 << 30},
+		},
+		{name: "divide by zero",
+			x: []int{4, 6},
+			op: func(a *Allocation) error {
+				_, err := a.DivideChecked(&Allocation{x: []int{2, 0}})
+				return err
+			},
+			want:    []int{4, 6},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Allocation{x: append([]int{}, tt.x...)}
+			err := tt.op(a)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(a.x, tt.want) {
+				t.Errorf("got %v, want %v", a.x, tt.want)
+			}
+		})
+	}
+}
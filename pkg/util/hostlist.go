@@ -0,0 +1,158 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExpandHostlist : expand a hostlist expression into a list of host names,
+// e.g. "node[001-003,010],login" results into [node001 node002 node003 node010 login]
+//   - multiple bracket ranges in a name are expanded in order, e.g. "r[1-2]n[1-2]"
+//   - zero padding is given by the width of the first number of a range
+func ExpandHostlist(hostlist string) ([]string, error) {
+	names := make([]string, 0)
+	for _, item := range splitHostlist(hostlist) {
+		if len(item) == 0 {
+			continue
+		}
+		expanded, err := expandHostlistItem(item)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, expanded...)
+	}
+	return names, nil
+}
+
+// splitHostlist : split a hostlist expression on commas outside brackets
+func splitHostlist(hostlist string) []string {
+	items := make([]string, 0)
+	depth := 0
+	start := 0
+	for i, c := range hostlist {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(hostlist[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(items, strings.TrimSpace(hostlist[start:]))
+}
+
+// expandHostlistItem : expand a single host name expression with bracket ranges
+func expandHostlistItem(item string) ([]string, error) {
+	open := strings.Index(item, "[")
+	if open < 0 {
+		if strings.Contains(item, "]") {
+			return nil, fmt.Errorf("invalid hostlist %q: unbalanced brackets", item)
+		}
+		return []string{item}, nil
+	}
+	close := strings.Index(item, "]")
+	if close < open {
+		return nil, fmt.Errorf("invalid hostlist %q: unbalanced brackets", item)
+	}
+	prefix := item[:open]
+	suffixes, err := expandHostlistItem(item[close+1:])
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	for _, r := range strings.Split(item[open+1:close], ",") {
+		bounds := strings.SplitN(r, "-", 2)
+		low, err := strconv.Atoi(bounds[0])
+		if err != nil || low < 0 {
+			return nil, fmt.Errorf("invalid hostlist %q: bad range %q", item, r)
+		}
+		high := low
+		if len(bounds) == 2 {
+			if high, err = strconv.Atoi(bounds[1]); err != nil || high < low {
+				return nil, fmt.Errorf("invalid hostlist %q: bad range %q", item, r)
+			}
+		}
+		width := len(bounds[0])
+		for i := low; i <= high; i++ {
+			for _, suffix := range suffixes {
+				names = append(names, fmt.Sprintf("%s%0*d%s", prefix, width, i, suffix))
+			}
+		}
+	}
+	return names, nil
+}
+
+// CompressHostlist : compress a list of host names into a hostlist expression,
+// e.g. [node001 node002 node003 node010 login] results into "login,node[001-003,010]"
+//   - names are grouped by prefix and width of their numeric suffix
+func CompressHostlist(names []string) string {
+	type hostKey struct {
+		prefix string
+		width  int
+	}
+	groups := make(map[hostKey][]int)
+	plain := make([]string, 0)
+	for _, name := range names {
+		i := len(name)
+		for i > 0 && name[i-1] >= '0' && name[i-1] <= '9' {
+			i--
+		}
+		if i == len(name) {
+			plain = append(plain, name)
+			continue
+		}
+		number, _ := strconv.Atoi(name[i:])
+		key := hostKey{prefix: name[:i], width: len(name) - i}
+		groups[key] = append(groups[key], number)
+	}
+
+	// order groups by prefix and width
+	keys := make([]hostKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].prefix == keys[j].prefix {
+			return keys[i].width < keys[j].width
+		}
+		return keys[i].prefix < keys[j].prefix
+	})
+	sort.Strings(plain)
+
+	items := plain
+	for _, key := range keys {
+		numbers := groups[key]
+		sort.Ints(numbers)
+		if len(numbers) == 1 {
+			items = append(items, fmt.Sprintf("%s%0*d", key.prefix, key.width, numbers[0]))
+			continue
+		}
+		var b bytes.Buffer
+		b.WriteString(key.prefix + "[")
+		for i := 0; i < len(numbers); {
+			j := i
+			for j+1 < len(numbers) && numbers[j+1] <= numbers[j]+1 {
+				j++
+			}
+			if i > 0 {
+				b.WriteString(",")
+			}
+			if j > i {
+				fmt.Fprintf(&b, "%0*d-%0*d", key.width, numbers[i], key.width, numbers[j])
+			} else {
+				fmt.Fprintf(&b, "%0*d", key.width, numbers[i])
+			}
+			i = j + 1
+		}
+		b.WriteString("]")
+		items = append(items, b.String())
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandHostlist(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostlist string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "plain", hostlist: "login,node1", want: []string{"login", "node1"}},
+		{name: "padded", hostlist: "node[008-010,012]",
+			want: []string{"node008", "node009", "node010", "node012"}},
+		{name: "multiple ranges", hostlist: "r[1-2]n[0-1],x",
+			want: []string{"r1n0", "r1n1", "r2n0", "r2n1", "x"}},
+		{name: "empty", hostlist: "", want: []string{}},
+		{name: "unbalanced", hostlist: "node[1-2", wantErr: true},
+		{name: "bad range", hostlist: "node[3-1]", wantErr: true},
+		{name: "not a number", hostlist: "node[a-b]", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandHostlist(tt.hostlist)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExpandHostlist() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandHostlist() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressHostlist(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		want  string
+	}{
+		{name: "plain", names: []string{"login", "gpu"}, want: "gpu,login"},
+		{name: "padded", names: []string{"node010", "node008", "node009", "node012"}, want: "node[008-010,012]"},
+		{name: "single", names: []string{"node1", "login"}, want: "login,node1"},
+		{name: "prefixes", names: []string{"a1", "b1", "a2"}, want: "a[1-2],b1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompressHostlist(tt.names)
+			if got != tt.want {
+				t.Errorf("CompressHostlist() = %v, want %v", got, tt.want)
+			}
+			expanded, _ := ExpandHostlist(got)
+			if len(expanded) != len(tt.names) {
+				t.Errorf("ExpandHostlist(CompressHostlist()) = %v, want %v", expanded, tt.names)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package util
+
+import (
+	"fmt"
+)
+
+// NamedAllocation : an allocation of resources given by name;
+// operations between named allocations align values by resource name
+// and fail if the allocations have different sets of resources
+type NamedAllocation struct {
+	// names of resources, ordering the values of the allocation
+	names []string
+	// values of the allocation
+	alloc *Allocation
+}
+
+// ResourceSetError : an operation between allocations with different sets of resources
+type ResourceSetError struct {
+	Names      []string
+	OtherNames []string
+}
+
+// Error : the error message
+func (e *ResourceSetError) Error() string {
+	return fmt.Sprintf("different resource sets %v and %v", e.Names, e.OtherNames)
+}
+
+// NewNamedAllocation : create a named allocation given resource names and their values
+func NewNamedAllocation(names []string, values []int) (*NamedAllocation, error) {
+	if len(names) != len(values) {
+		return nil, fmt.Errorf("%d resource names and %d values", len(names), len(values))
+	}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if len(name) == 0 {
+			return nil, fmt.Errorf("empty resource name")
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate resource name %q", name)
+		}
+		seen[name] = true
+	}
+	alloc, err := NewAllocationCopy(values)
+	if err != nil {
+		return nil, err
+	}
+	na := &NamedAllocation{
+		names: make([]string, len(names)),
+		alloc: alloc,
+	}
+	copy(na.names, names)
+	return na, nil
+}
+
+// ParseNamedAllocation : create a named allocation given quantities keyed by resource name
+// (e.g. "cpu": "500m", "memory": "64Gi"), ordered by the given resource names;
+// resources missing a quantity are zero, milli resources (e.g. cpu) are in milli units
+func ParseNamedAllocation(quantities map[string]string, names []string) (*NamedAllocation, error) {
+	index := make(map[string]int)
+	for i, name := range names {
+		index[name] = i
+	}
+	values := make([]int, len(names))
+	for name, q := range quantities {
+		i, exists := index[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown resource %q, expected one of %v", name, names)
+		}
+		value, err := ParseQuantity(q, IsMilliResource(name))
+		if err != nil {
+			return nil, fmt.Errorf("resource %s: %s", name, err.Error())
+		}
+		values[i] = value
+	}
+	return NewNamedAllocation(names, values)
+}
+
+// GetNames : the names of the resources
+func (na *NamedAllocation) GetNames() []string {
+	names := make([]string, len(na.names))
+	copy(names, na.names)
+	return names
+}
+
+// GetAllocation : a copy of the values ordered by the resource names
+func (na *NamedAllocation) GetAllocation() *Allocation {
+	return na.alloc.Clone()
+}
+
+// Get : the value of a resource (false if unknown resource)
+func (na *NamedAllocation) Get(name string) (int, bool) {
+	for i, n := range na.names {
+		if n == name {
+			return na.alloc.x[i], true
+		}
+	}
+	return 0, false
+}
+
+// Set : set the value of a resource (false if unknown resource)
+func (na *NamedAllocation) Set(name string, value int) bool {
+	for i, n := range na.names {
+		if n == name {
+			na.alloc.x[i] = value
+			return true
+		}
+	}
+	return false
+}
+
+// Clone : create a copy
+func (na *NamedAllocation) Clone() *NamedAllocation {
+	clone, _ := NewNamedAllocation(na.names, na.alloc.x)
+	return clone
+}
+
+// SameResources : check if the same set of resources as another allocation, regardless of order
+func (na *NamedAllocation) SameResources(other *NamedAllocation) bool {
+	_, err := na.aligned(other)
+	return err == nil
+}
+
+// Add : add another allocation to this one
+func (na *NamedAllocation) Add(other *NamedAllocation) error {
+	v, err := na.aligned(other)
+	if err != nil {
+		return err
+	}
+	na.alloc.Add(v)
+	return nil
+}
+
+// Subtract : subtract another allocation from this one
+func (na *NamedAllocation) Subtract(other *NamedAllocation) error {
+	v, err := na.aligned(other)
+	if err != nil {
+		return err
+	}
+	na.alloc.Subtract(v)
+	return nil
+}
+
+// Equal : check if equals another allocation
+func (na *NamedAllocation) Equal(other *NamedAllocation) (bool, error) {
+	v, err := na.aligned(other)
+	if err != nil {
+		return false, err
+	}
+	return na.alloc.Equal(v), nil
+}
+
+// LessOrEqual : check if less or equal to another allocation
+func (na *NamedAllocation) LessOrEqual(other *NamedAllocation) (bool, error) {
+	v, err := na.aligned(other)
+	if err != nil {
+		return false, err
+	}
+	return na.alloc.LessOrEqual(v), nil
+}
+
+// Fit : check if this allocation fits on an entity with a given capacity
+// and already allocated values
+func (na *NamedAllocation) Fit(allocated *NamedAllocation, capacity *NamedAllocation) (bool, error) {
+	a, err := na.aligned(allocated)
+	if err != nil {
+		return false, err
+	}
+	c, err := na.aligned(capacity)
+	if err != nil {
+		return false, err
+	}
+	return na.alloc.Fit(a, c), nil
+}
+
+// NumberToFit : number of this allocation fitting on an entity with a given capacity
+// and already allocated values
+func (na *NamedAllocation) NumberToFit(allocated *NamedAllocation, capacity *NamedAllocation) (int, error) {
+	a, err := na.aligned(allocated)
+	if err != nil {
+		return 0, err
+	}
+	c, err := na.aligned(capacity)
+	if err != nil {
+		return 0, err
+	}
+	return na.alloc.NumberToFit(a, c), nil
+}
+
+// aligned : the values of another allocation ordered by the resource names of this one
+func (na *NamedAllocation) aligned(other *NamedAllocation) (*Allocation, error) {
+	if other == nil {
+		return nil, fmt.Errorf("nil allocation")
+	}
+	if len(na.names) != len(other.names) {
+		return nil, &ResourceSetError{Names: na.GetNames(), OtherNames: other.GetNames()}
+	}
+	x := make([]int, len(na.names))
+	for i, name := range na.names {
+		value, exists := other.Get(name)
+		if !exists {
+			return nil, &ResourceSetError{Names: na.GetNames(), OtherNames: other.GetNames()}
+		}
+		x[i] = value
+	}
+	return NewAllocationCopy(x)
+}
+
+// String : a print out of the allocation with resource names
+func (na *NamedAllocation) String() string {
+	return na.alloc.StringPretty(na.names)
+}
@@ -0,0 +1,65 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestNamedAllocation_Operations(t *testing.T) {
+	capacity, err := ParseNamedAllocation(map[string]string{"cpu": "8", "memory": "64Gi"}, []string{"cpu", "memory"})
+	if err != nil {
+		t.Fatalf("ParseNamedAllocation() error = %v", err)
+	}
+	// same resources in a different order
+	demand, err := ParseNamedAllocation(map[string]string{"cpu": "1500m", "memory": "8Gi"}, []string{"memory", "cpu"})
+	if err != nil {
+		t.Fatalf("ParseNamedAllocation() error = %v", err)
+	}
+	allocated, _ := NewNamedAllocation([]string{"memory", "cpu"}, []int{16 << 30, 2000})
+	gpus, _ := NewNamedAllocation([]string{"cpu", "gpu"}, []int{1000, 1})
+
+	tests := []struct {
+		name    string
+		op      func() (int, error)
+		want    int
+		wantErr bool
+	}{
+		{name: "number to fit",
+			op:   func() (int, error) { return demand.NumberToFit(allocated, capacity) },
+			want: 4,
+		},
+		{name: "number to fit different resources",
+			op:      func() (int, error) { return demand.NumberToFit(allocated, gpus) },
+			wantErr: true,
+		},
+		{name: "add",
+			op: func() (int, error) {
+				sum := allocated.Clone()
+				err := sum.Add(demand)
+				cpu, _ := sum.Get("cpu")
+				return cpu, err
+			},
+			want: 3500,
+		},
+		{name: "subtract different resources",
+			op: func() (int, error) {
+				return 0, allocated.Clone().Subtract(gpus)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if _, isSetErr := err.(*ResourceSetError); tt.wantErr && !isSetErr {
+				t.Errorf("error = %v, want ResourceSetError", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
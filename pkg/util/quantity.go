@@ -0,0 +1,71 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// quantitySuffixes : multipliers of quantity suffixes
+var quantitySuffixes = map[string]*big.Rat{
+	"Ki": big.NewRat(1<<10, 1), "Mi": big.NewRat(1<<20, 1), "Gi": big.NewRat(1<<30, 1),
+	"Ti": big.NewRat(1<<40, 1), "Pi": big.NewRat(1<<50, 1), "Ei": big.NewRat(1<<60, 1),
+	"k": big.NewRat(1e3, 1), "M": big.NewRat(1e6, 1), "G": big.NewRat(1e9, 1),
+	"T": big.NewRat(1e12, 1), "P": big.NewRat(1e15, 1), "E": big.NewRat(1e18, 1),
+	"m": big.NewRat(1, 1e3), "": big.NewRat(1, 1),
+}
+
+// ParseQuantity : parse a quantity (e.g. "64Gi", "500m", "2") into integer units,
+// or milli units if milli is true, rounding up
+//   - returns error if the value is out of the range of int
+func ParseQuantity(s string, milli bool) (int, error) {
+	value, err := ParseQuantityInt64(s, milli)
+	if err != nil {
+		return 0, err
+	}
+	if value > math.MaxInt {
+		return 0, fmt.Errorf("quantity %q out of range", s)
+	}
+	return int(value), nil
+}
+
+// ParseQuantityInt64 : parse a quantity into 64-bit integer units, or milli units if milli is true,
+// rounding up
+//   - the number is parsed exactly, so integer quantities keep all their digits
+//   - returns error if the value is out of the range of int64
+func ParseQuantityInt64(s string, milli bool) (int64, error) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '+' && r != '-'
+	})
+	number, suffix := s, ""
+	if i >= 0 {
+		number, suffix = s[:i], s[i:]
+	}
+	multiplier, exists := quantitySuffixes[suffix]
+	if !exists || len(number) == 0 {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	value, ok := new(big.Rat).SetString(number)
+	if !ok || value.Sign() < 0 {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	value.Mul(value, multiplier)
+	if milli {
+		value.Mul(value, big.NewRat(1000, 1))
+	}
+	// round up to an integer
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	if remainder.Sign() > 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if !quotient.IsInt64() {
+		return 0, fmt.Errorf("quantity %q out of range", s)
+	}
+	return quotient.Int64(), nil
+}
+
+// IsMilliResource : is a resource canonically counted in milli units (e.g. cpu)
+func IsMilliResource(name string) bool {
+	return name == "cpu"
+}
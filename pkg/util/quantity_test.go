@@ -0,0 +1,72 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		s       string
+		milli   bool
+		want    int
+		wantErr bool
+	}{
+		{s: "2", milli: false, want: 2},
+		{s: "2", milli: true, want: 2000},
+		{s: "500m", milli: true, want: 500},
+		{s: "500m", milli: false, want: 1},
+		{s: "64Gi", milli: false, want: 64 << 30},
+		{s: "1.5k", milli: false, want: 1500},
+		{s: "1Ei", milli: false, want: 1 << 60},
+		{s: "10Ei", milli: false, wantErr: true},
+		{s: "2X", milli: false, wantErr: true},
+		{s: "-1", milli: false, wantErr: true},
+		{s: "Gi", milli: false, wantErr: true},
+		{s: "1.2.3", milli: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseQuantity(tt.s, tt.milli)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseQuantity() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseQuantity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQuantityInt64(t *testing.T) {
+	tests := []struct {
+		s       string
+		milli   bool
+		want    int64
+		wantErr bool
+	}{
+		{s: "9007199254740993", milli: false, want: 1<<53 + 1},
+		{s: "9223372036854775807", milli: false, want: math.MaxInt64},
+		{s: "9223372036854775808", milli: false, wantErr: true},
+		{s: "9223372036854775807m", milli: false, want: 9223372036854776},
+		{s: "9223372036854775807", milli: true, wantErr: true},
+		{s: "7Ei", milli: false, want: 7 << 60},
+		{s: "8Ei", milli: false, wantErr: true},
+		{s: "0.1", milli: true, want: 100},
+		{s: "1m", milli: false, want: 1},
+		{s: "0", milli: true, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseQuantityInt64(tt.s, tt.milli)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseQuantityInt64() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseQuantityInt64() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
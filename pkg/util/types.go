@@ -1,5 +1,11 @@
 package util
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
 // Affinity : the affinity type of a level constraint
 type Affinity int
 
@@ -40,12 +46,194 @@ type JMetaData struct {
 
 // JTreeSpec : spec of topology tree
 type JTreeSpec struct {
-	ResourceNames []string `json:"resource-names"`
-	LevelNames    []string `json:"level-names"`
-	Tree          TreeSpec `json:"tree"`
+	ResourceNames    []string           `json:"resource-names"`
+	LevelNames       []string           `json:"level-names"`
+	Tree             TreeSpec           `json:"tree"`
+	Overcommit       map[string]float64 `json:"overcommit,omitempty"`
+	DeviceLevelNames []string           `json:"device-level-names,omitempty"`
+	MaxMembers       int                `json:"max-members,omitempty"`
 }
 
-// TreeSpec : spec for (sub) tree
+// TreeSpec : spec for (sub) tree;
+// resources, reserved resources, overcommit ratios (keyed by resource name), and the maximum number of
+// members hosted over all groups apply to leaves only,
+// labels and maximum allocated apply to all nodes;
+// devices of a leaf form a device tree, with leaf devices specified as leaves and interior devices as levels
 type TreeSpec struct {
-	Level map[string]TreeSpec `json:"level"`
+	Level      map[string]TreeSpec `json:"level,omitempty"`
+	Capacity   Quantities          `json:"capacity,omitempty"`
+	Allocated  Quantities          `json:"allocated,omitempty"`
+	State      string              `json:"state,omitempty"`
+	Taints     []JTaint            `json:"taints,omitempty"`
+	Labels     map[string]string   `json:"labels,omitempty"`
+	Overcommit map[string]float64  `json:"overcommit,omitempty"`
+	Reserved   Quantities          `json:"reserved,omitempty"`
+	MaxAlloc   Quantities          `json:"max-allocated,omitempty"`
+	Devices    map[string]TreeSpec `json:"devices,omitempty"`
+	MaxMembers int                 `json:"max-members,omitempty"`
+}
+
+// Quantities : resource values keyed by resource name, given in a spec either as numbers
+// or as quantity strings (e.g. "64Gi", "500m"), where quantities of milli resources (e.g. cpu)
+// are in milli units, as in the Kubernetes extender
+type Quantities map[string]int
+
+// UnmarshalJSON : unmarshal resource values given as numbers or quantity strings
+func (q *Quantities) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*q = nil
+		return nil
+	}
+	values := make(Quantities, len(raw))
+	for name, r := range raw {
+		var value int
+		if err := json.Unmarshal(r, &value); err == nil {
+			values[name] = value
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(r, &s); err != nil {
+			return fmt.Errorf("resource %s: value %s is neither an integer nor a quantity", name, string(r))
+		}
+		value, err := ParseQuantity(s, IsMilliResource(name))
+		if err != nil {
+			return fmt.Errorf("resource %s: %s", name, err.Error())
+		}
+		values[name] = value
+	}
+	*q = values
+	return nil
+}
+
+// JTaint : spec of a taint on a leaf
+type JTaint struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+// JToleration : spec of a toleration of taints;
+// operator is Equal (default) or Exists (any value)
+type JToleration struct {
+	Key      string `json:"key,omitempty"`
+	Operator string `json:"operator,omitempty"`
+	Value    string `json:"value,omitempty"`
+}
+
+// JSelector : spec of a selector on labels of nodes along the path to a leaf;
+// operator is Equals (default), In, NotIn, or Exists
+type JSelector struct {
+	Key      string   `json:"key"`
+	Operator string   `json:"operator,omitempty"`
+	Values   []string `json:"values,omitempty"`
+}
+
+// StringToAffinity : get the affinity given its string representation (case insensitive)
+func StringToAffinity(s string) (Affinity, bool) {
+	switch strings.ToLower(s) {
+	case "spread":
+		return Spread, true
+	case "pack":
+		return Pack, true
+	}
+	return Spread, false
+}
+
+var (
+	// DefaultGroupKind : the default kind attribute of the placement group
+	DefaultGroupKind string = "PlacementGroup"
+)
+
+// PlacementGroup : JSON placement group
+type PlacementGroup struct {
+	Kind     string     `json:"kind"`
+	MetaData JMetaData  `json:"metadata"`
+	Spec     JGroupSpec `json:"spec"`
+}
+
+// JGroupSpec : spec of placement group
+type JGroupSpec struct {
+	Size             int                `json:"size"`
+	Demand           Quantities         `json:"demand"`
+	LevelConstraints []JLevelConstraint `json:"level-constraints,omitempty"`
+	Tolerations      []JToleration      `json:"tolerations,omitempty"`
+	NodeSelector     []JSelector        `json:"node-selector,omitempty"`
+	AntiAffinity     []JGroupAffinity   `json:"anti-affinity,omitempty"`
+	Affinity         []JGroupAffinity   `json:"affinity,omitempty"`
+	NodeOrder        string             `json:"node-order,omitempty"`
+	ResourceWeights  map[string]float64 `json:"resource-weights,omitempty"`
+	Duration         int                `json:"duration,omitempty"`
+}
+
+// JGroupAffinity : spec of an affinity or anti-affinity to another group at a level;
+// the level is given either by number (leaves are at level 0) or by name
+//   - max-shared applies to anti-affinity, hard to affinity
+type JGroupAffinity struct {
+	Group     string `json:"group"`
+	Level     *int   `json:"level,omitempty"`
+	LevelName string `json:"level-name,omitempty"`
+	MaxShared int    `json:"max-shared,omitempty"`
+	Hard      bool   `json:"hard,omitempty"`
+}
+
+// JLevelConstraint : spec of a level constraint;
+// the level is given either by number (leaves are at level 0) or by name
+type JLevelConstraint struct {
+	ID         string  `json:"id"`
+	Level      *int    `json:"level,omitempty"`
+	LevelName  string  `json:"level-name,omitempty"`
+	Affinity   string  `json:"affinity"`
+	Hard       bool    `json:"hard,omitempty"`
+	Range      *JRange `json:"range,omitempty"`
+	Partitions int     `json:"partitions,omitempty"`
+	Factor     int     `json:"factor,omitempty"`
+	// label key with the same value for all members under a node at the level
+	HomogeneousKey string `json:"homogeneous-key,omitempty"`
+	// nodes at the level are exclusive to the group
+	Exclusive bool `json:"exclusive,omitempty"`
+}
+
+// JRange : spec of a [min, max] range
+type JRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// JPlacement : placement of a placement group
+type JPlacement struct {
+	Group       string            `json:"group"`
+	Size        int               `json:"size"`
+	Placed      int               `json:"placed"`
+	FullyPlaced bool              `json:"fully-placed"`
+	LTree       *JLNode           `json:"ltree,omitempty"`
+	Bindings    map[string]string `json:"bindings,omitempty"`
+}
+
+// JLNode : node in a logical tree
+type JLNode struct {
+	ID       string    `json:"id"`
+	Count    int       `json:"count"`
+	Claimed  int       `json:"claimed,omitempty"`
+	Children []*JLNode `json:"children,omitempty"`
+}
+
+// JPNode : node in a physical tree with its resources;
+// capacity is the effective capacity given the overcommit ratios of leaves
+type JPNode struct {
+	ID         string             `json:"id"`
+	Level      string             `json:"level"`
+	Capacity   map[string]int     `json:"capacity,omitempty"`
+	Allocated  map[string]int     `json:"allocated,omitempty"`
+	State      string             `json:"state,omitempty"`
+	Taints     []JTaint           `json:"taints,omitempty"`
+	Labels     map[string]string  `json:"labels,omitempty"`
+	ReservedBy string             `json:"reserved-by,omitempty"`
+	Overcommit map[string]float64 `json:"overcommit,omitempty"`
+	Reserved   map[string]int     `json:"reserved,omitempty"`
+	MaxAlloc   map[string]int     `json:"max-allocated,omitempty"`
+	MaxMembers int                `json:"max-members,omitempty"`
+	Children   []*JPNode          `json:"children,omitempty"`
 }